@@ -10,6 +10,8 @@ import (
 
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-topo/pkg/manager"
+	"github.com/onosproject/onos-topo/pkg/northbound"
+	"github.com/onosproject/onos-topo/pkg/store"
 )
 
 var log = logging.GetLogger()
@@ -21,6 +23,14 @@ func main() {
 		RunE: runRootCommand,
 	}
 	cli.AddServiceEndpointFlags(cmd, "onos-topo gRPC")
+	cmd.Flags().Bool("in-memory-store", false, "use a non-persistent in-memory topology store instead of Atomix")
+	cmd.Flags().Bool("cached-reads", false, "serve topology reads from the local replica of the Atomix store")
+	cmd.Flags().Bool("indexed-reads", true, "find the objects matching topology queries with the local indexes of the Atomix store and read them from the local replica")
+	cmd.Flags().Int("watch-queue-size", store.DefaultWatchQueueSize, "the number of events queued for a slow watcher before the overflow policy applies")
+	cmd.Flags().String("watch-overflow-policy", store.OverflowDrop.String(), "the policy for watchers that fall behind: 'drop' closes the watch, 'coalesce' skips intermediate changes, 'resync' closes the watch for the topology to be listed again")
+	cmd.Flags().Int("journal-size", store.DefaultJournalSize, "the number of recent events kept for resuming watches")
+	cmd.Flags().String("kind-validation", northbound.KindValidationOff.String(), "the validation of objects against their kind on create and update: 'off', 'warn' or 'enforce'")
+	cmd.Flags().String("metrics-address", "", "the address on which Prometheus metrics are served at /metrics, e.g. ':9102'; metrics are not served if empty")
 	cli.Run(cmd)
 }
 
@@ -30,6 +40,63 @@ func runRootCommand(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
+	inMemoryStore, err := cmd.Flags().GetBool("in-memory-store")
+	if err != nil {
+		return err
+	}
+
+	cachedReads, err := cmd.Flags().GetBool("cached-reads")
+	if err != nil {
+		return err
+	}
+
+	indexedReads, err := cmd.Flags().GetBool("indexed-reads")
+	if err != nil {
+		return err
+	}
+
+	watchQueueSize, err := cmd.Flags().GetInt("watch-queue-size")
+	if err != nil {
+		return err
+	}
+	overflowPolicy, err := cmd.Flags().GetString("watch-overflow-policy")
+	if err != nil {
+		return err
+	}
+	watchOverflowPolicy, err := store.ParseOverflowPolicy(overflowPolicy)
+	if err != nil {
+		return err
+	}
+
+	journalSize, err := cmd.Flags().GetInt("journal-size")
+	if err != nil {
+		return err
+	}
+
+	validation, err := cmd.Flags().GetString("kind-validation")
+	if err != nil {
+		return err
+	}
+	kindValidation, err := northbound.ParseKindValidation(validation)
+	if err != nil {
+		return err
+	}
+
+	metricsAddress, err := cmd.Flags().GetString("metrics-address")
+	if err != nil {
+		return err
+	}
+
 	log.Infof("Starting onos-topo")
-	return cli.RunDaemon(manager.NewManager(manager.Config{ServiceFlags: flags}))
+	return cli.RunDaemon(manager.NewManager(manager.Config{
+		ServiceFlags:        flags,
+		InMemoryStore:       inMemoryStore,
+		CachedReads:         cachedReads,
+		IndexedReads:        indexedReads,
+		WatchQueueSize:      watchQueueSize,
+		WatchOverflowPolicy: watchOverflowPolicy,
+		JournalSize:         journalSize,
+		KindValidation:      kindValidation,
+		MetricsAddress:      metricsAddress,
+	}))
 }
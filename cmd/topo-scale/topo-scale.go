@@ -13,8 +13,11 @@ import (
 	"github.com/onosproject/onos-lib-go/pkg/certs"
 	"github.com/onosproject/onos-lib-go/pkg/grpc/retry"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-topo/pkg/northbound"
+	"github.com/onosproject/onos-topo/pkg/store"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"sync"
 )
 
@@ -259,11 +262,14 @@ func createBidirectionalLink(client topoapi.TopoClient, id1 string, id2 string,
 
 func createLink(client topoapi.TopoClient, id1 string, id2 string, kindID string, labels Labels) {
 	linkName := fmt.Sprintf("%s-%s", id1, id2)
-	err := createEntity(client, linkName, kindID, nil, labels)
+	object, err := northbound.NewTransactionObject(
+		store.CreateOperation(newEntity(linkName, kindID, nil, labels)),
+		store.CreateOperation(newRelation(id1, linkName, "originates")),
+		store.CreateOperation(newRelation(id2, linkName, "terminates")))
 	assertNoError(err)
-	err = createRelation(client, id1, linkName, "originates")
-	assertNoError(err)
-	err = createRelation(client, id2, linkName, "terminates")
+	// The link and the relations to its endpoints are created in a single transaction
+	ctx := metadata.AppendToOutgoingContext(context.Background(), northbound.TransactionKey, "true")
+	_, err = client.Create(ctx, &topoapi.CreateRequest{Object: object})
 	assertNoError(err)
 }
 
@@ -277,18 +283,8 @@ func createLinkTrunk(client topoapi.TopoClient, src string, tgt string, count in
 
 // createEntity creates an entity object
 func createEntity(client topoapi.TopoClient, id string, kindID string, aspectList []*types.Any, labels map[string]string) error {
-	aspects := map[string]*types.Any{}
-	for _, aspect := range aspectList {
-		aspects[aspect.TypeUrl] = aspect
-	}
 	_, err := client.Create(context.Background(), &topoapi.CreateRequest{
-		Object: &topoapi.Object{
-			ID:      topoapi.ID(id),
-			Type:    topoapi.Object_ENTITY,
-			Aspects: aspects,
-			Obj:     &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: topoapi.ID(kindID)}},
-			Labels:  labels,
-		},
+		Object: newEntity(id, kindID, aspectList, labels),
 	})
 	return err
 }
@@ -296,17 +292,37 @@ func createEntity(client topoapi.TopoClient, id string, kindID string, aspectLis
 // CreateRelation creates a relation object
 func createRelation(client topoapi.TopoClient, src string, tgt string, kindID string) error {
 	_, err := client.Create(context.Background(), &topoapi.CreateRequest{
-		Object: &topoapi.Object{
-			ID:   topoapi.ID(src + tgt + kindID),
-			Type: topoapi.Object_RELATION,
-			Obj: &topoapi.Object_Relation{
-				Relation: &topoapi.Relation{
-					SrcEntityID: topoapi.ID(src),
-					TgtEntityID: topoapi.ID(tgt),
-					KindID:      topoapi.ID(kindID),
-				},
-			},
-		},
+		Object: newRelation(src, tgt, kindID),
 	})
 	return err
 }
+
+// newEntity returns an entity object
+func newEntity(id string, kindID string, aspectList []*types.Any, labels map[string]string) *topoapi.Object {
+	aspects := map[string]*types.Any{}
+	for _, aspect := range aspectList {
+		aspects[aspect.TypeUrl] = aspect
+	}
+	return &topoapi.Object{
+		ID:      topoapi.ID(id),
+		Type:    topoapi.Object_ENTITY,
+		Aspects: aspects,
+		Obj:     &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: topoapi.ID(kindID)}},
+		Labels:  labels,
+	}
+}
+
+// newRelation returns a relation object
+func newRelation(src string, tgt string, kindID string) *topoapi.Object {
+	return &topoapi.Object{
+		ID:   topoapi.ID(src + tgt + kindID),
+		Type: topoapi.Object_RELATION,
+		Obj: &topoapi.Object_Relation{
+			Relation: &topoapi.Relation{
+				SrcEntityID: topoapi.ID(src),
+				TgtEntityID: topoapi.ID(tgt),
+				KindID:      topoapi.ID(kindID),
+			},
+		},
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/onosproject/onos-lib-go/pkg/cli"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
+	"github.com/onosproject/onos-lib-go/pkg/prom"
+	"github.com/onosproject/onos-topo/pkg/metrics"
 	service "github.com/onosproject/onos-topo/pkg/northbound"
 	"github.com/onosproject/onos-topo/pkg/store"
 )
@@ -19,6 +21,22 @@ var log = logging.GetLogger("manager")
 // Config is a manager configuration
 type Config struct {
 	ServiceFlags *cli.ServiceEndpointFlags
+	// InMemoryStore runs the topology store in local memory rather than in Atomix
+	InMemoryStore bool
+	// CachedReads serves reads from the local replica of the Atomix store
+	CachedReads bool
+	// IndexedReads narrows the reads from the Atomix store with its local indexes; onos-topo enables it by default
+	IndexedReads bool
+	// WatchQueueSize is the number of events queued for a slow watcher before WatchOverflowPolicy applies
+	WatchQueueSize int
+	// WatchOverflowPolicy is the policy applied to watchers that fall behind
+	WatchOverflowPolicy store.OverflowPolicy
+	// JournalSize is the number of recent events kept for resuming watches
+	JournalSize int
+	// KindValidation determines how objects that do not conform to their kind are handled
+	KindValidation service.KindValidation
+	// MetricsAddress is the address on which Prometheus metrics are served, if any
+	MetricsAddress string
 }
 
 // NewManager creates a new manager
@@ -40,14 +58,38 @@ type Manager struct {
 func (m *Manager) Start() error {
 	log.Info("Starting Manager")
 
-	var err error
-	if m.topoStore, err = store.NewAtomixStore(client.NewClient()); err != nil {
-		return err
+	opts := []store.StoreOption{store.WithJournalSize(m.Config.JournalSize)}
+	if m.Config.InMemoryStore {
+		log.Warn("Using in-memory topology store; topology will not be persisted or replicated")
+		m.topoStore = store.NewMemoryStore(opts...)
+	} else {
+		if m.Config.CachedReads {
+			opts = append(opts, store.WithCachedReads())
+		}
+		if m.Config.IndexedReads {
+			opts = append(opts, store.WithIndexedReads())
+		}
+		var err error
+		if m.topoStore, err = store.NewAtomixStore(client.NewClient(), opts...); err != nil {
+			return err
+		}
+	}
+
+	if m.Config.MetricsAddress != "" {
+		exporter := prom.NewExporter("/metrics", m.Config.MetricsAddress)
+		if err := exporter.RegisterCollector("watch", metrics.NewWatchCollector(m.topoStore)); err != nil {
+			return err
+		}
+		go func() {
+			if err := exporter.Run(); err != nil {
+				log.Errorf("Failed to serve metrics on %s: %v", m.Config.MetricsAddress, err)
+			}
+		}()
 	}
 
 	s := northbound.NewServer(cli.ServerConfigFromFlags(m.Config.ServiceFlags, northbound.SecurityConfig{}))
 	s.AddService(logging.Service{})
-	s.AddService(service.NewService(m.topoStore))
+	s.AddService(service.NewService(m.topoStore, m.Config.KindValidation, store.WithQueue(m.Config.WatchQueueSize, m.Config.WatchOverflowPolicy)))
 	return s.StartInBackground()
 }
 
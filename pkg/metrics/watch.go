@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics exports the metrics of the topology subsystem to Prometheus.
+package metrics
+
+import (
+	"github.com/onosproject/onos-lib-go/pkg/prom"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/onosproject/onos-topo/pkg/store"
+)
+
+var builder = prom.NewBuilder("onos", "topo", nil)
+
+var (
+	watchersDesc = builder.NewMetricDesc("watchers",
+		"Number of active watchers of the topology store.", []string{"overflow"}, nil)
+	watchQueueDepthDesc = builder.NewMetricDesc("watch_queue_depth",
+		"Number of events queued for delivery to the active watchers.", []string{"overflow"}, nil)
+	watchQueueMaxDepthDesc = builder.NewMetricDesc("watch_queue_max_depth",
+		"Highest number of events queued for an active watcher.", []string{"overflow"}, nil)
+	watchQueueCapacityDesc = builder.NewMetricDesc("watch_queue_capacity",
+		"Number of events queued for the active watchers before their overflow policy applies.", []string{"overflow"}, nil)
+	watchCoalescedDesc = builder.NewMetricDesc("watch_coalesced_events_total",
+		"Number of events merged into the queued events of watchers.", []string{"overflow"}, nil)
+)
+
+// overflowPolicies are the values of the overflow label of the watch metrics
+var overflowPolicies = []store.OverflowPolicy{store.OverflowDrop, store.OverflowCoalesce, store.OverflowResync}
+
+// NewWatchCollector returns a collector of the event queue statistics of the watchers of the given store
+func NewWatchCollector(s store.Store) prom.Collector {
+	return &watchCollector{store: s}
+}
+
+// watchCollector retrieves the event queue statistics of the watchers of a store
+type watchCollector struct {
+	store store.Store
+}
+
+// watchQueues aggregates the queue statistics of the active watchers with an overflow policy
+type watchQueues struct {
+	watchers int
+	depth    int
+	maxDepth int
+	capacity int
+}
+
+// Retrieve sends the metrics of the watchers by overflow policy, rather than by watcher, so that the number of
+// series does not grow with the number of Watch calls
+func (c *watchCollector) Retrieve(ch chan<- prometheus.Metric) error {
+	stats := c.store.WatchStats()
+	queues := make(map[store.OverflowPolicy]*watchQueues, len(overflowPolicies))
+	for _, overflow := range overflowPolicies {
+		queues[overflow] = &watchQueues{}
+	}
+	for _, stat := range stats.Watchers {
+		queue, ok := queues[stat.Overflow]
+		if !ok {
+			continue
+		}
+		queue.watchers++
+		queue.depth += stat.Depth
+		queue.capacity += stat.Capacity
+		if stat.MaxDepth > queue.maxDepth {
+			queue.maxDepth = stat.MaxDepth
+		}
+	}
+	for _, overflow := range overflowPolicies {
+		queue, label := queues[overflow], overflow.String()
+		ch <- builder.MustNewConstMetric(watchersDesc, prometheus.GaugeValue, float64(queue.watchers), label)
+		ch <- builder.MustNewConstMetric(watchQueueDepthDesc, prometheus.GaugeValue, float64(queue.depth), label)
+		ch <- builder.MustNewConstMetric(watchQueueMaxDepthDesc, prometheus.GaugeValue, float64(queue.maxDepth), label)
+		ch <- builder.MustNewConstMetric(watchQueueCapacityDesc, prometheus.GaugeValue, float64(queue.capacity), label)
+		ch <- builder.MustNewConstMetric(watchCoalescedDesc, prometheus.CounterValue, float64(stats.Coalesced[overflow]), label)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onosproject/onos-topo/pkg/store"
+)
+
+// retrieve returns the values of the metrics retrieved by the collector, by metric name and overflow policy
+func retrieve(t *testing.T, collector *watchCollector) map[string]map[string]float64 {
+	ch := make(chan prometheus.Metric, 100)
+	assert.NoError(t, collector.Retrieve(ch))
+	close(ch)
+	values := make(map[string]map[string]float64)
+	for metric := range ch {
+		m := &dto.Metric{}
+		assert.NoError(t, metric.Write(m))
+		labels := make(map[string]string)
+		for _, label := range m.Label {
+			labels[label.GetName()] = label.GetValue()
+		}
+		assert.Len(t, labels, 1)
+		value := m.GetGauge().GetValue() + m.GetCounter().GetValue()
+		name := metric.Desc().String()
+		if values[name] == nil {
+			values[name] = make(map[string]float64)
+		}
+		values[name][labels["overflow"]] = value
+	}
+	return values
+}
+
+func TestWatchCollector(t *testing.T) {
+	s := store.NewMemoryStore()
+	collector := NewWatchCollector(s).(*watchCollector)
+
+	// The metrics of every overflow policy must be reported, whether it has watchers or not
+	assert.Equal(t, map[string]float64{"drop": 0, "coalesce": 0, "resync": 0}, retrieve(t, collector)[watchersDesc.String()])
+
+	// The queues of the watchers that are not consumed must be reported by overflow policy
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan topoapi.Event)
+	assert.NoError(t, s.Watch(ctx, ch, nil, store.WithQueue(10, store.OverflowCoalesce)))
+	dropCtx, dropCancel := context.WithCancel(context.Background())
+	defer dropCancel()
+	dropCh := make(chan topoapi.Event)
+	assert.NoError(t, s.Watch(dropCtx, dropCh, nil, store.WithQueue(20, store.OverflowDrop)))
+	for _, id := range []topoapi.ID{"1", "2", "3"} {
+		assert.NoError(t, s.Create(context.Background(), &topoapi.Object{ID: id, Type: topoapi.Object_ENTITY}))
+	}
+	object, err := s.Get(context.Background(), "3")
+	assert.NoError(t, err)
+	object.Labels = map[string]string{"role": "spine"}
+	assert.NoError(t, s.Update(context.Background(), object))
+
+	assert.Eventually(t, func() bool {
+		values := retrieve(t, collector)
+		return values[watchersDesc.String()]["coalesce"] == 1 &&
+			values[watchersDesc.String()]["drop"] == 1 &&
+			values[watchersDesc.String()]["resync"] == 0 &&
+			values[watchQueueDepthDesc.String()]["coalesce"] == 2 &&
+			values[watchQueueDepthDesc.String()]["drop"] == 3 &&
+			values[watchQueueMaxDepthDesc.String()]["drop"] >= 3 &&
+			values[watchQueueCapacityDesc.String()]["coalesce"] == 10 &&
+			values[watchQueueCapacityDesc.String()]["drop"] == 20 &&
+			values[watchCoalescedDesc.String()]["coalesce"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The queues of closed watchers must no longer be reported, but the events they coalesced must still be counted
+	cancel()
+	for range ch { //revive:disable-line:empty-block
+	}
+	assert.Eventually(t, func() bool {
+		values := retrieve(t, collector)
+		return values[watchersDesc.String()]["coalesce"] == 0 &&
+			values[watchersDesc.String()]["drop"] == 1 &&
+			values[watchQueueDepthDesc.String()]["coalesce"] == 0 &&
+			values[watchQueueCapacityDesc.String()]["coalesce"] == 0 &&
+			values[watchCoalescedDesc.String()]["coalesce"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
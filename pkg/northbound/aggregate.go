@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package northbound
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/onosproject/onos-topo/pkg/store"
+)
+
+// GroupByKey is the gRPC metadata key with which List clients request the number of objects matching the request
+// filters instead of the objects, grouped by a comma-separated list of fields parsed by store.ParseGroupBy. An empty
+// value counts all the matching objects. The response holds no object; the count of each group is returned in the
+// GroupCountsKey response header metadata.
+const GroupByKey = "onos-topo-group-by"
+
+// GroupCountsKey is the gRPC response header metadata key listing the JSON encoding of the store.GroupCount of each
+// group of a GroupByKey request
+const GroupCountsKey = "onos-topo-group-counts"
+
+// groupByRequest returns the group by fields requested in the metadata of the given context, and whether objects
+// are to be counted
+func groupByRequest(ctx context.Context) ([]string, bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+	values := md.Get(GroupByKey)
+	if len(values) == 0 {
+		return nil, false, nil
+	}
+	groupBy, err := store.ParseGroupBy(values[0])
+	if err != nil {
+		return nil, false, err
+	}
+	return groupBy, true, nil
+}
+
+// GetGroupCounts returns the group counts carried by the header metadata of the response to a GroupByKey request
+func GetGroupCounts(header metadata.MD) ([]store.GroupCount, error) {
+	values := header.Get(GroupCountsKey)
+	counts := make([]store.GroupCount, 0, len(values))
+	for _, value := range values {
+		count := store.GroupCount{}
+		if err := json.Unmarshal([]byte(value), &count); err != nil {
+			return nil, errors.NewInvalid("invalid %s '%s': %v", GroupCountsKey, value, err)
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+// countHeader returns the response header metadata carrying the given counts
+func countHeader(counts []store.GroupCount) (metadata.MD, error) {
+	header := metadata.MD{}
+	for _, count := range counts {
+		value, err := json.Marshal(count)
+		if err != nil {
+			return nil, err
+		}
+		header.Append(GroupCountsKey, string(value))
+	}
+	return header, nil
+}
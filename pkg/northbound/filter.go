@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package northbound
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-topo/pkg/store"
+)
+
+// FilterKey is the gRPC metadata key with which Query, List and Watch clients request only the objects matching
+// a boolean expression of AND, OR and NOT over kind, type, label and aspect predicates, encoded as a JSON
+// store.FilterExpr. The objects must also match the request filters.
+const FilterKey = "onos-topo-filter"
+
+// filterRequest returns the given filters combined with the filter expression requested in the metadata of the
+// given context, if any
+func filterRequest(ctx context.Context, filters *topoapi.Filters) (*topoapi.Filters, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return filters, nil
+	}
+	values := md.Get(FilterKey)
+	if len(values) == 0 {
+		return filters, nil
+	}
+	var expr store.FilterExpr
+	if err := json.Unmarshal([]byte(values[0]), &expr); err != nil {
+		return nil, errors.NewInvalid("invalid %s: %v", FilterKey, err)
+	}
+	filter, err := store.NewExpressionFilter(&expr)
+	if err != nil {
+		return nil, err
+	}
+	combined := &topoapi.Filters{}
+	if filters != nil {
+		*combined = *filters
+	}
+	combined.LabelFilters = append(append([]*topoapi.Filter{}, combined.LabelFilters...), filter)
+	return combined, nil
+}
@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package northbound
+
+import (
+	"container/list"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-topo/pkg/store"
+)
+
+// PageSizeKey is the gRPC metadata key with which List clients request at most a number of objects per response.
+// If more objects match the request, the token of the next page is returned in the NextPageTokenKey response
+// header metadata.
+const PageSizeKey = "onos-topo-page-size"
+
+// PageTokenKey is the gRPC metadata key with which List clients request the page of a listing following a previous
+// page. The request must have the same filters, sort order and SortKey metadata as the request of the first page.
+const PageTokenKey = "onos-topo-page-token"
+
+// NextPageTokenKey is the gRPC response header metadata key holding the opaque token of the next page of a listing
+const NextPageTokenKey = "onos-topo-next-page-token"
+
+// SortKey is the gRPC metadata key with which List clients request objects sorted by a comma-separated list of
+// fields, parsed by store.ParseSortKeys; objects are finally sorted by ID in the order of the request
+const SortKey = "onos-topo-sort"
+
+// ListingRevisionKey is the gRPC response header metadata key holding the revision of a paginated listing, i.e.
+// the highest revision of the objects listed for its first page. Watching the topology with the
+// ResumeAfterRevisionKey metadata set to the revision delivers the changes made while the pages were read.
+const ListingRevisionKey = "onos-topo-listing-revision"
+
+// listingTTL is the time after which a listing whose pages are no longer requested is discarded
+const listingTTL = 5 * time.Minute
+
+// maxListings is the maximum number of listings kept open; the least recently read listing is discarded first
+const maxListings = 64
+
+// listedObject is an object in the snapshot of a listing, with the revision at which it was listed
+type listedObject struct {
+	id       topoapi.ID
+	revision topoapi.Revision
+}
+
+// listing is a snapshot of the objects listed by a paginated List request, taken for its first page. Only the IDs
+// and revisions of the objects following the first page are kept, in the order of the listing: the pages are read
+// from the snapshot, so that changes to the topology neither duplicate nor skip objects, and each page only reads
+// its own objects from the store.
+type listing struct {
+	id string
+	// request is a hash of the filters and sort order of the listing, so that pages are requested consistently
+	request  uint64
+	revision topoapi.Revision
+	pageSize int
+	objects  []listedObject
+	expires  time.Time
+}
+
+// listings are the open paginated listings of a server, ordered from the least to the most recently read
+type listings struct {
+	open  map[string]*list.Element
+	order list.List
+	mu    sync.Mutex
+}
+
+// sortRequest returns the sort specification requested in the metadata of the given context, if any
+func sortRequest(ctx context.Context) (string, []store.SortKey, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil, nil
+	}
+	values := md.Get(SortKey)
+	if len(values) == 0 {
+		return "", nil, nil
+	}
+	keys, err := store.ParseSortKeys(values[0])
+	if err != nil {
+		return "", nil, err
+	}
+	return values[0], keys, nil
+}
+
+// pageRequest returns the page size and page token requested in the metadata of the given context, if any
+func pageRequest(ctx context.Context) (int, string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, "", nil
+	}
+	pageSize := 0
+	if values := md.Get(PageSizeKey); len(values) > 0 {
+		size, err := strconv.Atoi(values[0])
+		if err != nil || size <= 0 {
+			return 0, "", errors.NewInvalid("invalid %s '%s'", PageSizeKey, values[0])
+		}
+		pageSize = size
+	}
+	token := ""
+	if values := md.Get(PageTokenKey); len(values) > 0 {
+		token = values[0]
+	}
+	return pageSize, token, nil
+}
+
+// start opens a listing of the given sorted objects and returns its first page along with the token of the next
+// page, if any, and the revision of the listing
+func (l *listings) start(req *topoapi.ListRequest, sortSpec string, objects []topoapi.Object, pageSize int) ([]topoapi.Object, string, topoapi.Revision) {
+	var revision topoapi.Revision
+	for i := range objects {
+		if objects[i].Revision > revision {
+			revision = objects[i].Revision
+		}
+	}
+	if len(objects) <= pageSize {
+		return objects, "", revision
+	}
+
+	opened := &listing{
+		id:       uuid.New().String(),
+		request:  listingHash(req, sortSpec),
+		revision: revision,
+		pageSize: pageSize,
+		objects:  make([]listedObject, 0, len(objects)-pageSize),
+		expires:  time.Now().Add(listingTTL),
+	}
+	for _, object := range objects[pageSize:] {
+		opened.objects = append(opened.objects, listedObject{id: object.ID, revision: object.Revision})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.open == nil {
+		l.open = make(map[string]*list.Element)
+	}
+	l.expire(time.Now())
+	for len(l.open) >= maxListings {
+		l.discard(l.order.Front())
+	}
+	l.open[opened.id] = l.order.PushBack(opened)
+	return objects[:pageSize], encodePageToken(opened.id, 0), revision
+}
+
+// next returns the objects of the page of a listing identified by the given token, along with the token of the
+// following page, if any, and the revision of the listing. The listing is discarded once its last page is read.
+func (l *listings) next(req *topoapi.ListRequest, sortSpec string, token string, pageSize int) ([]listedObject, string, topoapi.Revision, error) {
+	id, offset, err := decodePageToken(token)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expire(time.Now())
+	element, ok := l.open[id]
+	if !ok {
+		return nil, "", 0, errors.NewConflict("%s is unknown or expired; the listing must be restarted", PageTokenKey)
+	}
+	snapshot := element.Value.(*listing)
+	if snapshot.request != listingHash(req, sortSpec) {
+		return nil, "", 0, errors.NewInvalid("%s does not match the filters and sort order of the request", PageTokenKey)
+	}
+	if offset > len(snapshot.objects) {
+		return nil, "", 0, errors.NewInvalid("invalid %s '%s'", PageTokenKey, token)
+	}
+	if pageSize == 0 {
+		pageSize = snapshot.pageSize
+	}
+	end := offset + pageSize
+	if end >= len(snapshot.objects) {
+		l.discard(element)
+		return snapshot.objects[offset:], "", snapshot.revision, nil
+	}
+	snapshot.expires = time.Now().Add(listingTTL)
+	l.order.MoveToBack(element)
+	return snapshot.objects[offset:end], encodePageToken(id, end), snapshot.revision, nil
+}
+
+// expire discards the listings that expired before the given time; it must be called with the lock held
+func (l *listings) expire(now time.Time) {
+	for element := l.order.Front(); element != nil; {
+		next := element.Next()
+		if now.After(element.Value.(*listing).expires) {
+			l.discard(element)
+		}
+		element = next
+	}
+}
+
+// discard discards the listing of the given element; it must be called with the lock held
+func (l *listings) discard(element *list.Element) {
+	delete(l.open, element.Value.(*listing).id)
+	l.order.Remove(element)
+}
+
+// listingHash returns a hash of the filters and sort order of a listing
+func listingHash(req *topoapi.ListRequest, sortSpec string) uint64 {
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%d/%s/%s", req.SortOrder, sortSpec, proto.CompactTextString(req.Filters))
+	return hash.Sum64()
+}
+
+// encodePageToken returns an opaque token for the page of a listing starting at the given offset
+func encodePageToken(id string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s/%d", id, offset)))
+}
+
+// decodePageToken returns the listing and offset of the page identified by the given token
+func decodePageToken(token string) (string, int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		if id, value, ok := strings.Cut(string(bytes), "/"); ok {
+			if offset, err := strconv.Atoi(value); err == nil && offset >= 0 {
+				return id, offset, nil
+			}
+		}
+	}
+	return "", 0, errors.NewInvalid("invalid %s '%s'", PageTokenKey, token)
+}
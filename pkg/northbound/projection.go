@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package northbound
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// FieldsKey is the gRPC metadata key with which Get, List, Query and Watch clients request only some of the fields
+// of objects, as a comma-separated list of aspects ("aspect:<aspect type>"), labels ("label:<key>") and "relations"
+// for the relation IDs of entities. "aspect:*" and "label:*" include all the aspects or labels; the ID, type,
+// revision and kind of objects, and the source and target of relations, are always included.
+const FieldsKey = "onos-topo-fields"
+
+const (
+	aspectFieldPrefix = "aspect:"
+	labelFieldPrefix  = "label:"
+	relationsField    = "relations"
+	allFields         = "*"
+)
+
+// fieldMask is the set of fields of objects included in responses
+type fieldMask struct {
+	aspects   map[string]bool
+	labels    map[string]bool
+	relations bool
+}
+
+// fieldsRequest returns the field mask requested in the metadata of the given context, or nil if all fields are
+// requested
+func fieldsRequest(ctx context.Context) (*fieldMask, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	values := md.Get(FieldsKey)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return parseFieldMask(values[0])
+}
+
+// parseFieldMask parses a comma-separated list of fields
+func parseFieldMask(spec string) (*fieldMask, error) {
+	mask := &fieldMask{
+		aspects: make(map[string]bool),
+		labels:  make(map[string]bool),
+	}
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		switch {
+		case field == "":
+		case field == relationsField:
+			mask.relations = true
+		case strings.HasPrefix(field, aspectFieldPrefix) && len(field) > len(aspectFieldPrefix):
+			mask.aspects[strings.TrimPrefix(field, aspectFieldPrefix)] = true
+		case strings.HasPrefix(field, labelFieldPrefix) && len(field) > len(labelFieldPrefix):
+			mask.labels[strings.TrimPrefix(field, labelFieldPrefix)] = true
+		default:
+			return nil, errors.NewInvalid("invalid %s field '%s'", FieldsKey, field)
+		}
+	}
+	return mask, nil
+}
+
+// project returns a copy of the given object with only the fields of the mask; the object is returned unchanged
+// if the mask is nil
+func (m *fieldMask) project(object *topoapi.Object) *topoapi.Object {
+	if m == nil {
+		return object
+	}
+	projected := *object
+	projected.Aspects = nil
+	for aspectType, aspect := range object.Aspects {
+		if m.aspects[allFields] || m.aspects[aspectType] {
+			if projected.Aspects == nil {
+				projected.Aspects = make(map[string]*types.Any)
+			}
+			projected.Aspects[aspectType] = aspect
+		}
+	}
+	projected.Labels = nil
+	for key, value := range object.Labels {
+		if m.labels[allFields] || m.labels[key] {
+			if projected.Labels == nil {
+				projected.Labels = make(map[string]string)
+			}
+			projected.Labels[key] = value
+		}
+	}
+	if entity := object.GetEntity(); entity != nil && !m.relations {
+		projected.Obj = &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: entity.KindID}}
+	}
+	return &projected
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package northbound
+
+import (
+	"testing"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldMask(t *testing.T) {
+	object := &topoapi.Object{
+		ID:       "sw-1",
+		Revision: 3,
+		Type:     topoapi.Object_ENTITY,
+		Obj:      &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "switch", SrcRelationIDs: []topoapi.ID{"r1"}}},
+		Labels:   map[string]string{"pod": "pod-1", "rack": "r1"},
+	}
+	assert.NoError(t, object.SetAspectBytes("onos.topo.Location", []byte(`{"lat": 1, "lng": 2}`)))
+	assert.NoError(t, object.SetAspectBytes("onos.topo.P4RTServerInfo", []byte(`{"pipelines": []}`)))
+
+	// Only the requested aspects and labels must be included, without changing the object
+	mask, err := parseFieldMask("label:pod, aspect:onos.topo.Location")
+	assert.NoError(t, err)
+	projected := mask.project(object)
+	assert.Equal(t, topoapi.ID("sw-1"), projected.ID)
+	assert.Equal(t, topoapi.Revision(3), projected.Revision)
+	assert.Equal(t, map[string]string{"pod": "pod-1"}, projected.Labels)
+	assert.Len(t, projected.Aspects, 1)
+	assert.NotNil(t, projected.Aspects["onos.topo.Location"])
+	assert.Equal(t, topoapi.ID("switch"), projected.GetEntity().KindID)
+	assert.Empty(t, projected.GetEntity().SrcRelationIDs)
+	assert.Len(t, object.Labels, 2)
+	assert.Len(t, object.Aspects, 2)
+	assert.Len(t, object.GetEntity().SrcRelationIDs, 1)
+
+	// Wildcards must include all the aspects or labels
+	mask, err = parseFieldMask("label:*,relations")
+	assert.NoError(t, err)
+	projected = mask.project(object)
+	assert.Len(t, projected.Labels, 2)
+	assert.Nil(t, projected.Aspects)
+	assert.Equal(t, []topoapi.ID{"r1"}, projected.GetEntity().SrcRelationIDs)
+
+	// An empty mask must only include the identity of objects
+	mask, err = parseFieldMask("")
+	assert.NoError(t, err)
+	projected = mask.project(object)
+	assert.Nil(t, projected.Labels)
+	assert.Nil(t, projected.Aspects)
+
+	// A nil mask must include all fields
+	var all *fieldMask
+	assert.Same(t, object, all.project(object))
+
+	for _, spec := range []string{"labels", "aspect:", "label:pod,id"} {
+		_, err := parseFieldMask(spec)
+		assert.True(t, errors.IsInvalid(err), spec)
+	}
+}
@@ -0,0 +1,334 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package northbound
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+)
+
+// SchemaTypeURL is the type URL of the aspects of a Kind that carry the JSON schema of an aspect type rather
+// than its default value. The aspects of the entities and relations of the kind must then match the schema.
+const SchemaTypeURL = "application/schema+json"
+
+// schemaTypes are the JSON types a schema can require
+var schemaTypes = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+	"number":  true,
+	"integer": true,
+	"string":  true,
+}
+
+// schemaAnnotations are the keywords a schema can carry that do not affect validation
+var schemaAnnotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+}
+
+// schema is a JSON schema describing the value of an aspect. Only the validation keywords below are supported, and
+// schemas using other keywords, such as "$ref", "oneOf" or "format", are rejected rather than partially enforced.
+type schema struct {
+	Type                 schemaTypeList     `json:"type"`
+	Enum                 []interface{}      `json:"enum"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *additionalSchema  `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	pattern              *regexp.Regexp
+	// unsupported are the keywords of the schema that are neither supported nor annotations
+	unsupported []string
+}
+
+// schemaKeywords are the validation keywords supported by schemas
+var schemaKeywords = map[string]bool{
+	"type":                 true,
+	"enum":                 true,
+	"properties":           true,
+	"required":             true,
+	"additionalProperties": true,
+	"items":                true,
+	"minItems":             true,
+	"maxItems":             true,
+	"minimum":              true,
+	"maximum":              true,
+	"minLength":            true,
+	"maxLength":            true,
+	"pattern":              true,
+}
+
+func (s *schema) UnmarshalJSON(data []byte) error {
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return err
+	}
+	type plainSchema schema
+	if err := json.Unmarshal(data, (*plainSchema)(s)); err != nil {
+		return err
+	}
+	for keyword := range keywords {
+		if !schemaKeywords[keyword] && !schemaAnnotations[keyword] {
+			s.unsupported = append(s.unsupported, keyword)
+		}
+	}
+	sort.Strings(s.unsupported)
+	return nil
+}
+
+// schemaTypeList is the value of the "type" keyword, which is either a single type or a list of types
+type schemaTypeList []string
+
+func (t *schemaTypeList) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = schemaTypeList{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = names
+	return nil
+}
+
+// additionalSchema is the value of the "additionalProperties" keyword, which is either a boolean or a schema
+type additionalSchema struct {
+	allowed bool
+	schema  *schema
+}
+
+func (a *additionalSchema) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.allowed); err == nil {
+		return nil
+	}
+	a.allowed = true
+	return json.Unmarshal(data, &a.schema)
+}
+
+// parseSchema parses the given JSON schema
+func parseSchema(data []byte) (*schema, error) {
+	s := &schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compile checks the keywords of the schema and its subschemas, and compiles their patterns
+func (s *schema) compile(path string) error {
+	if len(s.unsupported) > 0 {
+		return fmt.Errorf("%sunsupported keywords '%s'", pathPrefix(path), strings.Join(s.unsupported, "', '"))
+	}
+	for _, name := range s.Type {
+		if !schemaTypes[name] {
+			return fmt.Errorf("%sunknown type '%s'", pathPrefix(path), name)
+		}
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%sinvalid pattern: %v", pathPrefix(path), err)
+		}
+		s.pattern = pattern
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%sproperty schema cannot be null", pathPrefix(joinPath(path, name)))
+		}
+		if err := property.compile(joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.schema != nil {
+		if err := s.AdditionalProperties.schema.compile(joinPath(path, "*")); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateJSON validates the given JSON value against the schema, returning an error listing each field that
+// does not match the schema
+func (s *schema) validateJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return errors.NewInvalid("invalid JSON: %v", err)
+	}
+	var violations []string
+	s.validate("", value, &violations)
+	if len(violations) > 0 {
+		return errors.NewInvalid("%s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// validate appends the violations of the schema by the value at the given path
+func (s *schema) validate(path string, value interface{}, violations *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, pathPrefix(path)+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Type) > 0 && !s.Type.matches(value) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), typeOf(value))
+		return
+	}
+	if len(s.Enum) > 0 && !s.enumContains(value) {
+		fail("value is not one of the allowed values")
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, pathPrefix(joinPath(path, name))+"is required")
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				property.validate(joinPath(path, name), v[name], violations)
+			} else if s.AdditionalProperties != nil {
+				if !s.AdditionalProperties.allowed {
+					*violations = append(*violations, pathPrefix(joinPath(path, name))+"is not allowed")
+				} else if s.AdditionalProperties.schema != nil {
+					s.AdditionalProperties.schema.validate(joinPath(path, name), v[name], violations)
+				}
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("expected at least %d items, got %d", *s.MinItems, len(v))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("expected at most %d items, got %d", *s.MaxItems, len(v))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+	case json.Number:
+		number, _ := v.Float64()
+		if s.Minimum != nil && number < *s.Minimum {
+			fail("expected a value of at least %v, got %v", *s.Minimum, v)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			fail("expected a value of at most %v, got %v", *s.Maximum, v)
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("expected at least %d characters, got %d", *s.MinLength, length)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("expected at most %d characters, got %d", *s.MaxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("value does not match pattern '%s'", s.Pattern)
+		}
+	}
+}
+
+// matches returns whether the value has one of the types of the list
+func (t schemaTypeList) matches(value interface{}) bool {
+	actual := typeOf(value)
+	for _, name := range t {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// enumContains returns whether the value is equal to one of the values of the "enum" keyword
+func (s *schema) enumContains(value interface{}) bool {
+	if number, ok := value.(json.Number); ok {
+		// enum values are decoded as floats
+		value, _ = number.Float64()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range s.Enum {
+		if candidate, err := json.Marshal(allowed); err == nil && bytes.Equal(data, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON type of a decoded value
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case json.Number:
+		if number, err := v.Float64(); err == nil && number == math.Trunc(number) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	}
+	return "unknown"
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func pathPrefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + ": "
+}
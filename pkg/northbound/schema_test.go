@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package northbound
+
+import (
+	"testing"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"lat": {"type": "number", "minimum": -90, "maximum": 90},
+		"lng": {"type": "number", "minimum": -180, "maximum": 180},
+		"name": {"type": "string", "maxLength": 8, "pattern": "^[a-z]+$"},
+		"mode": {"enum": ["auto", "manual", 1]},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"sectors": {"type": "integer"}
+	},
+	"required": ["lat", "lng"],
+	"additionalProperties": false
+}`
+
+func TestSchema(t *testing.T) {
+	schema, err := parseSchema([]byte(testSchema))
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.validateJSON([]byte(`{"lat": 1.5, "lng": -3}`)))
+	assert.NoError(t, schema.validateJSON([]byte(`{"lat": 1, "lng": 2, "name": "cell", "mode": "auto", "tags": ["a"], "sectors": 3.0}`)))
+	assert.NoError(t, schema.validateJSON([]byte(`{"lat": 1, "lng": 2, "mode": 1.0}`)))
+
+	for value, message := range map[string]string{
+		`{"lat": "1.5", "lng": 2}`:                      "lat: expected number, got string",
+		`{"lat": 1}`:                                    "lng: is required",
+		`{"lat": 91, "lng": 2}`:                         "lat: expected a value of at most 90, got 91",
+		`{"lat": 1, "lng": 2, "alt": 3}`:                "alt: is not allowed",
+		`{"lat": 1, "lng": 2, "name": "Cell"}`:          "name: value does not match pattern '^[a-z]+$'",
+		`{"lat": 1, "lng": 2, "name": "cellcellc"}`:     "name: expected at most 8 characters, got 9",
+		`{"lat": 1, "lng": 2, "mode": "off"}`:           "mode: value is not one of the allowed values",
+		`{"lat": 1, "lng": 2, "tags": ["a", 2]}`:        "tags[1]: expected string, got integer",
+		`{"lat": 1, "lng": 2, "tags": ["a", "b", "c"]}`: "tags: expected at most 2 items, got 3",
+		`{"lat": 1, "lng": 2, "sectors": 1.5}`:          "sectors: expected integer, got number",
+		`[]`:                                            "expected object, got array",
+		`{"lat": true, "lng": null}`:                    "lat: expected number, got boolean; lng: expected number, got null",
+	} {
+		err := schema.validateJSON([]byte(value))
+		assert.True(t, errors.IsInvalid(err), value)
+		assert.EqualError(t, err, message, value)
+	}
+
+	_, err = parseSchema([]byte(`{"type": "float"}`))
+	assert.Error(t, err)
+	_, err = parseSchema([]byte(`{"properties": {"name": {"pattern": "["}}}`))
+	assert.Error(t, err)
+	_, err = parseSchema([]byte(`{"type": 1}`))
+	assert.Error(t, err)
+
+	// Keywords that are not supported must be rejected rather than ignored, and annotations must be accepted
+	_, err = parseSchema([]byte(`{"title": "Location", "description": "position", "$schema": "http://json-schema.org/draft-07/schema#"}`))
+	assert.NoError(t, err)
+	for value, message := range map[string]string{
+		`{"$ref": "#/definitions/location"}`:                              "unsupported keywords '$ref'",
+		`{"oneOf": [{"type": "string"}, {"type": "number"}]}`:             "unsupported keywords 'oneOf'",
+		`{"properties": {"ip": {"type": "string", "format": "ipv4"}}}`:    "ip: unsupported keywords 'format'",
+		`{"items": {"const": 1, "exclusiveMinimum": 0}, "type": "array"}`: "[]: unsupported keywords 'const', 'exclusiveMinimum'",
+	} {
+		_, err = parseSchema([]byte(value))
+		assert.EqualError(t, err, message, value)
+	}
+}
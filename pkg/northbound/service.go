@@ -6,9 +6,11 @@ package northbound
 
 import (
 	"context"
-	"fmt"
-	"sort"
+	"encoding/json"
+	"strconv"
+	"time"
 
+	"github.com/gogo/protobuf/types"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
 
 	topoapi "github.com/onosproject/onos-api/go/onos/topo"
@@ -16,40 +18,169 @@ import (
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
 	"github.com/onosproject/onos-topo/pkg/store"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 var log = logging.GetLogger()
 
-// NewService returns a new topo Service
-func NewService(store store.Store) northbound.Service {
+// ResumeAfterRevisionKey is the gRPC metadata key with which Watch clients request to resume from the journaled
+// events following the revision of the last event they observed, rather than replaying the whole topology
+const ResumeAfterRevisionKey = "onos-topo-resume-after-revision"
+
+// CoalesceWindowKey is the gRPC metadata key with which Watch clients request the events for the same object within
+// a window, e.g. "500ms", to be coalesced into a single event carrying the latest state of the object
+const CoalesceWindowKey = "onos-topo-coalesce-window"
+
+// MaxEventRateKey is the gRPC metadata key with which Watch clients request at most a number of events per second;
+// events queued while the stream is rate limited are coalesced
+const MaxEventRateKey = "onos-topo-max-event-rate"
+
+// ChangesKey is the gRPC metadata key with which Watch clients request UPDATED and REMOVED events to carry the
+// changes to their object since its previous state, as the JSON encoded onos.topo.Changes aspect
+const ChangesKey = "onos-topo-changes"
+
+// BookmarksKey is the gRPC metadata key with which Watch clients request a bookmark event once the replay of the
+// stream is complete; bookmark events are NONE events for an object without ID or type, carrying the JSON encoded
+// onos.topo.Bookmark aspect
+const BookmarksKey = "onos-topo-bookmarks"
+
+// HeartbeatIntervalKey is the gRPC metadata key with which Watch clients request heartbeat bookmark events at an
+// interval, e.g. "30s", carrying the revision of the store
+const HeartbeatIntervalKey = "onos-topo-heartbeat-interval"
+
+// TraversalKey is the gRPC metadata key with which Query clients request a multi-hop traversal of relations,
+// encoded as a JSON store.Traversal, instead of a query of the request filters. A traversal exceeding its limits
+// streams the objects it reached until then, and is marked by the TraversalTruncatedKey response trailer metadata.
+const TraversalKey = "onos-topo-traversal"
+
+// TraversalTruncatedKey is the gRPC response trailer metadata key set to "true" when a traversal stops at its limits
+const TraversalTruncatedKey = "onos-topo-traversal-truncated"
+
+// PathsKey is the gRPC metadata key with which Query clients request the shortest paths between two entities,
+// encoded as a JSON store.PathQuery, instead of a query of the request filters. The entities and relations of each
+// path are streamed in order from its source, and the number of objects and the weight of each path are returned
+// in the PathLengthsKey and PathWeightsKey response header metadata.
+const PathsKey = "onos-topo-paths"
+
+// PathLengthsKey is the gRPC response header metadata key listing the number of objects streamed for each path
+const PathLengthsKey = "onos-topo-path-lengths"
+
+// PathWeightsKey is the gRPC response header metadata key listing the weight of each path
+const PathWeightsKey = "onos-topo-path-weights"
+
+// GeoKey is the gRPC metadata key with which Query clients request the objects whose onos.topo.Location aspect is
+// within a bounding box, within a radius or nearest to a point, encoded as a JSON store.GeoQuery. The objects must
+// also match the request filters, and the distance of each streamed object is returned in the GeoDistancesKey
+// response header metadata.
+const GeoKey = "onos-topo-geo"
+
+// GeoDistancesKey is the gRPC response header metadata key listing the distance in meters of each located object
+const GeoDistancesKey = "onos-topo-geo-distances"
+
+// KindValidation determines how objects that do not conform to their kind are handled by Create and Update. Aspects
+// that do not match the schemas of their kind are rejected whatever the mode.
+type KindValidation int
+
+const (
+	// KindValidationOff stores objects without validating them against their kind
+	KindValidationOff KindValidation = iota
+	// KindValidationWarn logs a warning for objects that do not conform to their kind, but stores them
+	KindValidationWarn
+	// KindValidationEnforce rejects objects that do not conform to their kind
+	KindValidationEnforce
+)
+
+// String returns the name of the kind validation mode
+func (v KindValidation) String() string {
+	switch v {
+	case KindValidationOff:
+		return "off"
+	case KindValidationWarn:
+		return "warn"
+	case KindValidationEnforce:
+		return "enforce"
+	}
+	return "unknown"
+}
+
+// ParseKindValidation returns the kind validation mode with the given name
+func ParseKindValidation(name string) (KindValidation, error) {
+	switch name {
+	case KindValidationOff.String():
+		return KindValidationOff, nil
+	case KindValidationWarn.String():
+		return KindValidationWarn, nil
+	case KindValidationEnforce.String():
+		return KindValidationEnforce, nil
+	}
+	return KindValidationOff, errors.NewInvalid("unknown kind validation mode '%s'", name)
+}
+
+// NewService returns a new topo Service validating objects against their kind according to the given mode;
+// the given watch options are applied to every Watch request
+func NewService(store store.Store, kindValidation KindValidation, watchOpts ...store.WatchOption) northbound.Service {
 	return &Service{
-		store: store,
+		store:          store,
+		kindValidation: kindValidation,
+		watchOpts:      watchOpts,
 	}
 }
 
 // Service is a Service implementation for administration.
 type Service struct {
-	store store.Store
+	store          store.Store
+	kindValidation KindValidation
+	watchOpts      []store.WatchOption
 }
 
 // Register registers the Service with the gRPC server.
 func (s Service) Register(r *grpc.Server) {
 	server := &Server{
-		objectStore: s.store,
+		objectStore:    s.store,
+		kindValidation: s.kindValidation,
+		watchOpts:      s.watchOpts,
 	}
 	topoapi.RegisterTopoServer(r, server)
 }
 
 // Server implements the gRPC service for administrative facilities.
 type Server struct {
-	objectStore store.Store
+	objectStore    store.Store
+	kindValidation KindValidation
+	watchOpts      []store.WatchOption
+	listings       listings
 }
 
 // Create creates a new topology object
 func (s *Server) Create(ctx context.Context, req *topoapi.CreateRequest) (*topoapi.CreateResponse, error) {
 	log.Infof("Received CreateRequest %+v", req)
+	transaction, err := transactionRequest(ctx)
+	if err != nil {
+		log.Warnf("CreateRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	if transaction {
+		object, err := s.transaction(ctx, req.Object)
+		if err != nil {
+			log.Warnf("CreateRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+		res := &topoapi.CreateResponse{
+			Object: object,
+		}
+		log.Infof("Sending CreateResponse %+v", res)
+		return res, nil
+	}
 	object := req.Object
-	err := s.objectStore.Create(ctx, object)
+	if err := checkTransactionAspect(object); err != nil {
+		log.Warnf("CreateRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	if err := s.validate(ctx, object); err != nil {
+		log.Warnf("CreateRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	err = s.objectStore.Create(ctx, object)
 	if err != nil {
 		log.Warnf("CreateRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
@@ -64,13 +195,18 @@ func (s *Server) Create(ctx context.Context, req *topoapi.CreateRequest) (*topoa
 // Get retrieves the specified topology object
 func (s *Server) Get(ctx context.Context, req *topoapi.GetRequest) (*topoapi.GetResponse, error) {
 	log.Infof("Received GetRequest %+v", req)
+	fields, err := fieldsRequest(ctx)
+	if err != nil {
+		log.Warnf("GetRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
 	object, err := s.objectStore.Get(ctx, req.ID)
 	if err != nil {
 		log.Warnf("GetRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
 	}
 	res := &topoapi.GetResponse{
-		Object: object,
+		Object: fields.project(object),
 	}
 	log.Infof("Sending GetResponse %+v", res)
 	return res, nil
@@ -79,6 +215,14 @@ func (s *Server) Get(ctx context.Context, req *topoapi.GetRequest) (*topoapi.Get
 // Update creates an existing topology object
 func (s *Server) Update(ctx context.Context, req *topoapi.UpdateRequest) (*topoapi.UpdateResponse, error) {
 	log.Infof("Received UpdateRequest %+v", req)
+	if err := checkTransactionAspect(req.Object); err != nil {
+		log.Warnf("UpdateRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	if err := s.validate(ctx, req.Object); err != nil {
+		log.Warnf("UpdateRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
 	err := s.objectStore.Update(ctx, req.Object)
 	if err != nil {
 		log.Warnf("UpdateRequest %+v failed: %v", req, err)
@@ -107,16 +251,109 @@ func (s *Server) Delete(ctx context.Context, req *topoapi.DeleteRequest) (*topoa
 // Query streams back results of a query
 func (s *Server) Query(req *topoapi.QueryRequest, server topoapi.Topo_QueryServer) error {
 	log.Infof("Received QueryRequest %+v", req)
+	filters, err := filterRequest(server.Context(), req.Filters)
+	if err != nil {
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	req.Filters = filters
+	fields, err := fieldsRequest(server.Context())
+	if err != nil {
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	if md, ok := metadata.FromIncomingContext(server.Context()); ok {
+		if values := md.Get(TraversalKey); len(values) > 0 {
+			return s.traverse(req, values[0], fields, server)
+		}
+		if values := md.Get(PathsKey); len(values) > 0 {
+			return s.paths(req, values[0], fields, server)
+		}
+		if values := md.Get(GeoKey); len(values) > 0 {
+			return s.locate(req, values[0], fields, server)
+		}
+	}
 
 	ch := make(chan *topoapi.Object, 512)
+	errCh := make(chan error, 1)
 	go func() {
-		if err := s.objectStore.Query(server.Context(), ch, req.Filters); err != nil {
-			log.Warnf("QueryRequest %+v failed: %v", req, err)
+		errCh <- s.objectStore.Query(server.Context(), ch, req.Filters)
+	}()
+
+	for object := range ch {
+		res := &topoapi.QueryResponse{Object: fields.project(object)}
+		log.Debugf("Sending QueryResponse %+v", res)
+		if err := server.Send(res); err != nil {
+			log.Warnf("QueryResponse %+v failed: %v", res, err)
+			return err
 		}
+	}
+	if err := <-errCh; err != nil {
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	return nil
+}
+
+// traverse streams back the objects reached by the given JSON encoded traversal
+func (s *Server) traverse(req *topoapi.QueryRequest, value string, fields *fieldMask, server topoapi.Topo_QueryServer) error {
+	var traversal store.Traversal
+	if err := json.Unmarshal([]byte(value), &traversal); err != nil {
+		err = errors.NewInvalid("invalid %s: %v", TraversalKey, err)
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+
+	ch := make(chan *topoapi.Object, 512)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.objectStore.Traverse(server.Context(), ch, traversal)
 	}()
 
 	for object := range ch {
-		res := &topoapi.QueryResponse{Object: object}
+		res := &topoapi.QueryResponse{Object: fields.project(object)}
+		log.Debugf("Sending QueryResponse %+v", res)
+		if err := server.Send(res); err != nil {
+			log.Warnf("QueryResponse %+v failed: %v", res, err)
+			return err
+		}
+	}
+	if err := <-errCh; err != nil {
+		if err == store.ErrTraversalTruncated {
+			server.SetTrailer(metadata.Pairs(TraversalTruncatedKey, "true"))
+			return nil
+		}
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	return nil
+}
+
+// locate streams back the objects located by the given JSON encoded geo query that match the request filters
+func (s *Server) locate(req *topoapi.QueryRequest, value string, fields *fieldMask, server topoapi.Topo_QueryServer) error {
+	var query store.GeoQuery
+	if err := json.Unmarshal([]byte(value), &query); err != nil {
+		err = errors.NewInvalid("invalid %s: %v", GeoKey, err)
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	query.Filters = req.Filters
+	located, err := s.objectStore.Locate(server.Context(), query)
+	if err != nil {
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+
+	header := metadata.MD{}
+	for _, l := range located {
+		header.Append(GeoDistancesKey, strconv.FormatFloat(l.Distance, 'g', -1, 64))
+	}
+	if err := server.SetHeader(header); err != nil {
+		return err
+	}
+
+	for _, l := range located {
+		res := &topoapi.QueryResponse{Object: fields.project(l.Object)}
 		log.Debugf("Sending QueryResponse %+v", res)
 		if err := server.Send(res); err != nil {
 			log.Warnf("QueryResponse %+v failed: %v", res, err)
@@ -126,24 +363,149 @@ func (s *Server) Query(req *topoapi.QueryRequest, server topoapi.Topo_QueryServe
 	return nil
 }
 
+// paths streams back the objects of the shortest paths requested by the given JSON encoded path query
+func (s *Server) paths(req *topoapi.QueryRequest, value string, fields *fieldMask, server topoapi.Topo_QueryServer) error {
+	var query store.PathQuery
+	if err := json.Unmarshal([]byte(value), &query); err != nil {
+		err = errors.NewInvalid("invalid %s: %v", PathsKey, err)
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	paths, err := s.objectStore.Paths(server.Context(), query)
+	if err != nil {
+		log.Warnf("QueryRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+
+	header := metadata.MD{}
+	for _, path := range paths {
+		header.Append(PathLengthsKey, strconv.Itoa(len(path.EntityIDs)+len(path.RelationIDs)))
+		header.Append(PathWeightsKey, strconv.FormatFloat(path.Weight, 'g', -1, 64))
+	}
+	if err := server.SetHeader(header); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		for i, entityID := range path.EntityIDs {
+			ids := []topoapi.ID{entityID}
+			if i < len(path.RelationIDs) {
+				ids = append(ids, path.RelationIDs[i])
+			}
+			for _, id := range ids {
+				object, err := s.objectStore.Get(server.Context(), id)
+				if err != nil {
+					log.Warnf("QueryRequest %+v failed: %v", req, err)
+					return errors.Status(err).Err()
+				}
+				res := &topoapi.QueryResponse{Object: fields.project(object)}
+				log.Debugf("Sending QueryResponse %+v", res)
+				if err := server.Send(res); err != nil {
+					log.Warnf("QueryResponse %+v failed: %v", res, err)
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // List returns list of all objects
 func (s *Server) List(ctx context.Context, req *topoapi.ListRequest) (*topoapi.ListResponse, error) {
 	log.Infof("Received ListRequest %+v", req)
-	objects, err := s.objectStore.List(ctx, req.Filters)
+	filters, err := filterRequest(ctx, req.Filters)
 	if err != nil {
 		log.Warnf("ListRequest %+v failed: %v", req, err)
 		return nil, errors.Status(err).Err()
 	}
+	req.Filters = filters
+	pageSize, pageToken, err := pageRequest(ctx)
+	if err != nil {
+		log.Warnf("ListRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	sortSpec, sortKeys, err := sortRequest(ctx)
+	if err != nil {
+		log.Warnf("ListRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	fields, err := fieldsRequest(ctx)
+	if err != nil {
+		log.Warnf("ListRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+	groupBy, count, err := groupByRequest(ctx)
+	if err != nil {
+		log.Warnf("ListRequest %+v failed: %v", req, err)
+		return nil, errors.Status(err).Err()
+	}
+
+	if count {
+		if pageSize > 0 || pageToken != "" {
+			err = errors.NewInvalid("%s cannot be combined with pagination", GroupByKey)
+			log.Warnf("ListRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+		counts, err := s.objectStore.Count(ctx, req.Filters, groupBy)
+		if err != nil {
+			log.Warnf("ListRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+		header, err := countHeader(counts)
+		if err != nil {
+			log.Warnf("ListRequest %+v failed: %v", req, err)
+			return nil, errors.Status(errors.NewInternal(err.Error())).Err()
+		}
+		if err := grpc.SetHeader(ctx, header); err != nil {
+			log.Warnf("ListRequest %+v failed: %v", req, err)
+			return nil, err
+		}
+		log.Infof("Sending ListResponse with %d groups", len(counts))
+		return &topoapi.ListResponse{}, nil
+	}
 
-	if req.SortOrder != topoapi.SortOrder_UNORDERED {
-		sort.Slice(objects, func(i, j int) bool {
-			if req.SortOrder == topoapi.SortOrder_DESCENDING {
-				return objects[i].ID > objects[j].ID
+	var objects []topoapi.Object
+	paginated := pageSize > 0 || pageToken != ""
+	if pageToken == "" {
+		objects, err = s.objectStore.List(ctx, req.Filters)
+		if err != nil {
+			log.Warnf("ListRequest %+v failed: %v", req, err)
+			return nil, errors.Status(err).Err()
+		}
+		// Paginated listings are always sorted, so that their pages follow a consistent order
+		if req.SortOrder != topoapi.SortOrder_UNORDERED || len(sortKeys) > 0 || paginated {
+			sortKeys = append(sortKeys, store.SortKey{Field: store.SortByID, Descending: req.SortOrder == topoapi.SortOrder_DESCENDING})
+			store.SortObjects(objects, sortKeys)
+		}
+	}
+	if paginated {
+		var nextPageToken string
+		var revision topoapi.Revision
+		if pageToken == "" {
+			objects, nextPageToken, revision = s.listings.start(req, sortSpec, objects, pageSize)
+		} else {
+			objects, nextPageToken, revision, err = s.nextPage(ctx, req, sortSpec, pageToken, pageSize)
+			if err != nil {
+				log.Warnf("ListRequest %+v failed: %v", req, err)
+				return nil, errors.Status(err).Err()
 			}
-			return objects[i].ID < objects[j].ID
-		})
+		}
+		header := metadata.Pairs(ListingRevisionKey, strconv.FormatUint(uint64(revision), 10))
+		if nextPageToken != "" {
+			header.Set(NextPageTokenKey, nextPageToken)
+		}
+		if err := grpc.SetHeader(ctx, header); err != nil {
+			return nil, err
+		}
 	}
 
+	if fields != nil {
+		projected := make([]topoapi.Object, 0, len(objects))
+		for i := range objects {
+			projected = append(projected, *fields.project(&objects[i]))
+		}
+		objects = projected
+	}
 	res := &topoapi.ListResponse{
 		Objects: objects,
 	}
@@ -151,13 +513,123 @@ func (s *Server) List(ctx context.Context, req *topoapi.ListRequest) (*topoapi.L
 	return res, nil
 }
 
+// nextPage reads the objects of the page of a listing identified by the given token from the store. The objects
+// are read at least at the revision they were listed at; objects removed since the first page are skipped.
+func (s *Server) nextPage(ctx context.Context, req *topoapi.ListRequest, sortSpec string, pageToken string, pageSize int) ([]topoapi.Object, string, topoapi.Revision, error) {
+	listed, nextPageToken, revision, err := s.listings.next(req, sortSpec, pageToken, pageSize)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	objects := make([]topoapi.Object, 0, len(listed))
+	for _, l := range listed {
+		object, err := s.objectStore.Get(ctx, l.id, store.WithMinRevision(l.id, l.revision))
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", 0, err
+		}
+		objects = append(objects, *object)
+	}
+	return objects, nextPageToken, revision, nil
+}
+
 // Watch streams topology changes
 func (s *Server) Watch(req *topoapi.WatchRequest, server topoapi.Topo_WatchServer) error {
 	log.Infof("Received WatchRequest %+v", req)
-	var watchOpts []store.WatchOption
+	filters, err := filterRequest(server.Context(), req.Filters)
+	if err != nil {
+		log.Warnf("WatchRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	req.Filters = filters
+	fields, err := fieldsRequest(server.Context())
+	if err != nil {
+		log.Warnf("WatchRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	}
+	if fields != nil {
+		// The events of a transaction are marked as a group even if the client requested only some of the aspects
+		fields.aspects[store.TransactionGroupAspect] = true
+	}
+	watchOpts := append([]store.WatchOption{}, s.watchOpts...)
 	if !req.Noreplay {
 		watchOpts = append(watchOpts, store.WithReplay())
 	}
+	if md, ok := metadata.FromIncomingContext(server.Context()); ok {
+		if values := md.Get(ResumeAfterRevisionKey); len(values) > 0 {
+			revision, err := strconv.ParseUint(values[0], 10, 64)
+			if err != nil {
+				err = errors.NewInvalid("invalid %s '%s'", ResumeAfterRevisionKey, values[0])
+				log.Warnf("WatchRequest %+v failed: %v", req, err)
+				return errors.Status(err).Err()
+			}
+			watchOpts = append(watchOpts, store.WithResumeAfter(topoapi.Revision(revision)))
+		}
+		if values := md.Get(CoalesceWindowKey); len(values) > 0 {
+			window, err := time.ParseDuration(values[0])
+			if err != nil || window < 0 {
+				err = errors.NewInvalid("invalid %s '%s'", CoalesceWindowKey, values[0])
+				log.Warnf("WatchRequest %+v failed: %v", req, err)
+				return errors.Status(err).Err()
+			}
+			watchOpts = append(watchOpts, store.WithCoalesceWindow(window))
+		}
+		if values := md.Get(MaxEventRateKey); len(values) > 0 {
+			maxRate, err := strconv.ParseFloat(values[0], 64)
+			if err != nil || maxRate <= 0 {
+				err = errors.NewInvalid("invalid %s '%s'", MaxEventRateKey, values[0])
+				log.Warnf("WatchRequest %+v failed: %v", req, err)
+				return errors.Status(err).Err()
+			}
+			watchOpts = append(watchOpts, store.WithMaxEventRate(maxRate))
+		}
+		if values := md.Get(ChangesKey); len(values) > 0 {
+			changes, err := strconv.ParseBool(values[0])
+			if err != nil {
+				err = errors.NewInvalid("invalid %s '%s'", ChangesKey, values[0])
+				log.Warnf("WatchRequest %+v failed: %v", req, err)
+				return errors.Status(err).Err()
+			}
+			if changes {
+				watchOpts = append(watchOpts, store.WithChanges())
+				if fields != nil {
+					// The changes are sent even if the client requested only some of the aspects
+					fields.aspects[store.ChangesAspect] = true
+				}
+			}
+		}
+		if values := md.Get(BookmarksKey); len(values) > 0 {
+			bookmarks, err := strconv.ParseBool(values[0])
+			if err != nil {
+				err = errors.NewInvalid("invalid %s '%s'", BookmarksKey, values[0])
+				log.Warnf("WatchRequest %+v failed: %v", req, err)
+				return errors.Status(err).Err()
+			}
+			if bookmarks {
+				watchOpts = append(watchOpts, store.WithBookmarks())
+			}
+		}
+		if values := md.Get(HeartbeatIntervalKey); len(values) > 0 {
+			interval, err := time.ParseDuration(values[0])
+			if err != nil || interval <= 0 {
+				err = errors.NewInvalid("invalid %s '%s'", HeartbeatIntervalKey, values[0])
+				log.Warnf("WatchRequest %+v failed: %v", req, err)
+				return errors.Status(err).Err()
+			}
+			watchOpts = append(watchOpts, store.WithHeartbeat(interval))
+		}
+		if fields != nil {
+			// Bookmarks are only described by their aspect
+			fields.aspects[store.BookmarkAspect] = true
+		}
+	}
+
+	// The store reports the error before closing the channel if it drops the watch
+	errCh := make(chan error, 1)
+	watchOpts = append(watchOpts, store.WithErrorHandler(func(err error) {
+		errCh <- err
+	}))
 
 	ch := make(chan topoapi.Event)
 	if err := s.objectStore.Watch(server.Context(), ch, req.Filters, watchOpts...); err != nil {
@@ -165,12 +637,29 @@ func (s *Server) Watch(req *topoapi.WatchRequest, server topoapi.Topo_WatchServe
 		return errors.Status(err).Err()
 	}
 
-	return s.Stream(server, ch)
+	if err := s.stream(server, ch, fields); err != nil {
+		return err
+	}
+	select {
+	case err := <-errCh:
+		log.Warnf("WatchRequest %+v failed: %v", req, err)
+		return errors.Status(err).Err()
+	default:
+		return nil
+	}
 }
 
 // Stream is the ongoing stream for WatchTerminations request
 func (s *Server) Stream(server topoapi.Topo_WatchServer, ch chan topoapi.Event) error {
+	return s.stream(server, ch, nil)
+}
+
+// stream sends the events of the given channel with the fields of the given mask
+func (s *Server) stream(server topoapi.Topo_WatchServer, ch chan topoapi.Event, fields *fieldMask) error {
 	for event := range ch {
+		if fields != nil {
+			event.Object = *fields.project(&event.Object)
+		}
 		res := &topoapi.WatchResponse{
 			Event: event,
 		}
@@ -184,49 +673,122 @@ func (s *Server) Stream(server topoapi.Topo_WatchServer, ch chan topoapi.Event)
 	return nil
 }
 
-// ValidateObject validates the given object
+// ValidateObject validates the given object against its kind: a relation must have a kind, the kind of an entity
+// or a relation must exist, and the object must only carry the aspects declared by the kind. Aspects for which the
+// kind carries a JSON schema must match the schema, and other aspects declared by the kind but missing from the
+// object are filled in from the kind. The schemas carried by a kind must be valid JSON schemas.
 func (s *Server) ValidateObject(ctx context.Context, object *topoapi.Object) error {
-	var kind *topoapi.Object
-	var err error
-	switch object.Type {
-	case topoapi.Object_KIND:
-	case topoapi.Object_ENTITY:
-		if object.GetEntity().KindID != topoapi.NullID {
-			kind, err = s.objectStore.Get(ctx, object.GetEntity().KindID)
-			if err != nil {
-				return err
-			}
+	if object.Type == topoapi.Object_RELATION && object.GetRelation().GetKindID() == topoapi.NullID {
+		return errors.NewInvalid("Relation '%s' must have a Kind", object.ID)
+	}
+	kindID := objectKindID(object)
+	if object.Type == topoapi.Object_KIND || kindID == topoapi.NullID {
+		return validateSchemas(object, nil)
+	}
+
+	kind, err := s.objectStore.Get(ctx, kindID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return errors.NewConflict("Kind '%s' does not exist", kindID)
 		}
-	case topoapi.Object_RELATION:
-		kind, err = s.objectStore.Get(ctx, object.GetRelation().KindID)
-		if err != nil {
-			return err
+		return err
+	}
+	if kind.Type != topoapi.Object_KIND {
+		return errors.NewConflict("Object '%s' is not a Kind", kindID)
+	}
+	if len(kind.Aspects) == 0 {
+		return nil
+	}
+
+	for aspectType := range object.Aspects {
+		if _, ok := kind.Aspects[aspectType]; !ok {
+			return errors.NewInvalid("Aspect '%s' is not declared by Kind '%s'", aspectType, kindID)
 		}
-		_, err := s.objectStore.Get(ctx, object.GetRelation().SrcEntityID)
-		if err != nil {
-			return err
+	}
+	if err := validateSchemas(object, kind); err != nil {
+		return err
+	}
+	for aspectType, aspect := range kind.Aspects {
+		if _, ok := object.Aspects[aspectType]; !ok && aspect.GetTypeUrl() != SchemaTypeURL {
+			if object.Aspects == nil {
+				object.Aspects = make(map[string]*types.Any)
+			}
+			object.Aspects[aspectType] = aspect
 		}
-		_, err = s.objectStore.Get(ctx, object.GetRelation().TgtEntityID)
-		if err != nil {
+	}
+	return nil
+}
+
+// validate validates the given object according to the kind validation mode of the server. Whatever the mode, the
+// schemas carried by kinds must be valid, and the aspects of objects must match the schemas of their kind.
+func (s *Server) validate(ctx context.Context, object *topoapi.Object) error {
+	if object == nil {
+		return nil
+	}
+	if s.kindValidation != KindValidationOff {
+		err := s.ValidateObject(ctx, object)
+		if err == nil || s.kindValidation == KindValidationEnforce {
 			return err
 		}
-	default:
-		log.Infof("Invalid type %v", object)
+		log.Warnf("Object %+v does not conform to its Kind: %v", object, err)
 	}
 
-	if kind != nil && object.Type != topoapi.Object_KIND {
-		if kind.Aspects != nil {
-			for attrName := range object.Aspects {
-				if _, ok := kind.Aspects[attrName]; !ok {
-					return fmt.Errorf("Invalid attribute %s", attrName)
-				}
-			}
-			for attrName, val := range kind.Aspects {
-				if _, ok := object.Aspects[attrName]; !ok {
-					object.Aspects[attrName] = val
+	kindID := objectKindID(object)
+	if object.Type == topoapi.Object_KIND || kindID == topoapi.NullID {
+		return validateSchemas(object, nil)
+	}
+	kind, err := s.objectStore.Get(ctx, kindID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if kind.Type != topoapi.Object_KIND {
+		return nil
+	}
+	return validateSchemas(object, kind)
+}
+
+// objectKindID returns the ID of the kind of an entity or a relation
+func objectKindID(object *topoapi.Object) topoapi.ID {
+	switch object.Type {
+	case topoapi.Object_ENTITY:
+		return object.GetEntity().GetKindID()
+	case topoapi.Object_RELATION:
+		return object.GetRelation().GetKindID()
+	}
+	return topoapi.NullID
+}
+
+// validateSchemas checks that the schemas carried by a kind are valid, or that the aspects of an entity or a
+// relation match the schemas carried by the given kind, if any
+func validateSchemas(object *topoapi.Object, kind *topoapi.Object) error {
+	if object.Type == topoapi.Object_KIND {
+		for aspectType, aspect := range object.Aspects {
+			if aspect.GetTypeUrl() == SchemaTypeURL {
+				if _, err := parseSchema(aspect.Value); err != nil {
+					return errors.NewInvalid("invalid schema for Aspect '%s': %v", aspectType, err)
 				}
 			}
 		}
+		return nil
+	}
+	if kind == nil {
+		return nil
+	}
+	for aspectType, aspect := range object.Aspects {
+		declared, ok := kind.Aspects[aspectType]
+		if !ok || declared.GetTypeUrl() != SchemaTypeURL {
+			continue
+		}
+		schema, err := parseSchema(declared.Value)
+		if err != nil {
+			return errors.NewConflict("Kind '%s' has an invalid schema for Aspect '%s': %v", kind.ID, aspectType, err)
+		}
+		if err := schema.validateJSON(aspect.GetValue()); err != nil {
+			return errors.NewInvalid("Aspect '%s' does not match the schema of Kind '%s': %v", aspectType, kind.ID, err)
+		}
 	}
 	return nil
 }
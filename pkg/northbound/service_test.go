@@ -6,19 +6,25 @@ package northbound
 
 import (
 	"context"
+	"fmt"
 	"github.com/atomix/go-sdk/pkg/primitive"
 	"github.com/atomix/go-sdk/pkg/test"
+	"github.com/gogo/protobuf/types"
 	"google.golang.org/grpc/credentials/insecure"
 	"io"
 	"net"
 	"sync"
 	"testing"
+	"time"
 
 	topoapi "github.com/onosproject/onos-api/go/onos/topo"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
 	"github.com/onosproject/onos-topo/pkg/store"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -39,10 +45,14 @@ func newTestService(client primitive.Client) (northbound.Service, error) {
 }
 
 func createServerConnection(t *testing.T, client primitive.Client) *grpc.ClientConn {
-	lis = bufconn.Listen(1024 * 1024)
 	s, err := newTestService(client)
 	assert.NoError(t, err)
 	assert.NotNil(t, s)
+	return createServiceConnection(t, s)
+}
+
+func createServiceConnection(t *testing.T, s northbound.Service) *grpc.ClientConn {
+	lis = bufconn.Listen(1024 * 1024)
 	server := grpc.NewServer()
 	s.Register(server)
 
@@ -291,3 +301,1119 @@ func TestSort(t *testing.T) {
 			(res.Objects[0].ID == "c" && res.Objects[1].ID == "b" && res.Objects[2].ID == "a")
 	})
 }
+
+type blockingWatchServer struct {
+	grpc.ServerStream
+	ctx     context.Context
+	release chan struct{}
+}
+
+func (s *blockingWatchServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *blockingWatchServer) Send(*topoapi.WatchResponse) error {
+	<-s.release
+	return nil
+}
+
+func TestSlowWatcher(t *testing.T) {
+	for policy, code := range map[store.OverflowPolicy]codes.Code{
+		store.OverflowDrop:   codes.Unavailable,
+		store.OverflowResync: codes.FailedPrecondition,
+	} {
+		objectStore := store.NewMemoryStore()
+		server := &Server{
+			objectStore: objectStore,
+			watchOpts:   []store.WatchOption{store.WithQueue(1, policy)},
+		}
+
+		stream := &blockingWatchServer{ctx: context.Background(), release: make(chan struct{})}
+		errCh := make(chan error)
+		go func() {
+			errCh <- server.Watch(&topoapi.WatchRequest{}, stream)
+		}()
+		assert.Eventually(t, func() bool {
+			return len(objectStore.WatchStats().Watchers) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		for _, id := range []topoapi.ID{"1", "2", "3", "4"} {
+			err := objectStore.Create(context.Background(), &topoapi.Object{ID: id, Type: topoapi.Object_ENTITY})
+			assert.NoError(t, err)
+		}
+		close(stream.release)
+
+		// A watcher that falls behind must be closed with a status telling whether it must list the topology again
+		err := <-errCh
+		assert.Equal(t, code, status.Code(err), policy.String())
+	}
+}
+
+func TestWatchResume(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	cres, err := client.Create(context.Background(), &topoapi.CreateRequest{
+		Object: &topoapi.Object{
+			ID:   "1",
+			Type: topoapi.Object_ENTITY,
+		},
+	})
+	assert.NoError(t, err)
+	_, err = client.Create(context.Background(), &topoapi.CreateRequest{
+		Object: &topoapi.Object{
+			ID:   "2",
+			Type: topoapi.Object_ENTITY,
+		},
+	})
+	assert.NoError(t, err)
+
+	// Only the changes following the requested revision must be replayed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, ResumeAfterRevisionKey, fmt.Sprint(cres.Object.Revision))
+	res, err := client.Watch(ctx, &topoapi.WatchRequest{})
+	assert.NoError(t, err)
+	e, err := res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, topoapi.EventType_ADDED, e.Event.Type)
+	assert.Equal(t, topoapi.ID("2"), e.Event.Object.ID)
+
+	// Invalid revisions must be rejected
+	ctx = metadata.AppendToOutgoingContext(context.Background(), ResumeAfterRevisionKey, "latest")
+	res, err = client.Watch(ctx, &topoapi.WatchRequest{})
+	assert.NoError(t, err)
+	_, err = res.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestKindValidation(t *testing.T) {
+	for _, validation := range []KindValidation{KindValidationOff, KindValidationWarn, KindValidationEnforce} {
+		t.Run(validation.String(), func(t *testing.T) {
+			testKindValidation(t, validation)
+		})
+	}
+}
+
+func testKindValidation(t *testing.T, validation KindValidation) {
+	server := &Server{
+		objectStore:    store.NewMemoryStore(),
+		kindValidation: validation,
+	}
+	ctx := context.Background()
+	_, err := server.Create(ctx, &topoapi.CreateRequest{
+		Object: &topoapi.Object{
+			ID:      "e2-node",
+			Type:    topoapi.Object_KIND,
+			Obj:     &topoapi.Object_Kind{Kind: &topoapi.Kind{Name: "e2-node"}},
+			Aspects: map[string]*types.Any{"onos.topo.E2Node": {TypeUrl: "onos.topo.E2Node"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	newEntity := func(id topoapi.ID, kindID topoapi.ID, aspects ...string) *topoapi.Object {
+		object := &topoapi.Object{
+			ID:   id,
+			Type: topoapi.Object_ENTITY,
+			Obj:  &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: kindID}},
+		}
+		for _, aspect := range aspects {
+			if object.Aspects == nil {
+				object.Aspects = make(map[string]*types.Any)
+			}
+			object.Aspects[aspect] = &types.Any{TypeUrl: aspect}
+		}
+		return object
+	}
+	rejected := func(code codes.Code, err error) {
+		if validation == KindValidationEnforce {
+			assert.Equal(t, code, status.Code(err))
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	// Objects of kinds that do not exist must be rejected with a FailedPrecondition status
+	_, err = server.Create(ctx, &topoapi.CreateRequest{Object: newEntity("1", "missing")})
+	rejected(codes.FailedPrecondition, err)
+
+	// Objects with aspects that are not declared by their kind must be rejected with an InvalidArgument status
+	_, err = server.Create(ctx, &topoapi.CreateRequest{Object: newEntity("2", "e2-node", "onos.topo.Location")})
+	rejected(codes.InvalidArgument, err)
+
+	// Aspects declared by the kind must be filled in from the kind
+	res, err := server.Create(ctx, &topoapi.CreateRequest{Object: newEntity("3", "e2-node")})
+	assert.NoError(t, err)
+	_, ok := res.Object.Aspects["onos.topo.E2Node"]
+	assert.Equal(t, validation != KindValidationOff, ok)
+
+	// Updates must be validated as well
+	object := res.Object
+	object.GetEntity().KindID = "missing"
+	_, err = server.Update(ctx, &topoapi.UpdateRequest{Object: object})
+	rejected(codes.FailedPrecondition, err)
+
+	// Entities without a kind are not validated
+	_, err = server.Create(ctx, &topoapi.CreateRequest{Object: newEntity("4", topoapi.NullID, "onos.topo.Location")})
+	assert.NoError(t, err)
+
+	// Relations without a kind must be rejected with an InvalidArgument status
+	_, err = server.Create(ctx, &topoapi.CreateRequest{
+		Object: &topoapi.Object{
+			ID:   "3-4",
+			Type: topoapi.Object_RELATION,
+			Obj:  &topoapi.Object_Relation{Relation: &topoapi.Relation{SrcEntityID: "3", TgtEntityID: "4"}},
+		},
+	})
+	rejected(codes.InvalidArgument, err)
+}
+
+func TestAspectSchema(t *testing.T) {
+	server := &Server{
+		objectStore:    store.NewMemoryStore(),
+		kindValidation: KindValidationEnforce,
+	}
+	ctx := context.Background()
+	kind := &topoapi.Object{
+		ID:   "e2-cell",
+		Type: topoapi.Object_KIND,
+		Obj:  &topoapi.Object_Kind{Kind: &topoapi.Kind{Name: "e2-cell"}},
+	}
+	assert.NoError(t, kind.SetAspectBytes("onos.topo.Location", []byte(`{"type": "["}`)))
+	kind.Aspects["onos.topo.Location"].TypeUrl = SchemaTypeURL
+
+	// Kinds with invalid schemas must be rejected
+	_, err := server.Create(ctx, &topoapi.CreateRequest{Object: kind})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	kind.Aspects["onos.topo.Location"].Value = []byte(`{
+		"type": "object",
+		"properties": {"lat": {"type": "number"}, "lng": {"type": "number"}}
+	}`)
+	_, err = server.Create(ctx, &topoapi.CreateRequest{Object: kind})
+	assert.NoError(t, err)
+
+	// Aspects matching the schema of their kind must be accepted, and the schema must not be filled in
+	cell := &topoapi.Object{
+		ID:   "1",
+		Type: topoapi.Object_ENTITY,
+		Obj:  &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "e2-cell"}},
+	}
+	assert.NoError(t, cell.SetAspect(&topoapi.Location{Lat: 1.5, Lng: 2.5}))
+	res, err := server.Create(ctx, &topoapi.CreateRequest{Object: cell})
+	assert.NoError(t, err)
+	assert.Equal(t, "onos.topo.Location", res.Object.Aspects["onos.topo.Location"].TypeUrl)
+
+	cell = &topoapi.Object{
+		ID:   "2",
+		Type: topoapi.Object_ENTITY,
+		Obj:  &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "e2-cell"}},
+	}
+	res, err = server.Create(ctx, &topoapi.CreateRequest{Object: cell})
+	assert.NoError(t, err)
+	assert.Len(t, res.Object.Aspects, 0)
+
+	// Aspects that do not match the schema of their kind must be rejected with the offending fields
+	assert.NoError(t, cell.SetAspectBytes("onos.topo.Location", []byte(`{"lat": "north", "lng": 2.5}`)))
+	_, err = server.Update(ctx, &topoapi.UpdateRequest{Object: cell})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "lat: expected number, got string")
+
+	// Schemas must be enforced whatever the kind validation mode
+	for _, validation := range []KindValidation{KindValidationOff, KindValidationWarn} {
+		server.kindValidation = validation
+		_, err = server.Update(ctx, &topoapi.UpdateRequest{Object: cell})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		kind.ID = "e2-node"
+		kind.Aspects["onos.topo.Location"].Value = []byte(`{"type": "string", "format": "ipv4"}`)
+		_, err = server.Create(ctx, &topoapi.CreateRequest{Object: kind})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestTraversal(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	for _, id := range []topoapi.ID{"pod", "switch", "port"} {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+			Object: &topoapi.Object{ID: id, Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{}}},
+		})
+		assert.NoError(t, err)
+	}
+	for _, relation := range []*topoapi.Relation{
+		{KindID: "contains", SrcEntityID: "pod", TgtEntityID: "switch"},
+		{KindID: "contains", SrcEntityID: "switch", TgtEntityID: "port"},
+	} {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+			Object: &topoapi.Object{Type: topoapi.Object_RELATION, Obj: &topoapi.Object_Relation{Relation: relation}},
+		})
+		assert.NoError(t, err)
+	}
+
+	query := func(traversal string) ([]topoapi.ID, error) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), TraversalKey, traversal)
+		stream, err := client.Query(ctx, &topoapi.QueryRequest{})
+		assert.NoError(t, err)
+		var ids []topoapi.ID
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				return ids, nil
+			}
+			if err != nil {
+				return ids, err
+			}
+			ids = append(ids, res.Object.ID)
+		}
+	}
+
+	// The traversal must stream the entities reached by its steps
+	assert.Eventually(t, func() bool {
+		ids, err := query(`{"start": "pod", "steps": [{"relationKind": "contains", "direction": "outbound", "minDepth": 2, "maxDepth": 2}]}`)
+		return err == nil && len(ids) == 1 && ids[0] == "port"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Invalid traversals must be rejected
+	_, err := query(`{"start": "pod", "steps": [{"direction": "sideways"}]}`)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = query(`{"start": "pod"}`)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = query(`{"start": "rack", "steps": [{}]}`)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Traversals exceeding their limits must stream the objects reached until then and be marked truncated
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TraversalKey, `{"start": "pod", "steps": [{"relationKind": "contains", "minDepth": 1, "maxDepth": 2}], "limit": 1}`)
+	stream, err := client.Query(ctx, &topoapi.QueryRequest{})
+	assert.NoError(t, err)
+	res, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, topoapi.ID("switch"), res.Object.ID)
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []string{"true"}, stream.Trailer().Get(TraversalTruncatedKey))
+}
+
+func TestPaths(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	for _, id := range []topoapi.ID{"a", "b", "c"} {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+			Object: &topoapi.Object{ID: id, Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{}}},
+		})
+		assert.NoError(t, err)
+	}
+	for _, relation := range []*topoapi.Relation{
+		{KindID: "link", SrcEntityID: "a", TgtEntityID: "b"},
+		{KindID: "link", SrcEntityID: "b", TgtEntityID: "c"},
+		{KindID: "link", SrcEntityID: "a", TgtEntityID: "c"},
+	} {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+			Object: &topoapi.Object{
+				ID:   relation.SrcEntityID + relation.TgtEntityID,
+				Type: topoapi.Object_RELATION,
+				Obj:  &topoapi.Object_Relation{Relation: relation},
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	query := func(paths string) ([]topoapi.ID, metadata.MD, error) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), PathsKey, paths)
+		stream, err := client.Query(ctx, &topoapi.QueryRequest{})
+		assert.NoError(t, err)
+		var ids []topoapi.ID
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				header, err := stream.Header()
+				return ids, header, err
+			}
+			if err != nil {
+				return ids, nil, err
+			}
+			ids = append(ids, res.Object.ID)
+		}
+	}
+
+	// The objects of each path must be streamed in order, with the length and weight of each path in the header
+	assert.Eventually(t, func() bool {
+		ids, header, err := query(`{"src": "a", "dst": "c", "relationKinds": ["link"], "k": 3}`)
+		return err == nil &&
+			assert.ObjectsAreEqual([]topoapi.ID{"a", "ac", "c", "a", "ab", "b", "bc", "c"}, ids) &&
+			assert.ObjectsAreEqual([]string{"3", "5"}, header.Get(PathLengthsKey)) &&
+			assert.ObjectsAreEqual([]string{"1", "2"}, header.Get(PathWeightsKey))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Invalid queries must be rejected
+	_, _, err := query(`{"src": "a", "dst": "c", "direction": "up"}`)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, _, err = query(`{"src": "a", "dst": "d"}`)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGeoQuery(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	for id, location := range map[topoapi.ID]string{
+		"node-1": `{"lat": 48.8566, "lng": 2.3522}`,
+		"cell-1": `{"lat": 48.8584, "lng": 2.2945}`,
+		"cell-2": `{"lat": 45.7640, "lng": 4.8357}`,
+	} {
+		kind := topoapi.ID("e2cell")
+		if id == "node-1" {
+			kind = "e2node"
+		}
+		object := &topoapi.Object{ID: id, Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: kind}}}
+		assert.NoError(t, object.SetAspectBytes("onos.topo.Location", []byte(location)))
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{Object: object})
+		assert.NoError(t, err)
+	}
+
+	query := func(geo string, filters *topoapi.Filters) ([]topoapi.ID, metadata.MD, error) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), GeoKey, geo)
+		stream, err := client.Query(ctx, &topoapi.QueryRequest{Filters: filters})
+		assert.NoError(t, err)
+		var ids []topoapi.ID
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				header, err := stream.Header()
+				return ids, header, err
+			}
+			if err != nil {
+				return ids, nil, err
+			}
+			ids = append(ids, res.Object.ID)
+		}
+	}
+
+	// Located objects must be streamed in order of distance, with their distances in the header
+	assert.Eventually(t, func() bool {
+		ids, header, err := query(`{"nearest": {"point": {"lat": 48.8566, "lng": 2.3522}, "k": 2}}`, nil)
+		return err == nil &&
+			assert.ObjectsAreEqual([]topoapi.ID{"node-1", "cell-1"}, ids) &&
+			len(header.Get(GeoDistancesKey)) == 2 && header.Get(GeoDistancesKey)[0] == "0"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Located objects must match the request filters
+	ids, _, err := query(`{"radius": {"center": {"lat": 48.8566, "lng": 2.3522}, "meters": 10000}}`, &topoapi.Filters{
+		KindFilter: &topoapi.Filter{Filter: &topoapi.Filter_Equal_{Equal_: &topoapi.EqualFilter{Value: "e2cell"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topoapi.ID{"cell-1"}, ids)
+
+	// Invalid queries must be rejected
+	_, _, err = query(`{"radius": {"center": {"lat": 48.8566, "lng": 2.3522}}}`, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, _, err = query(`{"nearest": 1}`, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListPagination(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	for i := 0; i < 25; i++ {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+			Object: &topoapi.Object{ID: topoapi.ID(fmt.Sprintf("port-%02d", i)), Type: topoapi.Object_ENTITY},
+		})
+		assert.NoError(t, err)
+	}
+
+	list := func(req *topoapi.ListRequest, pageSize int, pageToken string) ([]topoapi.ID, string, error) {
+		ctx := context.Background()
+		if pageSize != 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, PageSizeKey, fmt.Sprint(pageSize))
+		}
+		if pageToken != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, PageTokenKey, pageToken)
+		}
+		var header metadata.MD
+		res, err := client.List(ctx, req, grpc.Header(&header))
+		if err != nil {
+			return nil, "", err
+		}
+		var ids []topoapi.ID
+		for _, object := range res.Objects {
+			ids = append(ids, object.ID)
+		}
+		var next string
+		if values := header.Get(NextPageTokenKey); len(values) > 0 {
+			next = values[0]
+		}
+		return ids, next, nil
+	}
+
+	// The pages must be read from the objects listed for the first page, skipping the objects removed since
+	req := &topoapi.ListRequest{Filters: &topoapi.Filters{ObjectTypes: []topoapi.Object_Type{topoapi.Object_ENTITY}}}
+	ids, token, err := list(req, 10, "")
+	assert.NoError(t, err)
+	assert.Len(t, ids, 10)
+	assert.Equal(t, topoapi.ID("port-00"), ids[0])
+	assert.NotEmpty(t, token)
+	_, err = client.Delete(context.Background(), &topoapi.DeleteRequest{ID: "port-09"})
+	assert.NoError(t, err)
+
+	_, err = client.Create(context.Background(), &topoapi.CreateRequest{
+		Object: &topoapi.Object{ID: "port-10a", Type: topoapi.Object_ENTITY},
+	})
+	assert.NoError(t, err)
+	_, err = client.Delete(context.Background(), &topoapi.DeleteRequest{ID: "port-20"})
+	assert.NoError(t, err)
+
+	listed := ids
+	last := token
+	for token != "" {
+		last = token
+		ids, token, err = list(req, 0, token)
+		assert.NoError(t, err)
+		listed = append(listed, ids...)
+	}
+	assert.Len(t, listed, 24)
+	assert.Equal(t, topoapi.ID("port-10"), listed[10])
+	assert.Equal(t, topoapi.ID("port-11"), listed[11])
+	assert.NotContains(t, listed, topoapi.ID("port-10a"))
+	assert.NotContains(t, listed, topoapi.ID("port-20"))
+
+	// The listing must be discarded once its last page has been read
+	_, _, err = list(req, 0, last)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// A listing that fits in a page must not return a token
+	ids, token, err = list(&topoapi.ListRequest{SortOrder: topoapi.SortOrder_DESCENDING}, 100, "")
+	assert.NoError(t, err)
+	assert.Len(t, ids, 24)
+	assert.Equal(t, topoapi.ID("port-24"), ids[0])
+	assert.Empty(t, token)
+
+	// Page sizes may change between pages
+	ids, token, err = list(req, 20, "")
+	assert.NoError(t, err)
+	assert.Len(t, ids, 20)
+	ids, _, err = list(req, 2, token)
+	assert.NoError(t, err)
+	assert.Equal(t, []topoapi.ID{"port-21", "port-22"}, ids)
+
+	// Tokens must be rejected if they are invalid, expired, or used with other filters
+	_, _, err = list(&topoapi.ListRequest{}, 0, token)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, _, err = list(req, 0, "invalid")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, _, err = list(req, 0, encodePageToken("unknown", 0))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, _, err = list(req, -1, "")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Every page must carry the revision of its listing, even if objects changed since the first page
+	revision := func(md ...string) string {
+		var header metadata.MD
+		_, err := client.List(metadata.AppendToOutgoingContext(context.Background(), md...), req, grpc.Header(&header))
+		assert.NoError(t, err)
+		values := header.Get(ListingRevisionKey)
+		assert.Len(t, values, 1)
+		return fmt.Sprint(values)
+	}
+	first := revision(PageSizeKey, "1")
+	_, token, err = list(req, 1, "")
+	assert.NoError(t, err)
+	_, err = client.Create(context.Background(), &topoapi.CreateRequest{
+		Object: &topoapi.Object{ID: "port-30", Type: topoapi.Object_ENTITY},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, first, revision(PageTokenKey, token))
+}
+
+func TestListSnapshot(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+	atomixStore, err := store.NewAtomixStore(cluster)
+	assert.NoError(t, err)
+	for name, objectStore := range map[string]store.Store{"memory": store.NewMemoryStore(), "atomix": atomixStore} {
+		t.Run(name, func(t *testing.T) {
+			testListSnapshot(t, objectStore)
+		})
+	}
+}
+
+// testListSnapshot changes the sort fields of listed objects between the pages of a listing, and checks that the
+// pages hold each object of the first listing exactly once, in order, except for the objects removed since
+func testListSnapshot(t *testing.T, objectStore store.Store) {
+	conn := createServiceConnection(t, NewService(objectStore, KindValidationOff))
+	client := topoapi.NewTopoClient(conn)
+
+	port := func(i int) topoapi.ID {
+		return topoapi.ID(fmt.Sprintf("port-%02d", i))
+	}
+	rank := func(i int) map[string]string {
+		return map[string]string{"rank": fmt.Sprintf("%03d", i)}
+	}
+	for i := 0; i < 30; i++ {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+			Object: &topoapi.Object{ID: port(i), Type: topoapi.Object_ENTITY, Labels: rank(i)},
+		})
+		assert.NoError(t, err)
+	}
+	rerank := func(id topoapi.ID, labels map[string]string) {
+		res, err := client.Get(context.Background(), &topoapi.GetRequest{ID: id})
+		assert.NoError(t, err)
+		res.Object.Labels = labels
+		_, err = client.Update(context.Background(), &topoapi.UpdateRequest{Object: res.Object})
+		assert.NoError(t, err)
+	}
+
+	req := &topoapi.ListRequest{}
+	var listed []topoapi.ID
+	removed := make(map[topoapi.ID]bool)
+	token := ""
+	for page := 0; page == 0 || token != ""; page++ {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), SortKey, "label:rank", PageSizeKey, "7")
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, PageTokenKey, token)
+		}
+		var header metadata.MD
+		res, err := client.List(ctx, req, grpc.Header(&header))
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, object := range res.Objects {
+			listed = append(listed, object.ID)
+		}
+		token = ""
+		if values := header.Get(NextPageTokenKey); len(values) > 0 {
+			token = values[0]
+		}
+
+		// Add an object sorting first, move an unread object before the read ones and a read object after the
+		// unread ones, and remove an unread object
+		_, err = client.Create(context.Background(), &topoapi.CreateRequest{
+			Object: &topoapi.Object{ID: topoapi.ID(fmt.Sprintf("port-new-%d", page)), Type: topoapi.Object_ENTITY, Labels: rank(0)},
+		})
+		assert.NoError(t, err)
+		if unread := 7*page + 8; unread < 29 {
+			rerank(port(unread), rank(0))
+			rerank(port(7*page), rank(99))
+			_, err = client.Delete(context.Background(), &topoapi.DeleteRequest{ID: port(unread + 1)})
+			assert.NoError(t, err)
+			removed[port(unread+1)] = true
+		}
+	}
+
+	var expected []topoapi.ID
+	for i := 0; i < 30; i++ {
+		if !removed[port(i)] {
+			expected = append(expected, port(i))
+		}
+	}
+	assert.Equal(t, expected, listed)
+}
+
+func TestListSort(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	for i, rack := range []string{"2", "10", "1", "10", "2"} {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+			Object: &topoapi.Object{
+				ID:     topoapi.ID(fmt.Sprintf("sw-%d", i)),
+				Type:   topoapi.Object_ENTITY,
+				Obj:    &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "switch"}},
+				Labels: map[string]string{"rack": rack},
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	list := func(req *topoapi.ListRequest, md ...string) ([]topoapi.ID, string, error) {
+		var header metadata.MD
+		res, err := client.List(metadata.AppendToOutgoingContext(context.Background(), md...), req, grpc.Header(&header))
+		if err != nil {
+			return nil, "", err
+		}
+		var ids []topoapi.ID
+		for _, object := range res.Objects {
+			ids = append(ids, object.ID)
+		}
+		var next string
+		if values := header.Get(NextPageTokenKey); len(values) > 0 {
+			next = values[0]
+		}
+		return ids, next, nil
+	}
+
+	// Objects must be sorted by each key in order, then by ID in the order of the request
+	ids, _, err := list(&topoapi.ListRequest{SortOrder: topoapi.SortOrder_DESCENDING}, SortKey, "-label:rack")
+	assert.NoError(t, err)
+	assert.Equal(t, []topoapi.ID{"sw-3", "sw-1", "sw-4", "sw-0", "sw-2"}, ids)
+
+	// Paginated listings must be sorted before they are split into pages
+	ids, token, err := list(&topoapi.ListRequest{}, SortKey, "label:rack", PageSizeKey, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, []topoapi.ID{"sw-2", "sw-0"}, ids)
+	ids, _, err = list(&topoapi.ListRequest{}, SortKey, "label:rack", PageTokenKey, token)
+	assert.NoError(t, err)
+	assert.Equal(t, []topoapi.ID{"sw-4", "sw-1"}, ids)
+
+	// Pages must be requested with the sort keys of their listing
+	_, _, err = list(&topoapi.ListRequest{}, SortKey, "-label:rack", PageTokenKey, token)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, _, err = list(&topoapi.ListRequest{}, SortKey, "color")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestFieldProjection(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	for _, id := range []topoapi.ID{"sw-1", "sw-2"} {
+		object := &topoapi.Object{
+			ID:     id,
+			Type:   topoapi.Object_ENTITY,
+			Obj:    &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "switch"}},
+			Labels: map[string]string{"pod": "pod-1", "rack": "r1"},
+		}
+		assert.NoError(t, object.SetAspectBytes("onos.topo.P4RTServerInfo", []byte(`{"pipelines": []}`)))
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{Object: object})
+		assert.NoError(t, err)
+	}
+	_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+		Object: &topoapi.Object{
+			ID:   "sw-1-sw-2",
+			Type: topoapi.Object_RELATION,
+			Obj:  &topoapi.Object_Relation{Relation: &topoapi.Relation{KindID: "link", SrcEntityID: "sw-1", TgtEntityID: "sw-2"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), FieldsKey, "label:pod")
+	assertProjected := func(object *topoapi.Object) {
+		assert.Equal(t, map[string]string{"pod": "pod-1"}, object.Labels)
+		assert.Empty(t, object.Aspects)
+		assert.Empty(t, object.GetEntity().GetSrcRelationIDs())
+	}
+
+	// Get, List, Query and Watch must only return the requested fields
+	assert.Eventually(t, func() bool {
+		res, err := client.Get(context.Background(), &topoapi.GetRequest{ID: "sw-1"})
+		return err == nil && len(res.Object.GetEntity().SrcRelationIDs) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	res, err := client.Get(ctx, &topoapi.GetRequest{ID: "sw-1"})
+	assert.NoError(t, err)
+	assertProjected(res.Object)
+
+	filters := &topoapi.Filters{ObjectTypes: []topoapi.Object_Type{topoapi.Object_ENTITY}}
+	list, err := client.List(ctx, &topoapi.ListRequest{Filters: filters})
+	assert.NoError(t, err)
+	assert.Len(t, list.Objects, 2)
+	for i := range list.Objects {
+		assertProjected(&list.Objects[i])
+	}
+
+	query, err := client.Query(ctx, &topoapi.QueryRequest{Filters: filters})
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		res, err := query.Recv()
+		assert.NoError(t, err)
+		assertProjected(res.Object)
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watch, err := client.Watch(watchCtx, &topoapi.WatchRequest{Filters: filters})
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		res, err := watch.Recv()
+		assert.NoError(t, err)
+		assertProjected(&res.Event.Object)
+	}
+
+	// Relation IDs must be included if requested, and the stored objects must not be changed by projections
+	res, err = client.Get(metadata.AppendToOutgoingContext(context.Background(), FieldsKey, "relations"), &topoapi.GetRequest{ID: "sw-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []topoapi.ID{"sw-1-sw-2"}, res.Object.GetEntity().SrcRelationIDs)
+	assert.Empty(t, res.Object.Labels)
+	res, err = client.Get(context.Background(), &topoapi.GetRequest{ID: "sw-1"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Object.Labels, 2)
+	assert.Len(t, res.Object.Aspects, 1)
+
+	// Invalid field masks must be rejected
+	_, err = client.Get(metadata.AppendToOutgoingContext(context.Background(), FieldsKey, "pod"), &topoapi.GetRequest{ID: "sw-1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListCounts(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	for i, pod := range []string{"pod-1", "pod-1", "pod-2", ""} {
+		object := &topoapi.Object{
+			ID:   topoapi.ID(fmt.Sprintf("port-%d", i)),
+			Type: topoapi.Object_ENTITY,
+			Obj:  &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "port"}},
+		}
+		if pod != "" {
+			object.Labels = map[string]string{"pod": pod}
+		}
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{Object: object})
+		assert.NoError(t, err)
+	}
+	_, err := client.Create(context.Background(), &topoapi.CreateRequest{
+		Object: &topoapi.Object{
+			ID:   "pod-1",
+			Type: topoapi.Object_ENTITY,
+			Obj:  &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "pod"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	count := func(req *topoapi.ListRequest, md ...string) ([]store.GroupCount, error) {
+		var header metadata.MD
+		res, err := client.List(metadata.AppendToOutgoingContext(context.Background(), md...), req, grpc.Header(&header))
+		if err != nil {
+			return nil, err
+		}
+		// Counts must not be returned as objects
+		assert.Empty(t, res.Objects)
+		return GetGroupCounts(header)
+	}
+
+	// Objects must be counted instead of listed, grouped by the requested fields
+	counts, err := count(&topoapi.ListRequest{}, GroupByKey, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.GroupCount{{Count: 5}}, counts)
+	counts, err = count(&topoapi.ListRequest{}, GroupByKey, "kind")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.GroupCount{
+		{Group: map[string]string{"kind": "pod"}, Count: 1},
+		{Group: map[string]string{"kind": "port"}, Count: 4},
+	}, counts)
+	counts, err = count(&topoapi.ListRequest{
+		Filters: &topoapi.Filters{
+			KindFilter: &topoapi.Filter{Filter: &topoapi.Filter_Equal_{Equal_: &topoapi.EqualFilter{Value: "port"}}},
+		},
+	}, GroupByKey, "label:pod")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.GroupCount{
+		{Count: 1},
+		{Group: map[string]string{"label:pod": "pod-1"}, Count: 2},
+		{Group: map[string]string{"label:pod": "pod-2"}, Count: 1},
+	}, counts)
+
+	// Invalid group by fields and paginated counts must be rejected
+	_, err = count(&topoapi.ListRequest{}, GroupByKey, "id")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = count(&topoapi.ListRequest{}, GroupByKey, "kind", PageSizeKey, "1")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestFilterExpression(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	for _, object := range []*topoapi.Object{
+		{ID: "sw-1", Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "switch"}}, Labels: map[string]string{"pod": "pod-01", "role": "spine"}},
+		{ID: "sw-2", Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "switch"}}, Labels: map[string]string{"pod": "pod-02", "role": "leaf"}},
+		{ID: "sw-3", Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "switch"}}, Labels: map[string]string{"pod": "pod-02", "role": "spine"}},
+		{ID: "host-1", Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "host"}}, Labels: map[string]string{"pod": "pod-01"}},
+	} {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{Object: object})
+		assert.NoError(t, err)
+	}
+
+	expr := `{"or": [{"and": [{"kind": ["switch"]}, {"label": {"key": "pod", "values": ["pod-01"]}}]}, {"label": {"key": "role", "values": ["leaf"]}}]}`
+	ctx := metadata.AppendToOutgoingContext(context.Background(), FilterKey, expr)
+
+	// Listings and queries must only return the objects matching both the expression and the request filters
+	list, err := client.List(ctx, &topoapi.ListRequest{})
+	assert.NoError(t, err)
+	var ids []topoapi.ID
+	for _, object := range list.Objects {
+		ids = append(ids, object.ID)
+	}
+	assert.ElementsMatch(t, []topoapi.ID{"sw-1", "sw-2"}, ids)
+
+	stream, err := client.Query(ctx, &topoapi.QueryRequest{
+		Filters: &topoapi.Filters{
+			LabelFilters: []*topoapi.Filter{{Key: "pod", Filter: &topoapi.Filter_Equal_{Equal_: &topoapi.EqualFilter{Value: "pod-02"}}}},
+		},
+	})
+	assert.NoError(t, err)
+	ids = nil
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		ids = append(ids, res.Object.ID)
+	}
+	assert.Equal(t, []topoapi.ID{"sw-2"}, ids)
+
+	// Watches must replay and stream the objects matching the expression
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watch, err := client.Watch(watchCtx, &topoapi.WatchRequest{})
+	assert.NoError(t, err)
+	ids = nil
+	for i := 0; i < 2; i++ {
+		res, err := watch.Recv()
+		assert.NoError(t, err)
+		ids = append(ids, res.Event.Object.ID)
+	}
+	assert.ElementsMatch(t, []topoapi.ID{"sw-1", "sw-2"}, ids)
+	_, err = client.Create(context.Background(), &topoapi.CreateRequest{Object: &topoapi.Object{
+		ID: "host-2", Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "host"}}, Labels: map[string]string{"pod": "pod-01"},
+	}})
+	assert.NoError(t, err)
+	_, err = client.Create(context.Background(), &topoapi.CreateRequest{Object: &topoapi.Object{
+		ID: "sw-4", Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "switch"}}, Labels: map[string]string{"role": "leaf"},
+	}})
+	assert.NoError(t, err)
+	res, err := watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, topoapi.ID("sw-4"), res.Event.Object.ID)
+
+	// Invalid expressions must be rejected
+	for _, expr := range []string{`{"or": [`, `{"kind": ["switch"], "type": ["ENTITY"]}`} {
+		_, err = client.List(metadata.AppendToOutgoingContext(context.Background(), FilterKey, expr), &topoapi.ListRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), expr)
+	}
+}
+
+func TestCoalescedWatch(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	// Updates within the window must be sent as a single event with the latest state
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, CoalesceWindowKey, "200ms", MaxEventRateKey, "100")
+	res, err := client.Watch(ctx, &topoapi.WatchRequest{Noreplay: true})
+	assert.NoError(t, err)
+	object := &topoapi.Object{
+		ID:   "port-1",
+		Type: topoapi.Object_ENTITY,
+		Obj:  &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "port"}},
+	}
+	// Wait for the watch to be registered before changing the object
+	time.Sleep(100 * time.Millisecond)
+	cres, err := client.Create(context.Background(), &topoapi.CreateRequest{Object: object})
+	assert.NoError(t, err)
+	object = cres.Object
+	for i := 0; i < 3; i++ {
+		object.Labels = map[string]string{"rx": fmt.Sprint(i)}
+		ures, err := client.Update(context.Background(), &topoapi.UpdateRequest{Object: object})
+		assert.NoError(t, err)
+		object = ures.Object
+	}
+	e, err := res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, topoapi.EventType_ADDED, e.Event.Type)
+	assert.Equal(t, "2", e.Event.Object.Labels["rx"])
+	assert.Equal(t, object.Revision, e.Event.Object.Revision)
+
+	// Invalid windows and rates must be rejected
+	for _, md := range [][]string{{CoalesceWindowKey, "soon"}, {CoalesceWindowKey, "-1s"}, {MaxEventRateKey, "0"}} {
+		res, err = client.Watch(metadata.AppendToOutgoingContext(context.Background(), md...), &topoapi.WatchRequest{})
+		assert.NoError(t, err)
+		_, err = res.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), md)
+	}
+}
+
+func TestWatchChanges(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	// Updates must carry the changes to the object, even if only some aspects are requested
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, ChangesKey, "true", FieldsKey, "label:*")
+	res, err := client.Watch(ctx, &topoapi.WatchRequest{Noreplay: true})
+	assert.NoError(t, err)
+	// Wait for the watch to be registered before changing the object
+	time.Sleep(100 * time.Millisecond)
+	cres, err := client.Create(context.Background(), &topoapi.CreateRequest{Object: &topoapi.Object{
+		ID:     "port-1",
+		Type:   topoapi.Object_ENTITY,
+		Obj:    &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "port"}},
+		Labels: map[string]string{"speed": "10G"},
+	}})
+	assert.NoError(t, err)
+	object := cres.Object
+	revision := object.Revision
+	object.Labels = map[string]string{"speed": "100G"}
+	_, err = client.Update(context.Background(), &topoapi.UpdateRequest{Object: object})
+	assert.NoError(t, err)
+
+	e, err := res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, topoapi.EventType_ADDED, e.Event.Type)
+	assert.NotContains(t, e.Event.Object.Aspects, store.ChangesAspect)
+	e, err = res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, topoapi.EventType_UPDATED, e.Event.Type)
+	changes, err := store.GetChanges(&e.Event.Object)
+	assert.NoError(t, err)
+	assert.Equal(t, &store.Changes{
+		PreviousRevision: revision,
+		Labels:           []store.FieldChange{{Key: "speed", Change: store.FieldUpdated, Previous: "10G"}},
+	}, changes)
+
+	// Invalid requests must be rejected
+	res, err = client.Watch(metadata.AppendToOutgoingContext(context.Background(), ChangesKey, "maybe"), &topoapi.WatchRequest{})
+	assert.NoError(t, err)
+	_, err = res.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWatchBookmarks(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	// The replay must be followed by a bookmark, and heartbeats must be sent even if only some fields are requested
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, BookmarksKey, "true", HeartbeatIntervalKey, "100ms", FieldsKey, "label:*")
+	res, err := client.Watch(ctx, &topoapi.WatchRequest{})
+	assert.NoError(t, err)
+	e, err := res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, &store.Bookmark{Type: store.BookmarkSynced}, store.GetBookmark(&e.Event))
+	e, err = res.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, &store.Bookmark{Type: store.BookmarkHeartbeat}, store.GetBookmark(&e.Event))
+
+	// Invalid requests must be rejected
+	for _, md := range [][]string{{BookmarksKey, "maybe"}, {HeartbeatIntervalKey, "0s"}, {HeartbeatIntervalKey, "often"}} {
+		res, err = client.Watch(metadata.AppendToOutgoingContext(context.Background(), md...), &topoapi.WatchRequest{})
+		assert.NoError(t, err)
+		_, err = res.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), md)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+
+	conn := createServerConnection(t, cluster)
+	client := topoapi.NewTopoClient(conn)
+
+	entity := func(id topoapi.ID, kindID topoapi.ID) *topoapi.Object {
+		return &topoapi.Object{ID: id, Type: topoapi.Object_ENTITY, Obj: &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: kindID}}}
+	}
+	relation := func(id topoapi.ID, kindID topoapi.ID, src, tgt topoapi.ID) *topoapi.Object {
+		return &topoapi.Object{ID: id, Type: topoapi.Object_RELATION, Obj: &topoapi.Object_Relation{Relation: &topoapi.Relation{KindID: kindID, SrcEntityID: src, TgtEntityID: tgt}}}
+	}
+	for _, id := range []topoapi.ID{"port-1", "port-2"} {
+		_, err := client.Create(context.Background(), &topoapi.CreateRequest{Object: entity(id, "port")})
+		assert.NoError(t, err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TransactionKey, "true")
+	create := func(operations ...store.Operation) ([]store.Operation, error) {
+		object, err := NewTransactionObject(operations...)
+		assert.NoError(t, err)
+		res, err := client.Create(ctx, &topoapi.CreateRequest{Object: object})
+		if err != nil {
+			return nil, err
+		}
+		return GetTransactionOperations(res.Object)
+	}
+
+	// A link must be created along with the relations to its endpoints, and the committed objects returned
+	committed, err := create(
+		store.CreateOperation(entity("link-1", "link")),
+		store.CreateOperation(relation("link-1-src", "originates", "port-1", "link-1")),
+		store.CreateOperation(relation("link-1-tgt", "terminates", "link-1", "port-2")))
+	assert.NoError(t, err)
+	if assert.Len(t, committed, 3) {
+		for _, op := range committed {
+			assert.Equal(t, store.OperationCreate, op.Type)
+			assert.NotZero(t, op.Object.Revision)
+			assert.NotEmpty(t, op.Object.UUID)
+			gres, err := client.Get(context.Background(), &topoapi.GetRequest{ID: op.Object.ID})
+			assert.NoError(t, err)
+			assert.Equal(t, op.Object.Revision, gres.Object.Revision)
+		}
+	}
+
+	// A failing operation must fail the transaction without applying the other operations
+	_, err = create(
+		store.CreateOperation(entity("link-2", "link")),
+		store.CreateOperation(relation("link-2-src", "originates", "port-3", "link-2")))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = create(
+		store.DeleteOperation("link-1", 0),
+		store.CreateOperation(entity("port-1", "port")))
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	for _, id := range []topoapi.ID{"link-1", "link-1-src", "link-1-tgt"} {
+		_, err := client.Get(context.Background(), &topoapi.GetRequest{ID: id})
+		assert.NoError(t, err)
+	}
+	_, err = client.Get(context.Background(), &topoapi.GetRequest{ID: "link-2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Invalid requests must be rejected
+	_, err = client.Create(ctx, &topoapi.CreateRequest{Object: entity("link-3", "link")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = create()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	object, err := NewTransactionObject(store.CreateOperation(entity("link-3", "link")))
+	assert.NoError(t, err)
+	_, err = client.Create(metadata.AppendToOutgoingContext(context.Background(), TransactionKey, "maybe"), &topoapi.CreateRequest{Object: object})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// A transaction sent without the transaction metadata must not be created as an object
+	_, err = client.Create(context.Background(), &topoapi.CreateRequest{Object: object})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	object.ID = "link-3"
+	object.Type = topoapi.Object_ENTITY
+	object.Obj = &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: "link"}}
+	_, err = client.Create(context.Background(), &topoapi.CreateRequest{Object: object})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.Update(context.Background(), &topoapi.UpdateRequest{Object: object})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.Get(context.Background(), &topoapi.GetRequest{ID: "link-3"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
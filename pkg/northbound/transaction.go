@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package northbound
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-topo/pkg/store"
+)
+
+// TransactionKey is the gRPC metadata key with which Create clients request a transaction instead of the creation
+// of a single object: if set to "true", the object of the request carries the operations of the transaction in its
+// TransactionAspect, and the object of the response carries them with the objects as committed.
+const TransactionKey = "onos-topo-transaction"
+
+// TransactionAspect is the type of the aspect of the objects of transaction requests and responses; its value is
+// the JSON encoding of the TransactionOperations
+const TransactionAspect = "onos.topo.Transaction"
+
+// TransactionOperation is an operation of a transaction requested with TransactionKey
+type TransactionOperation struct {
+	// Type is the type of the operation: "create", "update" or "delete"
+	Type string `json:"type"`
+	// Object is the protobuf encoding of the object to create or update, or of the ID and revision of the object
+	// to delete
+	Object []byte `json:"object"`
+}
+
+// TransactionOperations are the operations of a transaction requested with TransactionKey
+type TransactionOperations struct {
+	Operations []TransactionOperation `json:"operations"`
+}
+
+// operationTypes are the names of the types of transaction operations
+var operationTypes = map[store.OperationType]string{
+	store.OperationCreate: "create",
+	store.OperationUpdate: "update",
+	store.OperationDelete: "delete",
+}
+
+// parseOperationType returns the type of transaction operation with the given name
+func parseOperationType(name string) (store.OperationType, bool) {
+	for operationType, operationName := range operationTypes {
+		if operationName == name {
+			return operationType, true
+		}
+	}
+	return 0, false
+}
+
+// NewTransactionObject returns the object of a Create request for a transaction of the given operations
+func NewTransactionObject(operations ...store.Operation) (*topoapi.Object, error) {
+	transaction := TransactionOperations{Operations: make([]TransactionOperation, 0, len(operations))}
+	for _, op := range operations {
+		operationType, ok := operationTypes[op.Type]
+		if !ok {
+			return nil, errors.NewInvalid("unknown operation type %d", op.Type)
+		}
+		bytes, err := proto.Marshal(op.Object)
+		if err != nil {
+			return nil, errors.NewInvalid("invalid transaction operation: %v", err)
+		}
+		transaction.Operations = append(transaction.Operations, TransactionOperation{Type: operationType, Object: bytes})
+	}
+	value, err := json.Marshal(transaction)
+	if err != nil {
+		return nil, errors.NewInvalid("invalid transaction: %v", err)
+	}
+	return &topoapi.Object{
+		Aspects: map[string]*types.Any{
+			TransactionAspect: {TypeUrl: TransactionAspect, Value: value},
+		},
+	}, nil
+}
+
+// GetTransactionOperations returns the operations carried by the object of a transaction request or response
+func GetTransactionOperations(object *topoapi.Object) ([]store.Operation, error) {
+	aspect, ok := object.GetAspects()[TransactionAspect]
+	if !ok || aspect == nil {
+		return nil, errors.NewInvalid("object does not carry the %s aspect", TransactionAspect)
+	}
+	transaction := TransactionOperations{}
+	if err := json.Unmarshal(aspect.Value, &transaction); err != nil {
+		return nil, errors.NewInvalid("invalid %s aspect: %v", TransactionAspect, err)
+	}
+	operations := make([]store.Operation, 0, len(transaction.Operations))
+	for _, op := range transaction.Operations {
+		operation := store.Operation{Object: &topoapi.Object{}}
+		operationType, ok := parseOperationType(op.Type)
+		if !ok {
+			return nil, errors.NewInvalid("unknown operation type '%s'", op.Type)
+		}
+		operation.Type = operationType
+		if err := proto.Unmarshal(op.Object, operation.Object); err != nil {
+			return nil, errors.NewInvalid("invalid %s object: %v", op.Type, err)
+		}
+		operations = append(operations, operation)
+	}
+	return operations, nil
+}
+
+// transactionRequest returns whether a transaction is requested in the metadata of the given context
+func transactionRequest(ctx context.Context) (bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false, nil
+	}
+	values := md.Get(TransactionKey)
+	if len(values) == 0 {
+		return false, nil
+	}
+	transaction, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, errors.NewInvalid("invalid %s '%s'", TransactionKey, values[0])
+	}
+	return transaction, nil
+}
+
+// checkTransactionAspect checks that the object of a Create or Update request that is not a transaction request
+// does not carry the TransactionAspect, so that a transaction sent without TransactionKey is not created as an object
+func checkTransactionAspect(object *topoapi.Object) error {
+	if _, ok := object.GetAspects()[TransactionAspect]; ok {
+		return errors.NewInvalid("aspect '%s' is reserved for requests with the %s metadata", TransactionAspect, TransactionKey)
+	}
+	return nil
+}
+
+// transaction validates and commits the operations of a transaction request, and returns the object of the
+// response, which carries the operations with the objects as committed
+func (s *Server) transaction(ctx context.Context, object *topoapi.Object) (*topoapi.Object, error) {
+	operations, err := GetTransactionOperations(object)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range operations {
+		if op.Type != store.OperationDelete {
+			if err := s.validate(ctx, op.Object); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.objectStore.Transaction(ctx, operations...); err != nil {
+		return nil, err
+	}
+	return NewTransactionObject(operations...)
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+const (
+	// GroupByType groups objects by their type
+	GroupByType = "type"
+	// GroupByKind groups entities and relations by their kind ID
+	GroupByKind = "kind"
+	// groupByLabelPrefix is the prefix of the fields grouping objects by the value of a label
+	groupByLabelPrefix = "label:"
+)
+
+// GroupCount is the number of objects in a group
+type GroupCount struct {
+	// Group is the value of each field the objects are grouped by; fields the objects do not have are omitted
+	Group map[string]string `json:"group,omitempty"`
+	// Count is the number of objects in the group
+	Count int `json:"count"`
+}
+
+// ParseGroupBy parses a comma-separated list of fields to group objects by: GroupByType, GroupByKind, or a label
+// as "label:<key>", e.g. "kind,label:pod". An empty list counts all the objects in a single group.
+func ParseGroupBy(spec string) ([]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(spec, ",") {
+		fields = append(fields, strings.TrimSpace(field))
+	}
+	if err := validateGroupBy(fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// validateGroupBy returns an Invalid error if any of the given fields cannot group objects
+func validateGroupBy(groupBy []string) error {
+	for _, field := range groupBy {
+		switch {
+		case field == GroupByType, field == GroupByKind:
+		case strings.HasPrefix(field, groupByLabelPrefix) && len(field) > len(groupByLabelPrefix):
+		default:
+			return errors.NewInvalid("invalid group by field '%s'", field)
+		}
+	}
+	return nil
+}
+
+// counter counts objects by group
+type counter struct {
+	groupBy []string
+	counts  map[string]*GroupCount
+}
+
+func newCounter(groupBy []string) *counter {
+	return &counter{
+		groupBy: groupBy,
+		counts:  make(map[string]*GroupCount),
+	}
+}
+
+// add counts the given object in its group
+func (c *counter) add(object *topoapi.Object) {
+	var key strings.Builder
+	group := make(map[string]string, len(c.groupBy))
+	for _, field := range c.groupBy {
+		value, ok := groupValue(object, field)
+		if ok {
+			group[field] = value
+			key.WriteString("+")
+			key.WriteString(value)
+		}
+		key.WriteByte(0)
+	}
+	count, ok := c.counts[key.String()]
+	if !ok {
+		count = &GroupCount{Group: group}
+		c.counts[key.String()] = count
+	}
+	count.Count++
+}
+
+// results returns the counts of the groups ordered by the values of their fields
+func (c *counter) results() []GroupCount {
+	keys := make([]string, 0, len(c.counts))
+	for key := range c.counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	results := make([]GroupCount, 0, len(keys))
+	for _, key := range keys {
+		count := *c.counts[key]
+		if len(count.Group) == 0 {
+			count.Group = nil
+		}
+		results = append(results, count)
+	}
+	return results
+}
+
+// groupValue returns the value of the given group by field of an object, if the object has it
+func groupValue(object *topoapi.Object, field string) (string, bool) {
+	switch field {
+	case GroupByType:
+		return object.Type.String(), true
+	case GroupByKind:
+		kindID := kindID(object)
+		return string(kindID), kindID != topoapi.NullID
+	}
+	value, ok := object.Labels[strings.TrimPrefix(field, groupByLabelPrefix)]
+	return value, ok
+}
+
+// countObjects counts the given objects by group
+func countObjects(objects []topoapi.Object, groupBy []string) []GroupCount {
+	c := newCounter(groupBy)
+	for i := range objects {
+		c.add(&objects[i])
+	}
+	return c.results()
+}
+
+// indexedFilters returns whether the given filters only apply to the fields of objects held by the object index,
+// i.e. their type, kind and labels
+func indexedFilters(filters *topoapi.Filters) bool {
+	if filters == nil {
+		return true
+	}
+	if filters.RelationFilter != nil || len(filters.WithAspects) > 0 {
+		return false
+	}
+	for _, filter := range filters.LabelFilters {
+		if !indexedLabelFilter(filter) {
+			return false
+		}
+	}
+	return true
+}
+
+func indexedLabelFilter(filter *topoapi.Filter) bool {
+	if ngo := filter.GetNot(); ngo != nil {
+		return indexedLabelFilter(ngo.Inner)
+	}
+	if filter.GetKey() == expressionKey {
+		operands, _ := filterOperands(filter)
+		for _, operand := range operands {
+			if e, err := parseExpression(operand); err != nil || !e.indexed {
+				return false
+			}
+		}
+		return true
+	}
+	_, key, _ := parseLabelKey(filter.GetKey())
+	_, _, isAspect := parseAspectKey(key)
+	return !isAspect
+}
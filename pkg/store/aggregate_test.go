@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCount(t *testing.T) {
+	forEachBackend(t, testCount)
+}
+
+func testCount(t *testing.T, newStore func() Store) {
+	store := newStore()
+	for _, c := range []struct {
+		id     topo.ID
+		kind   topo.ID
+		labels map[string]string
+	}{
+		{"pod-1", "pod", nil},
+		{"pod-2", "pod", nil},
+		{"port-1", "port", map[string]string{"pod": "pod-1", "speed": "100"}},
+		{"port-2", "port", map[string]string{"pod": "pod-1", "speed": "400"}},
+		{"port-3", "port", map[string]string{"pod": "pod-2", "speed": "400"}},
+		{"port-4", "port", map[string]string{"speed": "100"}},
+	} {
+		object := &topo.Object{
+			ID:     c.id,
+			Type:   topo.Object_ENTITY,
+			Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: c.kind}},
+			Labels: c.labels,
+		}
+		if c.kind == "port" {
+			assert.NoError(t, object.SetAspectBytes("onos.topo.PortInfo", []byte(`{"enabled": true}`)))
+		}
+		assert.NoError(t, store.Create(context.TODO(), object))
+	}
+	assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+		ID:   "pod-1-port-1",
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "contains", SrcEntityID: "pod-1", TgtEntityID: "port-1"}},
+	}))
+	assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+		ID:   "pod",
+		Type: topo.Object_KIND,
+		Obj:  &topo.Object_Kind{Kind: &topo.Kind{Name: "pod"}},
+	}))
+
+	// Objects must be counted in a single group without group by fields
+	counts, err := store.Count(context.TODO(), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{{Count: 8}}, counts)
+
+	// Objects must be grouped by type and kind, without a kind for kinds
+	counts, err = store.Count(context.TODO(), nil, []string{GroupByType})
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{
+		{Group: map[string]string{GroupByType: "ENTITY"}, Count: 6},
+		{Group: map[string]string{GroupByType: "KIND"}, Count: 1},
+		{Group: map[string]string{GroupByType: "RELATION"}, Count: 1},
+	}, counts)
+	counts, err = store.Count(context.TODO(), nil, []string{GroupByKind})
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{
+		{Count: 1},
+		{Group: map[string]string{GroupByKind: "contains"}, Count: 1},
+		{Group: map[string]string{GroupByKind: "pod"}, Count: 2},
+		{Group: map[string]string{GroupByKind: "port"}, Count: 4},
+	}, counts)
+
+	// Filtered objects must be grouped by several labels
+	portFilters := &topo.Filters{
+		KindFilter: &topo.Filter{Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "port"}}},
+	}
+	counts, err = store.Count(context.TODO(), portFilters, []string{"label:pod", "label:speed"})
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{
+		{Group: map[string]string{"label:speed": "100"}, Count: 1},
+		{Group: map[string]string{"label:pod": "pod-1", "label:speed": "100"}, Count: 1},
+		{Group: map[string]string{"label:pod": "pod-1", "label:speed": "400"}, Count: 1},
+		{Group: map[string]string{"label:pod": "pod-2", "label:speed": "400"}, Count: 1},
+	}, counts)
+	counts, err = store.Count(context.TODO(), &topo.Filters{
+		LabelFilters: []*topo.Filter{NewLabelFilter(LabelGreaterThan, "speed", "100")},
+	}, []string{"label:pod"})
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{
+		{Group: map[string]string{"label:pod": "pod-1"}, Count: 1},
+		{Group: map[string]string{"label:pod": "pod-2"}, Count: 1},
+	}, counts)
+
+	// Filters on aspects and relations must be applied to the objects
+	counts, err = store.Count(context.TODO(), &topo.Filters{WithAspects: []string{"onos.topo.PortInfo"}}, []string{GroupByKind})
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{{Group: map[string]string{GroupByKind: "port"}, Count: 4}}, counts)
+	counts, err = store.Count(context.TODO(), &topo.Filters{
+		LabelFilters: []*topo.Filter{{
+			Key:    AspectKey("onos.topo.PortInfo", "enabled"),
+			Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "true"}},
+		}},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{{Count: 4}}, counts)
+	counts, err = store.Count(context.TODO(), &topo.Filters{
+		RelationFilter: &topo.RelationFilter{SrcId: "pod-1", RelationKind: "contains", TargetKind: "port"},
+	}, []string{GroupByKind})
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{{Group: map[string]string{GroupByKind: "port"}, Count: 1}}, counts)
+
+	// Counts must follow changes to objects
+	assert.NoError(t, store.Delete(context.TODO(), "port-4", 0))
+	counts, err = store.Count(context.TODO(), portFilters, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{{Count: 3}}, counts)
+	counts, err = store.Count(context.TODO(), &topo.Filters{ObjectTypes: []topo.Object_Type{topo.Object_KIND}}, []string{GroupByKind})
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{{Count: 1}}, counts)
+
+	// Invalid group by fields and filters must be rejected
+	_, err = store.Count(context.TODO(), nil, []string{"label:"})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.Count(context.TODO(), &topo.Filters{
+		LabelFilters: []*topo.Filter{NewLabelFilter(LabelGreaterThan, "speed", "fast")},
+	}, nil)
+	assert.True(t, errors.IsInvalid(err))
+}
+
+func TestParseGroupBy(t *testing.T) {
+	fields, err := ParseGroupBy(" kind, label:pod ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{GroupByKind, "label:pod"}, fields)
+	fields, err = ParseGroupBy("")
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+	for _, spec := range []string{"id", "kind,", "label:", "aspect:onos.topo.PortInfo/enabled"} {
+		_, err := ParseGroupBy(spec)
+		assert.True(t, errors.IsInvalid(err), spec)
+	}
+}
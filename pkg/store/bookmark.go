@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/gogo/protobuf/types"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// BookmarkAspect is the type of the aspect of the bookmark events sent to watches requested WithBookmarks or
+// WithHeartbeat; its value is the JSON encoding of the Bookmark
+const BookmarkAspect = "onos.topo.Bookmark"
+
+// BookmarkType is the type of bookmark event
+type BookmarkType string
+
+const (
+	// BookmarkSynced is sent once the replay of a watch is complete; the events that follow are live changes
+	BookmarkSynced BookmarkType = "synced"
+	// BookmarkHeartbeat is sent periodically to watches requested WithHeartbeat
+	BookmarkHeartbeat BookmarkType = "heartbeat"
+)
+
+// Bookmark marks the progress of a watch. Bookmark events are EventType_NONE events for an object with an
+// unspecified type and no ID, whose revision is the revision of the bookmark.
+type Bookmark struct {
+	Type BookmarkType `json:"type"`
+	// Revision is the highest revision of the changes sent to the watcher before the bookmark. Revisions of the
+	// Atomix store are only ordered per partition, so it is the highest revision of all partitions.
+	Revision topoapi.Revision `json:"revision"`
+}
+
+// GetBookmark returns the bookmark of the given event, or nil if the event is not a bookmark
+func GetBookmark(event *topoapi.Event) *Bookmark {
+	if event.Type != topoapi.EventType_NONE || event.Object.Type != topoapi.Object_UNSPECIFIED {
+		return nil
+	}
+	aspect, ok := event.Object.Aspects[BookmarkAspect]
+	if !ok || aspect == nil {
+		return nil
+	}
+	bookmark := &Bookmark{}
+	if err := json.Unmarshal(aspect.Value, bookmark); err != nil {
+		return nil
+	}
+	return bookmark
+}
+
+// newBookmark returns a bookmark event of the given type at the given revision
+func newBookmark(bookmarkType BookmarkType, revision topoapi.Revision) topoapi.Event {
+	value, _ := json.Marshal(Bookmark{Type: bookmarkType, Revision: revision})
+	return topoapi.Event{
+		Type: topoapi.EventType_NONE,
+		Object: topoapi.Object{
+			Revision: revision,
+			Aspects: map[string]*types.Any{
+				BookmarkAspect: {TypeUrl: BookmarkAspect, Value: value},
+			},
+		},
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchBookmarks(t *testing.T) {
+	forEachBackend(t, testWatchBookmarks)
+}
+
+func testWatchBookmarks(t *testing.T, newStore func() Store) {
+	store := newStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Wait for the objects to be observed by the store before replaying them
+	observer := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, observer, nil))
+	var revision topo.Revision
+	for _, id := range []topo.ID{"sw-1", "sw-2", "sw-3"} {
+		object := &topo.Object{
+			ID:   id,
+			Type: topo.Object_ENTITY,
+			Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "switch"}},
+		}
+		assert.NoError(t, store.Create(context.TODO(), object))
+		if object.Revision > revision {
+			revision = object.Revision
+		}
+	}
+	waitForEvents(t, observer, "sw-1", "sw-2", "sw-3")
+
+	// The end of the replay must be marked with the revision of the replayed objects, even if no object matches
+	for _, filters := range []*topo.Filters{nil, {KindFilter: &topo.Filter{Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "host"}}}}} {
+		ch := make(chan topo.Event)
+		assert.NoError(t, store.Watch(ctx, ch, filters, WithReplay(), WithBookmarks()))
+		replayed := 0
+		var bookmark *Bookmark
+		for bookmark == nil {
+			event := nextWatchEvent(t, ch)
+			if bookmark = GetBookmark(&event); bookmark == nil {
+				assert.Equal(t, topo.EventType_NONE, event.Type)
+				replayed++
+			}
+		}
+		if filters == nil {
+			assert.Equal(t, 3, replayed)
+		} else {
+			assert.Equal(t, 0, replayed)
+		}
+		assert.Equal(t, &Bookmark{Type: BookmarkSynced, Revision: revision}, bookmark)
+	}
+
+	// Heartbeats must carry the revision of the changes sent before them
+	heartbeats := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, heartbeats, nil, WithHeartbeat(50*time.Millisecond)))
+	event := nextWatchEvent(t, heartbeats)
+	assert.Equal(t, &Bookmark{Type: BookmarkHeartbeat, Revision: revision}, GetBookmark(&event))
+	object, err := store.Get(context.TODO(), "sw-1")
+	assert.NoError(t, err)
+	object.Labels = map[string]string{"role": "spine"}
+	assert.NoError(t, store.Update(context.TODO(), object))
+	for {
+		event = nextWatchEvent(t, heartbeats)
+		if GetBookmark(&event) == nil {
+			break
+		}
+	}
+	assert.Equal(t, topo.EventType_UPDATED, event.Type)
+	event = nextWatchEvent(t, heartbeats)
+	if bookmark := GetBookmark(&event); assert.NotNil(t, bookmark) {
+		assert.Equal(t, BookmarkHeartbeat, bookmark.Type)
+		assert.GreaterOrEqual(t, bookmark.Revision, object.Revision)
+	}
+
+	// Objects must not be mistaken for bookmarks
+	assert.Nil(t, GetBookmark(&topo.Event{Type: topo.EventType_NONE, Object: *object}))
+
+	err = store.Watch(ctx, make(chan topo.Event), nil, WithHeartbeat(-time.Second))
+	assert.True(t, errors.IsInvalid(err))
+}
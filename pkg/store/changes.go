@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// ChangesAspect is the type of the aspect added to the UPDATED and REMOVED events of watches requested WithChanges;
+// its value is the JSON encoding of the Changes to the object of the event
+const ChangesAspect = "onos.topo.Changes"
+
+// ChangeType is the type of change to a field of an object
+type ChangeType string
+
+const (
+	// FieldAdded is the change of a field set since the previous state of an object
+	FieldAdded ChangeType = "added"
+	// FieldRemoved is the change of a field no longer set since the previous state of an object
+	FieldRemoved ChangeType = "removed"
+	// FieldUpdated is the change of a field whose value differs from the previous state of an object
+	FieldUpdated ChangeType = "updated"
+)
+
+// FieldChange is a change to a field of an object
+type FieldChange struct {
+	// Key is the label key or aspect type; it is empty for the kind, source and target of objects
+	Key    string     `json:"key,omitempty"`
+	Change ChangeType `json:"change"`
+	// Previous is the previous value of the field, if it was set
+	Previous string `json:"previous,omitempty"`
+}
+
+// Changes describes how an object changed since its previous state
+type Changes struct {
+	// PreviousRevision is the revision of the previous state of the object
+	PreviousRevision topoapi.Revision `json:"previousRevision"`
+	// Labels are the changes to the labels, ordered by key
+	Labels []FieldChange `json:"labels,omitempty"`
+	// Aspects are the changes to the aspects, ordered by type; the previous value is the aspect value
+	Aspects []FieldChange `json:"aspects,omitempty"`
+	// Kind is the change to the kind of an entity or relation
+	Kind *FieldChange `json:"kind,omitempty"`
+	// Source is the change to the source entity of a relation
+	Source *FieldChange `json:"source,omitempty"`
+	// Target is the change to the target entity of a relation
+	Target *FieldChange `json:"target,omitempty"`
+}
+
+// GetChanges returns the changes described by the ChangesAspect of an event object, or nil if the object does
+// not have the aspect
+func GetChanges(object *topoapi.Object) (*Changes, error) {
+	aspect, ok := object.Aspects[ChangesAspect]
+	if !ok || aspect == nil {
+		return nil, nil
+	}
+	changes := &Changes{}
+	if err := json.Unmarshal(aspect.Value, changes); err != nil {
+		return nil, errors.NewInvalid("invalid %s aspect: %v", ChangesAspect, err)
+	}
+	return changes, nil
+}
+
+// diffObjects returns the changes from the previous to the current state of an object
+func diffObjects(previous, object *topoapi.Object) Changes {
+	changes := Changes{
+		PreviousRevision: previous.Revision,
+	}
+	for _, key := range unionKeys(previous.Labels, object.Labels) {
+		before, hadLabel := previous.Labels[key]
+		after, hasLabel := object.Labels[key]
+		if change, ok := diffField(key, before, hadLabel, after, hasLabel); ok {
+			changes.Labels = append(changes.Labels, change)
+		}
+	}
+	for _, aspectType := range unionKeys(previous.Aspects, object.Aspects) {
+		if aspectType == ChangesAspect || aspectType == TransactionGroupAspect {
+			continue
+		}
+		before, hadAspect := previous.Aspects[aspectType]
+		after, hasAspect := object.Aspects[aspectType]
+		if change, ok := diffField(aspectType, string(before.GetValue()), before != nil && hadAspect,
+			string(after.GetValue()), after != nil && hasAspect); ok {
+			changes.Aspects = append(changes.Aspects, change)
+		}
+	}
+
+	changes.Kind = diffID(kindID(previous), kindID(object))
+	changes.Source = diffID(previous.GetRelation().GetSrcEntityID(), object.GetRelation().GetSrcEntityID())
+	changes.Target = diffID(previous.GetRelation().GetTgtEntityID(), object.GetRelation().GetTgtEntityID())
+	return changes
+}
+
+// diffField returns the change to a field from its previous to its current value, if any
+func diffField(key string, before string, hadField bool, after string, hasField bool) (FieldChange, bool) {
+	switch {
+	case hadField && !hasField:
+		return FieldChange{Key: key, Change: FieldRemoved, Previous: before}, true
+	case !hadField && hasField:
+		return FieldChange{Key: key, Change: FieldAdded}, true
+	case hadField && before != after:
+		return FieldChange{Key: key, Change: FieldUpdated, Previous: before}, true
+	}
+	return FieldChange{}, false
+}
+
+// diffID returns the change to an ID field of an object, or nil if the field did not change
+func diffID(before, after topoapi.ID) *FieldChange {
+	if change, ok := diffField("", string(before), before != "", string(after), after != ""); ok {
+		return &change
+	}
+	return nil
+}
+
+// unionKeys returns the keys of both maps in order
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkReserved checks that an object being written does not carry the aspects reserved for watch events, nor the
+// label keys reserved for filters
+func checkReserved(object *topoapi.Object) error {
+	for _, aspectType := range []string{ChangesAspect, BookmarkAspect, TransactionGroupAspect} {
+		if _, ok := object.Aspects[aspectType]; ok {
+			return errors.NewInvalid("Aspect '%s' is reserved for watch events", aspectType)
+		}
+	}
+	return checkLabels(object)
+}
+
+// withChanges returns the given event with a ChangesAspect describing the changes since the previous state of its
+// object
+func withChanges(event topoapi.Event, previous *topoapi.Object) topoapi.Event {
+	value, err := json.Marshal(diffObjects(previous, &event.Object))
+	if err != nil {
+		log.Warnf("Failed to describe the changes to Object %s: %v", event.Object.ID, err)
+		return event
+	}
+	return withAspect(event, ChangesAspect, value)
+}
+
+// withAspect returns the given event with an aspect of the given type and value added to its object. The aspects of
+// the event object are copied, as they are shared with the store and the other watchers.
+func withAspect(event topoapi.Event, aspectType string, value []byte) topoapi.Event {
+	aspects := make(map[string]*types.Any, len(event.Object.Aspects)+1)
+	for t, aspect := range event.Object.Aspects {
+		aspects[t] = aspect
+	}
+	aspects[aspectType] = &types.Any{TypeUrl: aspectType, Value: value}
+	event.Object.Aspects = aspects
+	return event
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchChanges(t *testing.T) {
+	forEachBackend(t, testWatchChanges)
+}
+
+func testWatchChanges(t *testing.T, newStore func() Store) {
+	store := newStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nextChanges := func(ch chan topo.Event, eventType topo.EventType) *Changes {
+		event := nextWatchEvent(t, ch)
+		assert.Equal(t, eventType, event.Type)
+		changes, err := GetChanges(&event.Object)
+		assert.NoError(t, err)
+		return changes
+	}
+
+	createSwitch := func(id topo.ID) {
+		assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+			ID:   id,
+			Type: topo.Object_ENTITY,
+			Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "switch"}},
+		}))
+	}
+	changed := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, changed, nil, WithChanges()))
+	unchanged := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, unchanged, nil))
+	for _, id := range []topo.ID{"sw-1", "sw-2", "sw-3"} {
+		createSwitch(id)
+	}
+	waitForEvents(t, changed, "sw-1", "sw-2", "sw-3")
+	waitForEvents(t, unchanged, "sw-1", "sw-2", "sw-3")
+
+	// Added objects have no previous state
+	object := &topo.Object{
+		ID:     "sw-1-sw-2",
+		Type:   topo.Object_RELATION,
+		Obj:    &topo.Object_Relation{Relation: &topo.Relation{KindID: "link", SrcEntityID: "sw-1", TgtEntityID: "sw-2"}},
+		Labels: map[string]string{"speed": "10G", "vlan": "10"},
+	}
+	assert.NoError(t, object.SetAspectBytes("onos.topo.Link", []byte(`{"mtu": 1500}`)))
+	assert.NoError(t, store.Create(context.TODO(), object))
+	assert.Nil(t, nextChanges(changed, topo.EventType_ADDED))
+	nextWatchEvent(t, unchanged)
+	revision := object.Revision
+
+	// Updates must describe the changes to labels, aspects and relation fields
+	object.Labels = map[string]string{"speed": "100G", "pod": "pod-01"}
+	assert.NoError(t, object.SetAspectBytes("onos.topo.Link", []byte(`{"mtu": 9000}`)))
+	assert.NoError(t, object.SetAspectBytes("onos.topo.Location", []byte(`{"lat": 1}`)))
+	object.GetRelation().KindID = "trunk"
+	object.GetRelation().TgtEntityID = "sw-3"
+	assert.NoError(t, store.Update(context.TODO(), object))
+	event := nextWatchEvent(t, changed)
+	assert.Equal(t, topo.EventType_UPDATED, event.Type)
+	assert.Equal(t, "trunk", string(event.Object.GetRelation().KindID))
+	changes, err := GetChanges(&event.Object)
+	assert.NoError(t, err)
+	assert.Equal(t, &Changes{
+		PreviousRevision: revision,
+		Labels: []FieldChange{
+			{Key: "pod", Change: FieldAdded},
+			{Key: "speed", Change: FieldUpdated, Previous: "10G"},
+			{Key: "vlan", Change: FieldRemoved, Previous: "10"},
+		},
+		Aspects: []FieldChange{
+			{Key: "onos.topo.Link", Change: FieldUpdated, Previous: `{"mtu": 1500}`},
+			{Key: "onos.topo.Location", Change: FieldAdded},
+		},
+		Kind:   &FieldChange{Change: FieldUpdated, Previous: "link"},
+		Target: &FieldChange{Change: FieldUpdated, Previous: "sw-2"},
+	}, changes)
+
+	// Watches without the option and stored objects must not carry the changes
+	event = nextWatchEvent(t, unchanged)
+	assert.NotContains(t, event.Object.Aspects, ChangesAspect)
+	stored, err := store.Get(context.TODO(), object.ID)
+	assert.NoError(t, err)
+	assert.NotContains(t, stored.Aspects, ChangesAspect)
+
+	// Removals must refer to the state of the object when it was removed
+	revision = object.Revision
+	assert.NoError(t, store.Delete(context.TODO(), object.ID, 0))
+	changes = nextChanges(changed, topo.EventType_REMOVED)
+	if assert.NotNil(t, changes) {
+		assert.Equal(t, revision, changes.PreviousRevision)
+		assert.Empty(t, changes.Labels)
+		assert.Nil(t, changes.Kind)
+	}
+	nextWatchEvent(t, unchanged)
+
+	// Coalesced events must describe the changes since the state last delivered to the watcher
+	coalesced := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, coalesced, nil, WithChanges(), WithCoalesceWindow(200*time.Millisecond)))
+	createSwitch("sw-4")
+	waitForEvents(t, coalesced, "sw-4")
+	switch4, err := store.Get(context.TODO(), "sw-4")
+	assert.NoError(t, err)
+	revision = switch4.Revision
+	for _, labels := range []map[string]string{{"role": "leaf"}, {"role": "spine"}} {
+		switch4.Labels = labels
+		assert.NoError(t, store.Update(context.TODO(), switch4))
+	}
+	changes = nextChanges(coalesced, topo.EventType_UPDATED)
+	assert.Equal(t, &Changes{
+		PreviousRevision: revision,
+		Labels:           []FieldChange{{Key: "role", Change: FieldAdded}},
+	}, changes)
+
+	// Objects carrying the aspects of watch events must not be written
+	switch4, err = store.Get(context.TODO(), "sw-4")
+	assert.NoError(t, err)
+	for _, aspectType := range []string{ChangesAspect, BookmarkAspect} {
+		reserved := *switch4
+		assert.NoError(t, reserved.SetAspectBytes(aspectType, []byte(`{}`)))
+		err = store.Update(context.TODO(), &reserved)
+		assert.True(t, errors.IsInvalid(err))
+		err = store.Transaction(context.TODO(), UpdateOperation(&reserved))
+		assert.True(t, errors.IsInvalid(err))
+		reserved.ID = "sw-5"
+		err = store.Create(context.TODO(), &reserved)
+		assert.True(t, errors.IsInvalid(err))
+		err = store.Transaction(context.TODO(), CreateOperation(&reserved))
+		assert.True(t, errors.IsInvalid(err))
+	}
+	_, err = store.Get(context.TODO(), "sw-5")
+	assert.True(t, errors.IsNotFound(err))
+}
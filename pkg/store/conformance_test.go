@@ -0,0 +1,316 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/atomix/go-sdk/pkg/test"
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// forEachBackend runs the given test against every Store implementation.
+// Stores returned by newStore within a single run share the same underlying state.
+func forEachBackend(t *testing.T, f func(t *testing.T, newStore func() Store)) {
+	t.Run("Atomix", func(t *testing.T) {
+		cluster := test.NewClient()
+		defer cluster.Close()
+		f(t, func() Store {
+			store, err := NewAtomixStore(cluster)
+			assert.NoError(t, err)
+			return store
+		})
+	})
+	t.Run("Memory", func(t *testing.T) {
+		store := NewMemoryStore()
+		defer store.Close()
+		f(t, func() Store {
+			return store
+		})
+	})
+}
+
+func TestRevisions(t *testing.T) {
+	forEachBackend(t, testRevisions)
+}
+
+func testRevisions(t *testing.T, newStore func() Store) {
+	store := newStore()
+
+	obj := &topo.Object{
+		ID:   "e1",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "foo"}},
+	}
+	err := store.Create(context.TODO(), obj)
+	assert.NoError(t, err)
+	created := obj.Revision
+	assert.NotEqual(t, topo.Revision(0), created)
+
+	// Creating the same object again must fail
+	err = store.Create(context.TODO(), &topo.Object{ID: "e1", Type: topo.Object_ENTITY})
+	assert.True(t, errors.IsAlreadyExists(err))
+
+	// Updates must advance the revision
+	obj.Labels = map[string]string{"env": "test"}
+	err = store.Update(context.TODO(), obj)
+	assert.NoError(t, err)
+	assert.Greater(t, obj.Revision, created)
+
+	// Updates and deletes with a stale revision must fail
+	stale := &topo.Object{ID: "e1", Type: topo.Object_ENTITY, Revision: created}
+	err = store.Update(context.TODO(), stale)
+	assert.True(t, errors.IsConflict(err))
+	err = store.Delete(context.TODO(), "e1", created)
+	assert.True(t, errors.IsConflict(err))
+
+	// Updates and deletes of missing objects must fail
+	err = store.Update(context.TODO(), &topo.Object{ID: "e2", Type: topo.Object_ENTITY, Revision: created})
+	assert.True(t, errors.IsNotFound(err))
+	err = store.Delete(context.TODO(), "e2", 0)
+	assert.True(t, errors.IsNotFound(err))
+
+	// Reads must return the latest revision
+	obj, err = store.Get(context.TODO(), "e1")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", obj.Labels["env"])
+
+	err = store.Delete(context.TODO(), "e1", obj.Revision)
+	assert.NoError(t, err)
+	_, err = store.Get(context.TODO(), "e1")
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestRelations(t *testing.T) {
+	forEachBackend(t, testRelations)
+}
+
+func testRelations(t *testing.T, newStore func() Store) {
+	store := newStore()
+
+	createNode(t, store, auxNode{id: "n1"})
+	createCell(t, store, auxCell{id: "c1"})
+	createCell(t, store, auxCell{id: "c2"})
+	createNodeToCell(t, store, auxNodeToCell{srcID: "n1", tgtID: "c1"})
+	createNodeToCell(t, store, auxNodeToCell{srcID: "n1", tgtID: "c2"})
+	createCellNeighbors(t, store, auxCellNeighbor{srcID: "c1", tgtID: "c2"})
+
+	// Relations must not be created with missing endpoints
+	err := store.Create(context.TODO(), &topo.Object{
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "e2-node-cell", SrcEntityID: "n1", TgtEntityID: "c3"}},
+	})
+	assert.True(t, errors.IsInvalid(err))
+
+	waitForRelations(t, store, "n1", 2, 0)
+	waitForRelations(t, store, "c1", 1, 2)
+	waitForRelations(t, store, "c2", 1, 2)
+
+	ch := make(chan topo.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = store.Watch(ctx, ch, nil)
+	assert.NoError(t, err)
+
+	// Deleting an entity must delete its relations
+	err = store.Delete(context.TODO(), "c1", 0)
+	assert.NoError(t, err)
+	removed := make(map[topo.ID]topo.Object_Type)
+	for {
+		event := nextWatchEvent(t, ch)
+		if event.Type == topo.EventType_REMOVED {
+			removed[event.Object.ID] = event.Object.Type
+			if event.Object.ID == "c1" {
+				break
+			}
+		}
+	}
+	// The relations must be removed before the entity
+	assert.Len(t, removed, 4)
+
+	objects, err := store.List(context.TODO(), &topo.Filters{ObjectTypes: []topo.Object_Type{topo.Object_RELATION}})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	waitForRelations(t, store, "n1", 1, 0)
+	waitForRelations(t, store, "c2", 0, 1)
+}
+
+// waitForRelations waits for the relation IDs of the given entity to reach the expected counts
+func waitForRelations(t *testing.T, store Store, id topo.ID, srcs, tgts int) {
+	assert.Eventually(t, func() bool {
+		object, err := store.Get(context.TODO(), id)
+		return err == nil &&
+			len(object.GetEntity().SrcRelationIDs) == srcs &&
+			len(object.GetEntity().TgtRelationIDs) == tgts
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRelationEndpoints(t *testing.T) {
+	forEachBackend(t, testRelationEndpoints)
+}
+
+func testRelationEndpoints(t *testing.T, newStore func() Store) {
+	store := newStore()
+
+	createNode(t, store, auxNode{id: "n1"})
+	createNode(t, store, auxNode{id: "n2"})
+	createCell(t, store, auxCell{id: "c1"})
+	createCell(t, store, auxCell{id: "c2"})
+	relation := &topo.Object{
+		ID:   "r1",
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "e2-node-cell", SrcEntityID: "n1", TgtEntityID: "c1"}},
+	}
+	err := store.Create(context.TODO(), relation)
+	assert.NoError(t, err)
+	waitForRelations(t, store, "n1", 1, 0)
+	waitForRelations(t, store, "c1", 0, 1)
+
+	// Relations must not be re-pointed to missing endpoints
+	relation.GetRelation().TgtEntityID = "c3"
+	err = store.Update(context.TODO(), relation)
+	assert.True(t, errors.IsInvalid(err))
+
+	// Re-pointing a relation must move it to the relation IDs of its new endpoints
+	relation.GetRelation().SrcEntityID = "n2"
+	relation.GetRelation().TgtEntityID = "c2"
+	err = store.Update(context.TODO(), relation)
+	assert.NoError(t, err)
+	waitForRelations(t, store, "n1", 0, 0)
+	waitForRelations(t, store, "c1", 0, 0)
+	waitForRelations(t, store, "n2", 1, 0)
+	waitForRelations(t, store, "c2", 0, 1)
+
+	// Updates that do not change the endpoints must not be rejected
+	relation.Labels = map[string]string{"band": "n78"}
+	err = store.Update(context.TODO(), relation)
+	assert.NoError(t, err)
+
+	objects, err := store.List(context.TODO(), &topo.Filters{
+		RelationFilter: &topo.RelationFilter{SrcId: "n2", RelationKind: "e2-node-cell", Scope: topo.RelationFilterScope_TARGETS_ONLY},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, topo.ID("c2"), objects[0].ID)
+
+	// Transactions must validate and re-index the endpoints of updated relations
+	relation.GetRelation().SrcEntityID = "n3"
+	err = store.Transaction(context.TODO(), UpdateOperation(relation))
+	assert.True(t, errors.IsInvalid(err))
+	relation.GetRelation().SrcEntityID = "n1"
+	err = store.Transaction(context.TODO(), UpdateOperation(relation))
+	assert.NoError(t, err)
+	waitForRelations(t, store, "n1", 1, 0)
+	waitForRelations(t, store, "n2", 0, 0)
+
+	// Deleting a previous endpoint must not delete the relation; deleting a current endpoint must
+	err = store.Delete(context.TODO(), "n2", 0)
+	assert.NoError(t, err)
+	_, err = store.Get(context.TODO(), "r1")
+	assert.NoError(t, err)
+	err = store.Delete(context.TODO(), "c2", 0)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := store.Get(context.TODO(), "r1")
+		return errors.IsNotFound(err)
+	}, 5*time.Second, 10*time.Millisecond)
+	waitForRelations(t, store, "n1", 0, 0)
+}
+
+func TestWatchReplay(t *testing.T) {
+	forEachBackend(t, testWatchReplay)
+}
+
+func testWatchReplay(t *testing.T, newStore func() Store) {
+	store := newStore()
+
+	// Wait for the store to publish the initial objects before replaying them
+	ch := make(chan topo.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := store.Watch(ctx, ch, nil)
+	assert.NoError(t, err)
+	createNode(t, store, auxNode{id: "n1"})
+	createCell(t, store, auxCell{id: "c1"})
+	waitForEvents(t, ch, "n1", "c1")
+	cancel()
+
+	ch = make(chan topo.Event)
+	ctx, cancel = context.WithCancel(context.Background())
+	err = store.Watch(ctx, ch, nil, WithReplay())
+	assert.NoError(t, err)
+
+	replayed := make(map[topo.ID]bool)
+	for i := 0; i < 2; i++ {
+		event := nextWatchEvent(t, ch)
+		assert.Equal(t, topo.EventType_NONE, event.Type)
+		replayed[event.Object.ID] = true
+	}
+	assert.True(t, replayed["n1"])
+	assert.True(t, replayed["c1"])
+
+	createCell(t, store, auxCell{id: "c2"})
+	event := nextWatchEvent(t, ch)
+	assert.Equal(t, topo.EventType_ADDED, event.Type)
+	assert.Equal(t, topo.ID("c2"), event.Object.ID)
+	assert.NotEqual(t, topo.Revision(0), event.Object.Revision)
+
+	// The event channel must be closed once the context is canceled
+	cancel()
+	for range ch { //revive:disable-line:empty-block
+	}
+}
+
+func TestIndexedList(t *testing.T) {
+	forEachBackend(t, testIndexedList)
+}
+
+func testIndexedList(t *testing.T, newStore func() Store) {
+	store := newStore()
+
+	for i := 0; i < 20; i++ {
+		createCell(t, store, auxCell{id: fmt.Sprintf("c%d", i), labels: map[string]string{"pod": fmt.Sprintf("pod-%d", i%10)}})
+	}
+	createNode(t, store, auxNode{id: "n1", labels: map[string]string{"pod": "pod-1"}})
+
+	// Lists narrowed by the indexes must reflect preceding writes
+	kindFilter := &topo.Filter{Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "e2-node"}}}
+	objects, err := store.List(context.TODO(), &topo.Filters{KindFilter: kindFilter})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	podFilter := []*topo.Filter{{Key: "pod", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "pod-1"}}}}
+	objects, err = store.List(context.TODO(), &topo.Filters{LabelFilters: podFilter})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 3)
+
+	objects, err = store.List(context.TODO(), &topo.Filters{KindFilter: kindFilter, LabelFilters: podFilter})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, topo.ID("n1"), objects[0].ID)
+
+	// Relabel an object and verify the indexes follow
+	object, err := store.Get(context.TODO(), "c1")
+	assert.NoError(t, err)
+	object.Labels["pod"] = "pod-2"
+	err = store.Update(context.TODO(), object)
+	assert.NoError(t, err)
+
+	ch := make(chan *topo.Object, depth)
+	err = store.Query(context.TODO(), ch, &topo.Filters{LabelFilters: podFilter})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, consume(ch))
+
+	err = store.Delete(context.TODO(), "n1", 0)
+	assert.NoError(t, err)
+	objects, err = store.List(context.TODO(), &topo.Filters{KindFilter: kindFilter})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 0)
+}
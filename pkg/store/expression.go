@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// expressionKey is the key of the label filters holding a JSON encoded FilterExpr. Since the Filters message can
+// only AND its filters, expressions are encoded in label filters so that they are combined with the other filters
+// and evaluated by List, Query, Watch and the other store queries like any label filter.
+const expressionKey = filterKeyPrefix + "expr"
+
+// maxExpressionDepth bounds the nesting of filter expressions
+const maxExpressionDepth = 32
+
+// FilterExpr is a boolean expression over the kind, type, labels and aspects of objects. Exactly one of its fields
+// must be set: And, Or and Not compose nested expressions, and the others are predicates.
+type FilterExpr struct {
+	// And matches objects matching all the nested expressions
+	And []*FilterExpr `json:"and,omitempty"`
+	// Or matches objects matching any of the nested expressions
+	Or []*FilterExpr `json:"or,omitempty"`
+	// Not matches objects not matching the nested expression
+	Not *FilterExpr `json:"not,omitempty"`
+	// Kind matches entities and relations with any of the given kind IDs
+	Kind []string `json:"kind,omitempty"`
+	// Type matches objects of any of the given types, e.g. "ENTITY"
+	Type []string `json:"type,omitempty"`
+	// Label matches objects like a label filter
+	Label *LabelPredicate `json:"label,omitempty"`
+	// Aspect matches objects that have an aspect of the given type
+	Aspect string `json:"aspect,omitempty"`
+}
+
+// LabelPredicate matches the value of a label, or of an aspect field, like a label filter
+type LabelPredicate struct {
+	// Key is the label key, or the dotted path of the field if Aspect is set
+	Key string `json:"key"`
+	// Aspect is the type of the aspect holding the field matched instead of a label
+	Aspect string `json:"aspect,omitempty"`
+	// Operator is applied to the value with each of the values as operand; without operator, the value must be
+	// equal to any of the values, missing values being compared as empty values
+	Operator LabelOperator `json:"op,omitempty"`
+	// Values are the values or operands the value is compared to; they are optional for LabelExists
+	Values []string `json:"values,omitempty"`
+}
+
+// NewExpressionFilter returns a label filter matching the objects that match the given expression, to be added to
+// the label filters of a Filters message
+func NewExpressionFilter(expr *FilterExpr) (*topoapi.Filter, error) {
+	if _, err := compileExpr(expr, 0); err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(expr)
+	if err != nil {
+		return nil, errors.NewInvalid("invalid filter expression: %v", err)
+	}
+	return &topoapi.Filter{
+		Key:    expressionKey,
+		Filter: &topoapi.Filter_Equal_{Equal_: &topoapi.EqualFilter{Value: string(value)}},
+	}, nil
+}
+
+// expression is a compiled filter expression
+type expression struct {
+	match func(object *topoapi.Object) bool
+	// indexed is true if the expression only applies to the fields of objects held by the object index
+	indexed bool
+}
+
+// compileExpr validates the given expression and compiles it into a predicate
+func compileExpr(expr *FilterExpr, depth int) (*expression, error) {
+	if expr == nil {
+		return nil, errors.NewInvalid("invalid filter expression: empty expression")
+	}
+	if depth > maxExpressionDepth {
+		return nil, errors.NewInvalid("invalid filter expression: more than %d nested expressions", maxExpressionDepth)
+	}
+	set := 0
+	for _, ok := range []bool{expr.And != nil, expr.Or != nil, expr.Not != nil, expr.Kind != nil, expr.Type != nil, expr.Label != nil, expr.Aspect != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.NewInvalid("invalid filter expression: expected exactly one of and, or, not, kind, type, label and aspect")
+	}
+
+	switch {
+	case expr.And != nil, expr.Or != nil:
+		operands := expr.And
+		if expr.Or != nil {
+			operands = expr.Or
+		}
+		if len(operands) == 0 {
+			return nil, errors.NewInvalid("invalid filter expression: empty and/or")
+		}
+		compiled := make([]*expression, 0, len(operands))
+		indexed := true
+		for _, operand := range operands {
+			e, err := compileExpr(operand, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			compiled = append(compiled, e)
+			indexed = indexed && e.indexed
+		}
+		all := expr.And != nil
+		return &expression{
+			match: func(object *topoapi.Object) bool {
+				for _, e := range compiled {
+					if e.match(object) != all {
+						return !all
+					}
+				}
+				return all
+			},
+			indexed: indexed,
+		}, nil
+	case expr.Not != nil:
+		inner, err := compileExpr(expr.Not, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return &expression{
+			match: func(object *topoapi.Object) bool {
+				return !inner.match(object)
+			},
+			indexed: inner.indexed,
+		}, nil
+	case expr.Kind != nil:
+		filter := &topoapi.Filter{Filter: &topoapi.Filter_In{In: &topoapi.InFilter{Values: expr.Kind}}}
+		return &expression{
+			match: func(object *topoapi.Object) bool {
+				return matchKind(object, filter)
+			},
+			indexed: true,
+		}, nil
+	case expr.Type != nil:
+		types := make([]topoapi.Object_Type, 0, len(expr.Type))
+		for _, name := range expr.Type {
+			objectType, ok := topoapi.Object_Type_value[name]
+			if !ok {
+				return nil, errors.NewInvalid("invalid filter expression: unknown object type '%s'", name)
+			}
+			types = append(types, topoapi.Object_Type(objectType))
+		}
+		if len(types) == 0 {
+			return nil, errors.NewInvalid("invalid filter expression: empty type")
+		}
+		return &expression{
+			match: func(object *topoapi.Object) bool {
+				return matchType(object, types)
+			},
+			indexed: true,
+		}, nil
+	case expr.Label != nil:
+		filter, err := expr.Label.filter()
+		if err != nil {
+			return nil, err
+		}
+		return &expression{
+			match: func(object *topoapi.Object) bool {
+				return matchLabel(object, filter)
+			},
+			indexed: indexedLabelFilter(filter),
+		}, nil
+	}
+	aspects := []string{expr.Aspect}
+	return &expression{
+		match: func(object *topoapi.Object) bool {
+			return matchAspects(object, aspects)
+		},
+	}, nil
+}
+
+// filter returns the label filter equivalent to the predicate
+func (p *LabelPredicate) filter() (*topoapi.Filter, error) {
+	if p.Key == "" || strings.HasPrefix(p.Key, filterKeyPrefix) {
+		return nil, errors.NewInvalid("invalid filter expression: invalid label key %q", p.Key)
+	}
+	key := p.Key
+	if p.Aspect != "" {
+		key = AspectKey(p.Aspect, p.Key)
+	}
+	if p.Operator != "" {
+		operator, _, ok := parseLabelKey(NewLabelFilter(p.Operator, "", "").Key)
+		if !ok || operator != p.Operator {
+			return nil, errors.NewInvalid("invalid filter expression: unknown label operator '%s'", p.Operator)
+		}
+		key = NewLabelFilter(p.Operator, key, "").Key
+	}
+	values := p.Values
+	if len(values) == 0 {
+		if p.Operator != LabelExists {
+			return nil, errors.NewInvalid("invalid filter expression: no values for label '%s'", p.Key)
+		}
+		values = []string{""}
+	}
+	filter := &topoapi.Filter{Key: key, Filter: &topoapi.Filter_In{In: &topoapi.InFilter{Values: values}}}
+	if err := validateLabelFilter(filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// maxCachedExpressions bounds the number of compiled filter expressions kept for label filters
+const maxCachedExpressions = 256
+
+// expressions are the compiled filter expressions of label filters
+var expressions = newLRU[*expression](maxCachedExpressions)
+
+// parseExpression returns the compiled JSON encoded filter expression, compiling each expression once while it is
+// cached
+func parseExpression(value string) (*expression, error) {
+	if e, ok := expressions.get(value); ok {
+		return e, nil
+	}
+	var expr FilterExpr
+	if err := json.Unmarshal([]byte(value), &expr); err != nil {
+		return nil, errors.NewInvalid("invalid filter expression: %v", err)
+	}
+	e, err := compileExpr(&expr, 0)
+	if err != nil {
+		return nil, err
+	}
+	expressions.add(value, e)
+	return e, nil
+}
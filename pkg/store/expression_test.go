@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterExpressions(t *testing.T) {
+	forEachBackend(t, testFilterExpressions)
+}
+
+func testFilterExpressions(t *testing.T, newStore func() Store) {
+	store := newStore()
+	for _, c := range []struct {
+		id     topo.ID
+		kind   topo.ID
+		labels map[string]string
+		role   string
+	}{
+		{"sw-1", "switch", map[string]string{"pod": "pod-01"}, "spine"},
+		{"sw-2", "switch", map[string]string{"pod": "pod-02"}, "leaf"},
+		{"sw-3", "switch", map[string]string{"pod": "pod-02"}, "spine"},
+		{"sw-4", "switch", nil, ""},
+		{"host-1", "host", map[string]string{"pod": "pod-01"}, ""},
+	} {
+		object := &topo.Object{
+			ID:     c.id,
+			Type:   topo.Object_ENTITY,
+			Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: c.kind}},
+			Labels: c.labels,
+		}
+		if c.role != "" {
+			assert.NoError(t, object.SetAspectBytes("onos.topo.Switch", []byte(`{"role": "`+c.role+`"}`)))
+		}
+		assert.NoError(t, store.Create(context.TODO(), object))
+	}
+	assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+		ID:     "sw-1-host-1",
+		Type:   topo.Object_RELATION,
+		Obj:    &topo.Object_Relation{Relation: &topo.Relation{KindID: "link", SrcEntityID: "sw-1", TgtEntityID: "host-1"}},
+		Labels: map[string]string{"pod": "pod-01"},
+	}))
+
+	filters := func(expr *FilterExpr) *topo.Filters {
+		filter, err := NewExpressionFilter(expr)
+		assert.NoError(t, err)
+		return &topo.Filters{LabelFilters: []*topo.Filter{filter}}
+	}
+	listIDs := func(filters *topo.Filters) []topo.ID {
+		objects, err := store.List(context.TODO(), filters)
+		assert.NoError(t, err)
+		ids := make([]topo.ID, 0, len(objects))
+		for _, object := range objects {
+			ids = append(ids, object.ID)
+		}
+		return ids
+	}
+
+	// Switches in pod-01 or any leaf
+	leaf := &FilterExpr{Label: &LabelPredicate{Aspect: "onos.topo.Switch", Key: "role", Values: []string{"leaf"}}}
+	switchesOrLeaves := filters(&FilterExpr{Or: []*FilterExpr{
+		{And: []*FilterExpr{
+			{Kind: []string{"switch"}},
+			{Label: &LabelPredicate{Key: "pod", Values: []string{"pod-01"}}},
+		}},
+		leaf,
+	}})
+	assert.ElementsMatch(t, []topo.ID{"sw-1", "sw-2"}, listIDs(switchesOrLeaves))
+
+	// Negations, types, label operators and aspects must be composed
+	assert.ElementsMatch(t, []topo.ID{"sw-3", "sw-4"}, listIDs(filters(&FilterExpr{And: []*FilterExpr{
+		{Type: []string{"ENTITY"}},
+		{Not: &FilterExpr{Or: []*FilterExpr{
+			{Label: &LabelPredicate{Key: "pod", Values: []string{"pod-01"}}},
+			leaf,
+		}}},
+	}})))
+	assert.ElementsMatch(t, []topo.ID{"sw-4"}, listIDs(filters(&FilterExpr{And: []*FilterExpr{
+		{Kind: []string{"switch"}},
+		{Not: &FilterExpr{Label: &LabelPredicate{Key: "pod", Operator: LabelExists}}},
+	}})))
+	assert.ElementsMatch(t, []topo.ID{"sw-1", "sw-3", "sw-1-host-1"}, listIDs(filters(&FilterExpr{Or: []*FilterExpr{
+		{Type: []string{"RELATION"}},
+		{And: []*FilterExpr{
+			{Aspect: "onos.topo.Switch"},
+			{Not: leaf},
+		}},
+	}})))
+
+	// Expressions must be combined with the other filters
+	combined := filters(&FilterExpr{Or: []*FilterExpr{
+		{Label: &LabelPredicate{Key: "pod", Operator: LabelPrefix, Values: []string{"pod-0"}}},
+		leaf,
+	}})
+	combined.KindFilter = &topo.Filter{Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "switch"}}}
+	combined.LabelFilters = append(combined.LabelFilters, &topo.Filter{
+		Key:    "pod",
+		Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "pod-02"}},
+	})
+	assert.ElementsMatch(t, []topo.ID{"sw-2", "sw-3"}, listIDs(combined))
+
+	// Queries, counts and watches must evaluate expressions like listings
+	ch := make(chan *topo.Object)
+	go func() {
+		assert.NoError(t, store.Query(context.TODO(), ch, switchesOrLeaves))
+	}()
+	var queried []topo.ID
+	for object := range ch {
+		queried = append(queried, object.ID)
+	}
+	assert.ElementsMatch(t, []topo.ID{"sw-1", "sw-2"}, queried)
+	counts, err := store.Count(context.TODO(), switchesOrLeaves, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{{Count: 2}}, counts)
+	counts, err = store.Count(context.TODO(), filters(&FilterExpr{Or: []*FilterExpr{
+		{Type: []string{"RELATION"}},
+		{Kind: []string{"host"}},
+	}}), []string{GroupByType})
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupCount{
+		{Group: map[string]string{GroupByType: "ENTITY"}, Count: 1},
+		{Group: map[string]string{GroupByType: "RELATION"}, Count: 1},
+	}, counts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, events, switchesOrLeaves))
+	leafObject := &topo.Object{
+		ID:   "sw-5",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "switch"}},
+	}
+	assert.NoError(t, leafObject.SetAspectBytes("onos.topo.Switch", []byte(`{"role": "leaf"}`)))
+	assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+		ID:     "host-2",
+		Type:   topo.Object_ENTITY,
+		Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: "host"}},
+		Labels: map[string]string{"pod": "pod-01"},
+	}))
+	assert.NoError(t, store.Create(context.TODO(), leafObject))
+	assert.Equal(t, topo.ID("sw-5"), nextWatchEvent(t, events).Object.ID)
+
+	// Invalid expressions must be rejected
+	for _, expr := range []*FilterExpr{
+		{},
+		{Kind: []string{"switch"}, Type: []string{"ENTITY"}},
+		{And: []*FilterExpr{}},
+		{Not: &FilterExpr{}},
+		{Type: []string{"SWITCH"}},
+		{Label: &LabelPredicate{Key: "pod"}},
+		{Label: &LabelPredicate{Key: "pod", Operator: "like", Values: []string{"pod"}}},
+		{Label: &LabelPredicate{Key: "pod", Operator: LabelRegex, Values: []string{"("}}},
+	} {
+		_, err := NewExpressionFilter(expr)
+		assert.True(t, errors.IsInvalid(err), "%+v", expr)
+	}
+	_, err = store.List(context.TODO(), &topo.Filters{LabelFilters: []*topo.Filter{{
+		Key:    expressionKey,
+		Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: `{"or": [{"kind": ["switch"]}, {"type": ["SWITCH"]}]}`}},
+	}}})
+	assert.True(t, errors.IsInvalid(err))
+	err = store.Watch(ctx, make(chan topo.Event), &topo.Filters{LabelFilters: []*topo.Filter{{
+		Key:    expressionKey,
+		Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: `{"kind": "switch"}`}},
+	}}})
+	assert.True(t, errors.IsInvalid(err))
+}
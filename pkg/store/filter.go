@@ -4,7 +4,17 @@
 
 package store
 
-import topoapi "github.com/onosproject/onos-api/go/onos/topo"
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
 
 func match(object *topoapi.Object, filters *topoapi.Filters) bool {
 	return filters == nil ||
@@ -21,27 +31,72 @@ func matchLabels(object *topoapi.Object, filters []*topoapi.Filter) bool {
 }
 
 func matchLabel(object *topoapi.Object, filter *topoapi.Filter) bool {
-	eqo := filter.GetEqual_()
-	if eqo != nil {
-		return object.Labels[filter.Key] == eqo.Value
+	if ngo := filter.GetNot(); ngo != nil {
+		return !matchLabel(object, ngo.Inner)
 	}
-	igo := filter.GetIn()
-	if igo != nil {
-		actual := object.Labels[filter.Key]
-		for _, v := range igo.Values {
-			if v == actual {
+	var operands []string
+	if eqo := filter.GetEqual_(); eqo != nil {
+		operands = []string{eqo.Value}
+	} else if igo := filter.GetIn(); igo != nil {
+		operands = igo.Values
+	} else {
+		return false
+	}
+	if filter.GetKey() == expressionKey {
+		return matchExpressions(object, operands)
+	}
+
+	// Operators only match objects with the label, whereas missing labels are compared as empty values
+	operator, key, hasOperator := parseLabelKey(filter.GetKey())
+	value, numeric, ok := labelValue(object, key)
+	if !ok && hasOperator {
+		return false
+	}
+	for _, operand := range operands {
+		if hasOperator {
+			if matchLabelValue(operator, value, operand) {
+				return true
+			}
+		} else if value == operand {
+			return true
+		} else if numeric {
+			// Numbers read from aspects are equal whatever their encoding, e.g. 42 and 42.0
+			expected, err := strconv.ParseFloat(operand, 64)
+			if actual, _ := strconv.ParseFloat(value, 64); err == nil && actual == expected {
 				return true
 			}
 		}
-		return false
-	}
-	ngo := filter.GetNot()
-	if ngo != nil {
-		return !matchLabel(object, ngo.Inner)
 	}
 	return false
 }
 
+// labelValue returns the value of the label with the given key, or of the aspect field it addresses, and whether
+// the value is a JSON number
+func labelValue(object *topoapi.Object, key string) (string, bool, bool) {
+	aspectType, path, ok := parseAspectKey(key)
+	if !ok {
+		value, ok := object.Labels[key]
+		return value, false, ok
+	}
+	field, ok, err := aspectField(object, aspectType, path)
+	if err != nil || !ok {
+		return "", false, false
+	}
+	switch v := field.(type) {
+	case string:
+		return v, false, true
+	case json.Number:
+		return v.String(), true, true
+	case bool:
+		return strconv.FormatBool(v), false, true
+	}
+	encoded, err := json.Marshal(field)
+	if err != nil {
+		return "", false, false
+	}
+	return string(encoded), false, true
+}
+
 func matchKind(object *topoapi.Object, filter *topoapi.Filter) bool {
 	if filter == nil {
 		return true
@@ -101,3 +156,247 @@ func matchAspects(object *topoapi.Object, aspects []string) bool {
 	}
 	return true
 }
+
+// filterKeyPrefix starts the keys of the label filters that apply a label operator, address an aspect field or hold
+// a filter expression. Label keys cannot start with it, so label filters with any other key match the label with
+// that key verbatim, whatever characters it contains.
+const filterKeyPrefix = "\x00"
+
+// LabelOperator is an operator applied to the value of a label by a label filter. Since the Filter message only
+// supports equality and set membership, operators are encoded in the key of the label filters built by
+// NewLabelFilter; the value of an Equal_ filter, or any of the values of an In filter, is the operand of the operator.
+type LabelOperator string
+
+const (
+	// LabelExists matches objects that have the label, whatever its value; its operand is ignored.
+	// Wrapping it in a Not filter matches objects that do not have the label.
+	LabelExists LabelOperator = "exists"
+	// LabelPrefix matches objects whose label value starts with the operand
+	LabelPrefix LabelOperator = "prefix"
+	// LabelRegex matches objects whose label value matches the regular expression operand
+	LabelRegex LabelOperator = "regex"
+	// LabelGreaterThan matches objects whose numeric label value is greater than the operand
+	LabelGreaterThan LabelOperator = "gt"
+	// LabelGreaterOrEqual matches objects whose numeric label value is greater than or equal to the operand
+	LabelGreaterOrEqual LabelOperator = "gte"
+	// LabelLessThan matches objects whose numeric label value is less than the operand
+	LabelLessThan LabelOperator = "lt"
+	// LabelLessOrEqual matches objects whose numeric label value is less than or equal to the operand
+	LabelLessOrEqual LabelOperator = "lte"
+)
+
+// NewLabelFilter returns a label filter applying the given operator to the value of the label with the given key
+func NewLabelFilter(operator LabelOperator, key string, operand string) *topoapi.Filter {
+	return &topoapi.Filter{
+		Key:    filterKeyPrefix + string(operator) + ":" + key,
+		Filter: &topoapi.Filter_Equal_{Equal_: &topoapi.EqualFilter{Value: operand}},
+	}
+}
+
+// parseLabelKey returns the operator and label key encoded in the key of a label filter built by NewLabelFilter.
+// Other keys are plain label keys.
+func parseLabelKey(key string) (LabelOperator, string, bool) {
+	if !strings.HasPrefix(key, filterKeyPrefix) {
+		return "", key, false
+	}
+	name, labelKey, ok := strings.Cut(strings.TrimPrefix(key, filterKeyPrefix), ":")
+	if !ok {
+		return "", key, false
+	}
+	switch operator := LabelOperator(name); operator {
+	case LabelExists, LabelPrefix, LabelRegex, LabelGreaterThan, LabelGreaterOrEqual, LabelLessThan, LabelLessOrEqual:
+		return operator, labelKey, true
+	}
+	return "", key, false
+}
+
+// checkLabels checks that the label keys of an object being written do not start with the prefix reserved for
+// the keys of label operators, aspect fields and filter expressions
+func checkLabels(object *topoapi.Object) error {
+	for key := range object.Labels {
+		if strings.HasPrefix(key, filterKeyPrefix) {
+			return errors.NewInvalid("Label key %q is reserved for filters", key)
+		}
+	}
+	return nil
+}
+
+// validateFilters returns an error if the operands of the label filters cannot be applied by their operators
+func validateFilters(filters *topoapi.Filters) error {
+	if filters == nil {
+		return nil
+	}
+	for _, filter := range filters.LabelFilters {
+		if err := validateLabelFilter(filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateLabelFilter(filter *topoapi.Filter) error {
+	if ngo := filter.GetNot(); ngo != nil {
+		return validateLabelFilter(ngo.Inner)
+	}
+	if filter.GetKey() == expressionKey {
+		operands, _ := filterOperands(filter)
+		for _, operand := range operands {
+			if _, err := parseExpression(operand); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	operator, key, ok := parseLabelKey(filter.GetKey())
+	if aspectType, path, isAspect := parseAspectKey(key); isAspect && (aspectType == "" || path == "") {
+		return errors.NewInvalid("invalid aspect field '%s': expected '%s<aspect type>/<field path>'",
+			strings.TrimPrefix(key, filterKeyPrefix), aspectKeyPrefix)
+	}
+	if !ok {
+		return nil
+	}
+	operands, _ := filterOperands(filter)
+	for _, operand := range operands {
+		switch operator {
+		case LabelRegex:
+			if len(operand) > maxRegexLength {
+				return errors.NewInvalid("regular expression for label '%s' is longer than %d bytes", key, maxRegexLength)
+			}
+			if _, err := compileRegex(operand); err != nil {
+				return errors.NewInvalid("invalid regular expression '%s' for label '%s': %v", operand, key, err)
+			}
+		case LabelGreaterThan, LabelGreaterOrEqual, LabelLessThan, LabelLessOrEqual:
+			if _, err := strconv.ParseFloat(operand, 64); err != nil {
+				return errors.NewInvalid("invalid numeric operand '%s' for label '%s'", operand, key)
+			}
+		}
+	}
+	return nil
+}
+
+// matchExpressions returns whether the object matches any of the given JSON encoded filter expressions
+func matchExpressions(object *topoapi.Object, values []string) bool {
+	for _, value := range values {
+		if e, err := parseExpression(value); err == nil && e.match(object) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLabelValue returns whether the given label value satisfies the operator with the given operand
+func matchLabelValue(operator LabelOperator, value string, operand string) bool {
+	switch operator {
+	case LabelExists:
+		return true
+	case LabelPrefix:
+		return strings.HasPrefix(value, operand)
+	case LabelRegex:
+		re, err := compileRegex(operand)
+		return err == nil && re.MatchString(value)
+	}
+	actual, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	expected, err := strconv.ParseFloat(operand, 64)
+	if err != nil {
+		return false
+	}
+	switch operator {
+	case LabelGreaterThan:
+		return actual > expected
+	case LabelGreaterOrEqual:
+		return actual >= expected
+	case LabelLessThan:
+		return actual < expected
+	case LabelLessOrEqual:
+		return actual <= expected
+	}
+	return false
+}
+
+// maxCachedRegexes bounds the number of compiled regular expressions kept for label filters
+const maxCachedRegexes = 256
+
+// maxRegexLength bounds the length of the regular expressions of label filters
+const maxRegexLength = 1024
+
+// regexes are the compiled regular expressions of label filters
+var regexes = newLRU[*regexp.Regexp](maxCachedRegexes)
+
+// compileRegex returns the compiled regular expression, compiling each expression once while it is cached
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexes.get(expr); ok {
+		return re, nil
+	}
+	if len(expr) > maxRegexLength {
+		return nil, errors.NewInvalid("regular expression longer than %d bytes", maxRegexLength)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexes.add(expr, re)
+	return re, nil
+}
+
+// aspectKeyPrefix is the prefix of the names of aspect fields, "aspect:<aspect type>/<field path>"
+const aspectKeyPrefix = "aspect:"
+
+// AspectKey returns a label filter key addressing the field at the given dotted path in the JSON value of the aspect
+// of the given type, e.g. AspectKey("onos.topo.E2Cell", "pci"). Label filters with the key, including those applying
+// label operators to it, match the value of the field instead of a label; numeric path elements index arrays.
+func AspectKey(aspectType string, path string) string {
+	return filterKeyPrefix + aspectKeyPrefix + aspectType + "/" + path
+}
+
+// parseAspectKey returns the aspect type and field path addressed by a label filter key built by AspectKey, if any
+func parseAspectKey(key string) (string, string, bool) {
+	if !strings.HasPrefix(key, filterKeyPrefix) {
+		return "", "", false
+	}
+	return parseAspectField(strings.TrimPrefix(key, filterKeyPrefix))
+}
+
+// parseAspectField returns the aspect type and field path of an aspect field named
+// "aspect:<aspect type>/<field path>", if the name has the prefix of aspect fields
+func parseAspectField(name string) (string, string, bool) {
+	if !strings.HasPrefix(name, aspectKeyPrefix) {
+		return "", "", false
+	}
+	aspectType, path, _ := strings.Cut(strings.TrimPrefix(name, aspectKeyPrefix), "/")
+	return aspectType, path, true
+}
+
+// aspectField returns the field at the given dotted path in the JSON value of the aspect of the given type, with
+// numbers decoded as json.Number. Missing and null fields are not found.
+func aspectField(object *topoapi.Object, aspectType string, path string) (interface{}, bool, error) {
+	aspect, ok := object.Aspects[aspectType]
+	if !ok || aspect == nil {
+		return nil, false, nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(aspect.Value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false, errors.NewInvalid("Aspect '%s' of Object '%s' is not valid JSON: %v", aspectType, object.ID, err)
+	}
+	for _, name := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if value, ok = v[name]; !ok {
+				return nil, false, nil
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false, nil
+			}
+			value = v[i]
+		default:
+			return nil, false, nil
+		}
+	}
+	return value, value != nil, nil
+}
@@ -0,0 +1,371 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// locationAspect is the type of the aspect holding the geographic location of an object
+const locationAspect = "onos.topo.Location"
+
+// earthRadius is the mean radius of the Earth in meters
+const earthRadius = 6371008.8
+
+// geoGridDegrees is the size in degrees of the cells of the grid indexing object locations
+const geoGridDegrees = 0.1
+
+// GeoPoint is a geographic location in degrees
+type GeoPoint struct {
+	// Lat is the latitude in degrees, between -90 and 90
+	Lat float64 `json:"lat"`
+	// Lng is the longitude in degrees, between -180 and 180
+	Lng float64 `json:"lng"`
+}
+
+func (p GeoPoint) valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// distance returns the great-circle distance in meters between the points
+func (p GeoPoint) distance(other GeoPoint) float64 {
+	lat1, lat2 := p.Lat*math.Pi/180, other.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (other.Lng - p.Lng) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// GeoBoundingBox is the area between two corners; the box crosses the antimeridian if the longitude of its
+// minimum corner is greater than the longitude of its maximum corner
+type GeoBoundingBox struct {
+	// Min is the south-west corner of the box
+	Min GeoPoint `json:"min"`
+	// Max is the north-east corner of the box
+	Max GeoPoint `json:"max"`
+}
+
+func (b GeoBoundingBox) contains(p GeoPoint) bool {
+	if p.Lat < b.Min.Lat || p.Lat > b.Max.Lat {
+		return false
+	}
+	if b.Min.Lng <= b.Max.Lng {
+		return p.Lng >= b.Min.Lng && p.Lng <= b.Max.Lng
+	}
+	return p.Lng >= b.Min.Lng || p.Lng <= b.Max.Lng
+}
+
+// GeoRadius is the area within a distance of a point
+type GeoRadius struct {
+	// Center is the point at the center of the area
+	Center GeoPoint `json:"center"`
+	// Meters is the distance from the center to the edge of the area
+	Meters float64 `json:"meters"`
+}
+
+// bounds returns the smallest bounding box containing the area
+func (r GeoRadius) bounds() GeoBoundingBox {
+	dLat := r.Meters / earthRadius * 180 / math.Pi
+	box := GeoBoundingBox{
+		Min: GeoPoint{Lat: r.Center.Lat - dLat, Lng: -180},
+		Max: GeoPoint{Lat: r.Center.Lat + dLat, Lng: 180},
+	}
+	if box.Min.Lat <= -90 || box.Max.Lat >= 90 {
+		// The area contains a pole, so it spans all longitudes
+		box.Min.Lat, box.Max.Lat = math.Max(box.Min.Lat, -90), math.Min(box.Max.Lat, 90)
+		return box
+	}
+	ratio := math.Sin(r.Meters/earthRadius) / math.Cos(r.Center.Lat*math.Pi/180)
+	if ratio >= 1 {
+		return box
+	}
+	dLng := math.Asin(ratio) * 180 / math.Pi
+	box.Min.Lng, box.Max.Lng = r.Center.Lng-dLng, r.Center.Lng+dLng
+	if box.Min.Lng < -180 {
+		box.Min.Lng += 360
+	}
+	if box.Max.Lng > 180 {
+		box.Max.Lng -= 360
+	}
+	return box
+}
+
+// GeoNearest is the K objects nearest to a point
+type GeoNearest struct {
+	// Point is the point to which objects are nearest
+	Point GeoPoint `json:"point"`
+	// K is the maximum number of objects
+	K int `json:"k"`
+}
+
+// GeoQuery is a query of the objects whose onos.topo.Location aspect is within a bounding box, within a radius
+// or nearest to a point; exactly one of them must be set. Objects within a radius or nearest to a point are
+// returned in order of increasing distance, and objects within a bounding box in order of their IDs.
+type GeoQuery struct {
+	// BoundingBox requests the objects within a bounding box
+	BoundingBox *GeoBoundingBox `json:"boundingBox,omitempty"`
+	// Radius requests the objects within a distance of a point
+	Radius *GeoRadius `json:"radius,omitempty"`
+	// Nearest requests the objects nearest to a point
+	Nearest *GeoNearest `json:"nearest,omitempty"`
+	// Filters are the filters the objects must also match
+	Filters *topoapi.Filters `json:"-"`
+}
+
+func (q GeoQuery) validate() error {
+	var points []GeoPoint
+	areas := 0
+	if q.BoundingBox != nil {
+		areas++
+		points = append(points, q.BoundingBox.Min, q.BoundingBox.Max)
+		if q.BoundingBox.Min.Lat > q.BoundingBox.Max.Lat {
+			return errors.NewInvalid("bounding box minimum latitude %g exceeds its maximum latitude %g", q.BoundingBox.Min.Lat, q.BoundingBox.Max.Lat)
+		}
+	}
+	if q.Radius != nil {
+		areas++
+		points = append(points, q.Radius.Center)
+		if !(q.Radius.Meters > 0) || math.IsInf(q.Radius.Meters, 0) {
+			return errors.NewInvalid("radius must be a positive number of meters")
+		}
+	}
+	if q.Nearest != nil {
+		areas++
+		points = append(points, q.Nearest.Point)
+		if q.Nearest.K <= 0 {
+			return errors.NewInvalid("number of nearest objects must be positive")
+		}
+	}
+	if areas != 1 {
+		return errors.NewInvalid("geo query must have exactly one of a bounding box, a radius or a nearest point")
+	}
+	for _, point := range points {
+		if !point.valid() {
+			return errors.NewInvalid("invalid location (%g, %g)", point.Lat, point.Lng)
+		}
+	}
+	if q.Filters != nil && q.Filters.RelationFilter != nil {
+		return errors.NewInvalid("geo query cannot be combined with a relation filter")
+	}
+	return validateFilters(q.Filters)
+}
+
+// origin returns the point from which the query measures distances, if any
+func (q GeoQuery) origin() (GeoPoint, bool) {
+	if q.Radius != nil {
+		return q.Radius.Center, true
+	}
+	if q.Nearest != nil {
+		return q.Nearest.Point, true
+	}
+	return GeoPoint{}, false
+}
+
+// bounds returns the bounding box containing the objects matching the query, if the query is bounded
+func (q GeoQuery) bounds() (GeoBoundingBox, bool) {
+	if q.BoundingBox != nil {
+		return *q.BoundingBox, true
+	}
+	if q.Radius != nil {
+		return q.Radius.bounds(), true
+	}
+	return GeoBoundingBox{}, false
+}
+
+// contains returns whether the given location matches the query
+func (q GeoQuery) contains(p GeoPoint) bool {
+	if q.BoundingBox != nil {
+		return q.BoundingBox.contains(p)
+	}
+	if q.Radius != nil {
+		return q.Radius.Center.distance(p) <= q.Radius.Meters
+	}
+	return true
+}
+
+// Located is an object found by a geo query
+type Located struct {
+	// Object is the object
+	Object *topoapi.Object
+	// Distance is the distance in meters from the center of a radius query or from the point of a nearest query
+	Distance float64
+}
+
+// objectLocation returns the location held by the onos.topo.Location aspect of the given object, if it is valid
+func objectLocation(object *topoapi.Object) (GeoPoint, bool) {
+	if _, ok := object.Aspects[locationAspect]; !ok {
+		return GeoPoint{}, false
+	}
+	location := &topoapi.Location{}
+	if err := object.GetAspect(location); err != nil {
+		return GeoPoint{}, false
+	}
+	point := GeoPoint{Lat: location.Lat, Lng: location.Lng}
+	if wgs84 := location.GetWgs84(); wgs84 != nil {
+		point = GeoPoint{Lat: wgs84.LatitudeDeg, Lng: wgs84.LongitudeDeg}
+	}
+	return point, point.valid()
+}
+
+// geoCandidate is the indexed location of an object that may match a geo query
+type geoCandidate struct {
+	id       topoapi.ID
+	point    GeoPoint
+	distance float64
+}
+
+// sortCandidates orders the candidates of the given query by distance, or by ID if the query has no origin
+func sortCandidates(query GeoQuery, candidates []geoCandidate) {
+	origin, ok := query.origin()
+	if ok {
+		for i := range candidates {
+			candidates[i].distance = origin.distance(candidates[i].point)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].id < candidates[j].id
+	})
+}
+
+// locate reads the given candidates in order and returns the objects that still match the query
+func locate(ctx context.Context, get getFunc, query GeoQuery, candidates []geoCandidate, opts ...ReadOption) ([]Located, error) {
+	sortCandidates(query, candidates)
+	origin, _ := query.origin()
+	var results []Located
+	for _, candidate := range candidates {
+		if query.Nearest != nil && len(results) == query.Nearest.K {
+			break
+		}
+		object, err := get(ctx, candidate.id, opts...)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		// The object may have moved since the candidate was indexed
+		point, ok := objectLocation(object)
+		if !ok || !query.contains(point) || !matchLocated(object, query.Filters) {
+			continue
+		}
+		located := Located{Object: object}
+		if query.Nearest != nil || query.Radius != nil {
+			located.Distance = origin.distance(point)
+		}
+		results = append(results, located)
+	}
+
+	// Objects that moved since they were indexed may be out of order
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Distance < results[j].Distance
+	})
+	return results, nil
+}
+
+func matchLocated(object *topoapi.Object, filters *topoapi.Filters) bool {
+	return filters == nil || (match(object, filters) && matchType(object, filters.ObjectTypes))
+}
+
+// geoCell is a cell of the grid indexing object locations
+type geoCell struct {
+	lat int
+	lng int
+}
+
+func newGeoCell(p GeoPoint) geoCell {
+	return geoCell{
+		lat: int(math.Floor(p.Lat / geoGridDegrees)),
+		lng: int(math.Floor(p.Lng / geoGridDegrees)),
+	}
+}
+
+// geoIndex is a grid of the locations of objects
+type geoIndex struct {
+	points map[topoapi.ID]GeoPoint
+	cells  map[geoCell]idSet
+}
+
+func newGeoIndex() *geoIndex {
+	return &geoIndex{
+		points: make(map[topoapi.ID]GeoPoint),
+		cells:  make(map[geoCell]idSet),
+	}
+}
+
+func (i *geoIndex) add(id topoapi.ID, p GeoPoint) {
+	i.points[id] = p
+	addID(i.cells, newGeoCell(p), id)
+}
+
+func (i *geoIndex) remove(id topoapi.ID) {
+	if p, ok := i.points[id]; ok {
+		delete(i.points, id)
+		removeID(i.cells, newGeoCell(p), id)
+	}
+}
+
+// candidates returns the indexed locations within the bounds of the given query, restricted to the given IDs
+// if narrowed
+func (i *geoIndex) candidates(query GeoQuery, ids idSet, narrowed bool) []geoCandidate {
+	var candidates []geoCandidate
+	add := func(id topoapi.ID, p GeoPoint) {
+		if narrowed {
+			if _, ok := ids[id]; !ok {
+				return
+			}
+		}
+		if query.contains(p) {
+			candidates = append(candidates, geoCandidate{id: id, point: p})
+		}
+	}
+
+	// Scan the cells covering the bounds of the query, unless there are more cells than locations
+	box, bounded := query.bounds()
+	if bounded {
+		minCell, maxCell := newGeoCell(box.Min), newGeoCell(box.Max)
+		lngRanges := [][2]int{{minCell.lng, maxCell.lng}}
+		if box.Min.Lng > box.Max.Lng {
+			lngRanges = [][2]int{{minCell.lng, newGeoCell(GeoPoint{Lng: 180}).lng}, {newGeoCell(GeoPoint{Lng: -180}).lng, maxCell.lng}}
+		}
+		cells := 0
+		for _, lngRange := range lngRanges {
+			cells += (maxCell.lat - minCell.lat + 1) * (lngRange[1] - lngRange[0] + 1)
+		}
+		if cells <= len(i.points) {
+			for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+				for _, lngRange := range lngRanges {
+					for lng := lngRange[0]; lng <= lngRange[1]; lng++ {
+						for id := range i.cells[geoCell{lat: lat, lng: lng}] {
+							add(id, i.points[id])
+						}
+					}
+				}
+			}
+			return candidates
+		}
+	}
+	if narrowed && len(ids) < len(i.points) {
+		for id := range ids {
+			if p, ok := i.points[id]; ok {
+				add(id, p)
+			}
+		}
+		return candidates
+	}
+	for id, p := range i.points {
+		add(id, p)
+	}
+	return candidates
+}
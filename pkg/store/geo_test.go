@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocate(t *testing.T) {
+	forEachBackend(t, testLocate)
+}
+
+// createLocatedCells creates e2 nodes and cells located around Paris, with a cell on each side of the antimeridian
+func createLocatedCells(t *testing.T, store Store) {
+	for _, c := range []struct {
+		id       topo.ID
+		kind     topo.ID
+		location string
+		labels   map[string]string
+	}{
+		{"node-1", "e2node", `{"lat": 48.8566, "lng": 2.3522}`, nil},
+		{"cell-1", "e2cell", `{"lat": 48.8584, "lng": 2.2945}`, map[string]string{"band": "n78"}},
+		{"cell-2", "e2cell", `{"lat": 48.8606, "lng": 2.3376}`, map[string]string{"band": "n41"}},
+		{"cell-3", "e2cell", `{"wgs84": {"latitude_deg": 48.8530, "longitude_deg": 2.3499}}`, map[string]string{"band": "n78"}},
+		{"cell-4", "e2cell", `{"lat": 45.7640, "lng": 4.8357}`, map[string]string{"band": "n78"}},
+		{"cell-5", "e2cell", `{"lat": -17.7134, "lng": 178.0650}`, nil},
+		{"cell-6", "e2cell", `{"lat": -14.2756, "lng": -178.1200}`, nil},
+		{"cell-7", "e2cell", `{"lat": 123.0, "lng": 321.0}`, nil},
+	} {
+		object := &topo.Object{
+			ID:     c.id,
+			Type:   topo.Object_ENTITY,
+			Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: c.kind}},
+			Labels: c.labels,
+		}
+		assert.NoError(t, object.SetAspectBytes(locationAspect, []byte(c.location)))
+		assert.NoError(t, store.Create(context.TODO(), object))
+	}
+	assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+		ID:   "cell-8",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "e2cell"}},
+	}))
+}
+
+// locatedIDs returns the IDs of the objects located by the given query, in order
+func locatedIDs(t *testing.T, store Store, query GeoQuery) []topo.ID {
+	located, err := store.Locate(context.TODO(), query)
+	assert.NoError(t, err)
+	ids := make([]topo.ID, 0, len(located))
+	for _, l := range located {
+		ids = append(ids, l.Object.ID)
+	}
+	return ids
+}
+
+func testLocate(t *testing.T, newStore func() Store) {
+	store := newStore()
+	createLocatedCells(t, store)
+	paris := GeoPoint{Lat: 48.8566, Lng: 2.3522}
+
+	// Objects within a radius must be ordered by distance
+	ids := locatedIDs(t, store, GeoQuery{Radius: &GeoRadius{Center: paris, Meters: 2000}})
+	assert.Equal(t, []topo.ID{"node-1", "cell-3", "cell-2"}, ids)
+	located, err := store.Locate(context.TODO(), GeoQuery{Radius: &GeoRadius{Center: paris, Meters: 2000}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, located[0].Distance)
+	assert.InDelta(t, 434, located[1].Distance, 1)
+
+	// Objects within a bounding box must be ordered by ID, including boxes crossing the antimeridian
+	ids = locatedIDs(t, store, GeoQuery{BoundingBox: &GeoBoundingBox{
+		Min: GeoPoint{Lat: 45, Lng: 2.3},
+		Max: GeoPoint{Lat: 49, Lng: 5},
+	}})
+	assert.Equal(t, []topo.ID{"cell-2", "cell-3", "cell-4", "node-1"}, ids)
+	ids = locatedIDs(t, store, GeoQuery{BoundingBox: &GeoBoundingBox{
+		Min: GeoPoint{Lat: -20, Lng: 170},
+		Max: GeoPoint{Lat: -10, Lng: -170},
+	}})
+	assert.Equal(t, []topo.ID{"cell-5", "cell-6"}, ids)
+	ids = locatedIDs(t, store, GeoQuery{Radius: &GeoRadius{Center: GeoPoint{Lat: -16, Lng: 180}, Meters: 500000}})
+	assert.ElementsMatch(t, []topo.ID{"cell-5", "cell-6"}, ids)
+
+	// Nearest objects must be limited to K and combined with kind and label filters
+	ids = locatedIDs(t, store, GeoQuery{Nearest: &GeoNearest{Point: paris, K: 2}})
+	assert.Equal(t, []topo.ID{"node-1", "cell-3"}, ids)
+	ids = locatedIDs(t, store, GeoQuery{
+		Nearest: &GeoNearest{Point: paris, K: 3},
+		Filters: &topo.Filters{
+			KindFilter:   &topo.Filter{Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "e2cell"}}},
+			LabelFilters: []*topo.Filter{{Key: "band", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "n78"}}}},
+		},
+	})
+	assert.Equal(t, []topo.ID{"cell-3", "cell-1", "cell-4"}, ids)
+
+	// Moved objects must be found at their new location
+	object, err := store.Get(context.TODO(), "cell-4")
+	assert.NoError(t, err)
+	assert.NoError(t, object.SetAspectBytes(locationAspect, []byte(`{"lat": 48.8570, "lng": 2.3525}`)))
+	assert.NoError(t, store.Update(context.TODO(), object))
+	ids = locatedIDs(t, store, GeoQuery{Radius: &GeoRadius{Center: paris, Meters: 100}})
+	assert.Equal(t, []topo.ID{"node-1", "cell-4"}, ids)
+	assert.NoError(t, store.Delete(context.TODO(), "node-1", 0))
+	ids = locatedIDs(t, store, GeoQuery{Radius: &GeoRadius{Center: paris, Meters: 100}})
+	assert.Equal(t, []topo.ID{"cell-4"}, ids)
+
+	// Invalid queries must be rejected
+	for _, query := range []GeoQuery{
+		{},
+		{Radius: &GeoRadius{Center: paris, Meters: 1}, Nearest: &GeoNearest{Point: paris, K: 1}},
+		{Radius: &GeoRadius{Center: paris}},
+		{Nearest: &GeoNearest{Point: GeoPoint{Lat: 91}, K: 1}},
+		{BoundingBox: &GeoBoundingBox{Min: GeoPoint{Lat: 10}, Max: GeoPoint{Lat: 5}}},
+		{Nearest: &GeoNearest{Point: paris, K: 1}, Filters: &topo.Filters{RelationFilter: &topo.RelationFilter{SrcId: "node-1"}}},
+	} {
+		_, err := store.Locate(context.TODO(), query)
+		assert.True(t, errors.IsInvalid(err), fmt.Sprintf("%+v", query))
+	}
+}
+
+func TestGeoIndex(t *testing.T) {
+	index := newGeoIndex()
+	for i := 0; i < 100; i++ {
+		index.add(topo.ID(fmt.Sprintf("p%d", i)), GeoPoint{Lat: float64(i) / 10, Lng: float64(i) / 10})
+	}
+	index.remove("p50")
+
+	// Small areas must be answered from the cells they cover, and large areas by scanning the locations
+	query := GeoQuery{BoundingBox: &GeoBoundingBox{Min: GeoPoint{Lat: 4.95, Lng: 4.95}, Max: GeoPoint{Lat: 5.25, Lng: 5.25}}}
+	candidates := index.candidates(query, nil, false)
+	sortCandidates(query, candidates)
+	assert.Len(t, candidates, 2)
+	assert.Equal(t, topo.ID("p51"), candidates[0].id)
+	query = GeoQuery{BoundingBox: &GeoBoundingBox{Min: GeoPoint{Lat: -90, Lng: -180}, Max: GeoPoint{Lat: 90, Lng: 180}}}
+	assert.Len(t, index.candidates(query, nil, false), 99)
+	assert.Len(t, index.candidates(query, idSet{"p1": {}, "p50": {}}, true), 1)
+
+	// Radius bounds must cover the poles and the antimeridian
+	box := GeoRadius{Center: GeoPoint{Lat: 89.9, Lng: 10}, Meters: 50000}.bounds()
+	assert.Equal(t, -180.0, box.Min.Lng)
+	assert.Equal(t, 90.0, box.Max.Lat)
+	box = GeoRadius{Center: GeoPoint{Lat: 0, Lng: 179.9}, Meters: 50000}.bounds()
+	assert.Greater(t, box.Min.Lng, box.Max.Lng)
+	assert.True(t, box.contains(GeoPoint{Lat: 0, Lng: -179.9}))
+}
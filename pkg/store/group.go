@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// transactionTimeout is the time after which the events of a committed transaction that have been received are sent
+// individually, if some of its events are still missing
+const transactionTimeout = 10 * time.Second
+
+// pendingTransaction is a transaction being committed through an Atomix store, until its events have been sent
+type pendingTransaction struct {
+	id string
+	// ids are the IDs of the objects changed by the transaction, in the order of its operations, and eventTypes is
+	// the type of the event of the change to each object
+	ids        []topoapi.ID
+	eventTypes map[topoapi.ID]topoapi.EventType
+	// revisions is the revision of each object created or updated by the transaction, once committed
+	revisions map[topoapi.ID]topoapi.Revision
+	committed bool
+}
+
+// matches returns whether a change is made by the committed transaction; the revision of the removal of an object
+// is that of its last change, so removals are matched by object
+func (t *pendingTransaction) matches(c *change) bool {
+	id := c.event.Object.ID
+	eventType, ok := t.eventTypes[id]
+	if !t.committed || !ok || eventType != c.event.Type {
+		return false
+	}
+	return eventType == topoapi.EventType_REMOVED || t.revisions[id] == c.event.Object.Revision
+}
+
+// transactionEvents sends the events of an Atomix store to its watchers. Atomix delivers the events of a transaction
+// separately, and only orders them per partition, so the events of the objects changed by the transactions being
+// committed through the store are held until all the events of each transaction have been received, and then sent
+// as a group. The events of other objects are sent as soon as they are received.
+type transactionEvents struct {
+	watchers *watchers
+	pending  []*pendingTransaction
+	// held are the events held for the pending transactions in the order they were received; sent events are nil
+	held []*change
+	mu   sync.Mutex
+}
+
+func newTransactionEvents(watchers *watchers) *transactionEvents {
+	return &transactionEvents{
+		watchers: watchers,
+	}
+}
+
+// begin registers a transaction before it is committed, so that the events of the objects it changes are held
+func (e *transactionEvents) begin(tx *transaction) *pendingTransaction {
+	pending := &pendingTransaction{
+		id:         uuid.New().String(),
+		ids:        make([]topoapi.ID, 0, len(tx.operations)),
+		eventTypes: make(map[topoapi.ID]topoapi.EventType, len(tx.operations)),
+		revisions:  make(map[topoapi.ID]topoapi.Revision, len(tx.operations)),
+	}
+	for _, op := range tx.operations {
+		eventType := topoapi.EventType_REMOVED
+		switch op.Type {
+		case OperationCreate:
+			eventType = topoapi.EventType_ADDED
+		case OperationUpdate:
+			eventType = topoapi.EventType_UPDATED
+		}
+		pending.ids = append(pending.ids, op.Object.ID)
+		pending.eventTypes[op.Object.ID] = eventType
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, pending)
+	return pending
+}
+
+// commit records the revisions of the objects created and updated by a committed transaction, and sends its events
+// once they have all been received
+func (e *transactionEvents) commit(pending *pendingTransaction, operations []Operation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, op := range operations {
+		if op.Type != OperationDelete {
+			pending.revisions[op.Object.ID] = op.Object.Revision
+		}
+	}
+	pending.committed = true
+	time.AfterFunc(transactionTimeout, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.remove(pending) {
+			log.Warnf("Timed out waiting for the events of transaction %s; sending them individually", pending.id)
+			e.release()
+		}
+	})
+	e.release()
+}
+
+// abort discards a transaction that failed to commit, and sends the events held for it individually
+func (e *transactionEvents) abort(pending *pendingTransaction) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remove(pending)
+	e.release()
+}
+
+// send sends an event to the watchers along with the previous state of its object, unless it is held for a pending
+// transaction
+func (e *transactionEvents) send(event topoapi.Event, previous *topoapi.Object) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.pending) == 0 && len(e.held) == 0 {
+		e.watchers.send(event, previous)
+		return
+	}
+	e.held = append(e.held, &change{event: event, previous: previous})
+	e.release()
+}
+
+// remove removes a pending transaction, returning false if it is no longer pending; it must be called with the
+// lock held
+func (e *transactionEvents) remove(pending *pendingTransaction) bool {
+	for i, t := range e.pending {
+		if t == pending {
+			e.pending = append(e.pending[:i], e.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// release sends the held events that no longer need to be held, in order for each object: the events of a committed
+// transaction once they have all been received, and the events of objects not changed by a pending transaction. An
+// event is held as long as an earlier event for the same object is held. It must be called with the lock held.
+func (e *transactionEvents) release() {
+	for progress := true; progress; {
+		progress = false
+		blocked := make(map[topoapi.ID]bool)
+		for i, c := range e.held {
+			if c == nil || blocked[c.event.Object.ID] {
+				continue
+			}
+			pending, matched := e.transaction(c)
+			switch {
+			case pending == nil:
+				e.watchers.send(c.event, c.previous)
+				e.held[i] = nil
+				progress = true
+			case matched && e.sendGroup(pending, i):
+				progress = true
+			default:
+				blocked[c.event.Object.ID] = true
+			}
+		}
+	}
+
+	held := e.held[:0]
+	for _, c := range e.held {
+		if c != nil {
+			held = append(held, c)
+		}
+	}
+	for i := len(held); i < len(e.held); i++ {
+		e.held[i] = nil
+	}
+	e.held = held
+}
+
+// transaction returns the pending transaction that made the given change, or a transaction being committed that
+// changes its object, since the change may be made by the transaction; it must be called with the lock held
+func (e *transactionEvents) transaction(c *change) (*pendingTransaction, bool) {
+	for _, pending := range e.pending {
+		if pending.matches(c) {
+			return pending, true
+		}
+	}
+	for _, pending := range e.pending {
+		if _, ok := pending.eventTypes[c.event.Object.ID]; ok && !pending.committed {
+			return pending, false
+		}
+	}
+	return nil, false
+}
+
+// sendGroup sends the events of a committed transaction as a group if they have all been received, and no earlier
+// event for the objects of the transaction is still held; first is the position of the first of its held events.
+// It must be called with the lock held.
+func (e *transactionEvents) sendGroup(pending *pendingTransaction, first int) bool {
+	positions := make(map[topoapi.ID]int, len(pending.ids))
+	for i := first; i < len(e.held); i++ {
+		if c := e.held[i]; c != nil && pending.matches(c) {
+			positions[c.event.Object.ID] = i
+		}
+	}
+	if len(positions) < len(pending.ids) {
+		return false
+	}
+	for i, c := range e.held {
+		if c != nil {
+			if position, ok := positions[c.event.Object.ID]; ok && i < position {
+				return false
+			}
+		}
+	}
+
+	changes := make([]change, 0, len(pending.ids))
+	for _, id := range pending.ids {
+		changes = append(changes, *e.held[positions[id]])
+		e.held[positions[id]] = nil
+	}
+	e.watchers.sendGroup(pending.id, changes)
+	e.remove(pending)
+	return true
+}
@@ -0,0 +1,310 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"sync"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// idSet is a set of object IDs
+type idSet map[topoapi.ID]struct{}
+
+// objectIndex maintains secondary indexes of object IDs by object type, kind ID and label key/value
+type objectIndex struct {
+	// indexed keys of each object, used to remove stale entries when an object changes
+	entries map[topoapi.ID]indexEntry
+	types   map[topoapi.Object_Type]idSet
+	kinds   map[topoapi.ID]idSet
+	labels  map[string]map[string]idSet
+	geo     *geoIndex
+	mu      sync.RWMutex
+}
+
+type indexEntry struct {
+	objectType topoapi.Object_Type
+	kindID     topoapi.ID
+	labels     map[string]string
+	location   *GeoPoint
+}
+
+func newObjectIndex() *objectIndex {
+	return &objectIndex{
+		entries: make(map[topoapi.ID]indexEntry),
+		types:   make(map[topoapi.Object_Type]idSet),
+		kinds:   make(map[topoapi.ID]idSet),
+		labels:  make(map[string]map[string]idSet),
+		geo:     newGeoIndex(),
+	}
+}
+
+// update indexes the given object, replacing any previously indexed state for the same ID
+func (i *objectIndex) update(object *topoapi.Object) {
+	entry := indexEntry{
+		objectType: object.Type,
+		kindID:     kindID(object),
+		labels:     make(map[string]string, len(object.Labels)),
+	}
+	for key, value := range object.Labels {
+		entry.labels[key] = value
+	}
+	if location, ok := objectLocation(object); ok {
+		entry.location = &location
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(object.ID)
+	i.entries[object.ID] = entry
+	addID(i.types, entry.objectType, object.ID)
+	if entry.kindID != "" {
+		addID(i.kinds, entry.kindID, object.ID)
+	}
+	for key, value := range entry.labels {
+		values, ok := i.labels[key]
+		if !ok {
+			values = make(map[string]idSet)
+			i.labels[key] = values
+		}
+		addID(values, value, object.ID)
+	}
+	if entry.location != nil {
+		i.geo.add(object.ID, *entry.location)
+	}
+}
+
+// delete removes the object with the given ID from the index
+func (i *objectIndex) delete(id topoapi.ID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+}
+
+// remove removes the object with the given ID from the index; it must be called with the index lock held
+func (i *objectIndex) remove(id topoapi.ID) {
+	entry, ok := i.entries[id]
+	if !ok {
+		return
+	}
+	delete(i.entries, id)
+	i.geo.remove(id)
+	removeID(i.types, entry.objectType, id)
+	if entry.kindID != "" {
+		removeID(i.kinds, entry.kindID, id)
+	}
+	for key, value := range entry.labels {
+		if values, ok := i.labels[key]; ok {
+			removeID(values, value, id)
+			if len(values) == 0 {
+				delete(i.labels, key)
+			}
+		}
+	}
+}
+
+// size returns the number of indexed objects
+func (i *objectIndex) size() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.entries)
+}
+
+// candidates returns the IDs of the objects that may match the given filters. The caller must still apply
+// the filters to the returned objects. If none of the filters can be answered from the index, candidates
+// returns false.
+func (i *objectIndex) candidates(filters *topoapi.Filters) (idSet, bool) {
+	if filters == nil {
+		return nil, false
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var result idSet
+	narrowed := false
+	intersect := func(ids idSet) {
+		if !narrowed {
+			result = ids
+			narrowed = true
+			return
+		}
+		for id := range result {
+			if _, ok := ids[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+
+	if len(filters.ObjectTypes) > 0 {
+		ids := make(idSet)
+		for _, objectType := range filters.ObjectTypes {
+			union(ids, i.types[objectType])
+		}
+		intersect(ids)
+	}
+
+	if values, ok := filterValues(filters.KindFilter); ok {
+		ids := make(idSet)
+		for _, value := range values {
+			union(ids, i.kinds[topoapi.ID(value)])
+		}
+		intersect(ids)
+	}
+
+	for _, filter := range filters.LabelFilters {
+		if filter.GetKey() == expressionKey {
+			// Filter expressions are applied to the candidates
+			continue
+		}
+		operator, key, ok := parseLabelKey(filter.GetKey())
+		if _, _, isAspect := parseAspectKey(key); isAspect {
+			// Aspect fields are not indexed
+			continue
+		}
+		if ok {
+			// Operators only match objects with the label, so they can be applied to the indexed label values
+			if operands, ok := filterOperands(filter); ok {
+				ids := make(idSet)
+				for value, valueIDs := range i.labels[key] {
+					for _, operand := range operands {
+						if matchLabelValue(operator, value, operand) {
+							union(ids, valueIDs)
+							break
+						}
+					}
+				}
+				intersect(ids)
+			}
+			continue
+		}
+		if values, ok := filterValues(filter); ok {
+			ids := make(idSet)
+			for _, value := range values {
+				union(ids, i.labels[filter.Key][value])
+			}
+			intersect(ids)
+		}
+	}
+	return result, narrowed
+}
+
+// locate returns the indexed locations that may match the given geo query, restricted to the given IDs if narrowed
+func (i *objectIndex) locate(query GeoQuery, ids idSet, narrowed bool) []geoCandidate {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.geo.candidates(query, ids, narrowed)
+}
+
+// count counts the indexed objects matching the given filters by group, restricted to the given IDs if narrowed.
+// The filters must only apply to indexed fields.
+func (i *objectIndex) count(filters *topoapi.Filters, groupBy []string, ids idSet, narrowed bool) []GroupCount {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	c := newCounter(groupBy)
+	add := func(id topoapi.ID, entry indexEntry) {
+		object := entry.object(id)
+		if match(object, filters) {
+			c.add(object)
+		}
+	}
+	if narrowed {
+		for id := range ids {
+			if entry, ok := i.entries[id]; ok {
+				add(id, entry)
+			}
+		}
+	} else {
+		for id, entry := range i.entries {
+			add(id, entry)
+		}
+	}
+	return c.results()
+}
+
+// object returns an object with the indexed fields of the entry
+func (e indexEntry) object(id topoapi.ID) *topoapi.Object {
+	object := &topoapi.Object{
+		ID:     id,
+		Type:   e.objectType,
+		Labels: e.labels,
+	}
+	switch e.objectType {
+	case topoapi.Object_ENTITY:
+		object.Obj = &topoapi.Object_Entity{Entity: &topoapi.Entity{KindID: e.kindID}}
+	case topoapi.Object_RELATION:
+		object.Obj = &topoapi.Object_Relation{Relation: &topoapi.Relation{KindID: e.kindID}}
+	}
+	return object
+}
+
+// filterValues returns the values accepted by an equality or set membership filter. Filters that can match
+// objects without the filtered field, such as negations or comparisons to an empty value, are not indexable.
+func filterValues(filter *topoapi.Filter) ([]string, bool) {
+	if filter == nil {
+		return nil, false
+	}
+	if eqo := filter.GetEqual_(); eqo != nil {
+		if eqo.Value == "" {
+			return nil, false
+		}
+		return []string{eqo.Value}, true
+	}
+	if igo := filter.GetIn(); igo != nil {
+		for _, value := range igo.Values {
+			if value == "" {
+				return nil, false
+			}
+		}
+		return igo.Values, true
+	}
+	return nil, false
+}
+
+// filterOperands returns the operands of an equality or set membership filter
+func filterOperands(filter *topoapi.Filter) ([]string, bool) {
+	if eqo := filter.GetEqual_(); eqo != nil {
+		return []string{eqo.Value}, true
+	}
+	if igo := filter.GetIn(); igo != nil {
+		return igo.Values, true
+	}
+	return nil, false
+}
+
+// kindID returns the kind ID of an entity or relation
+func kindID(object *topoapi.Object) topoapi.ID {
+	switch object.Type {
+	case topoapi.Object_ENTITY:
+		return object.GetEntity().GetKindID()
+	case topoapi.Object_RELATION:
+		return object.GetRelation().GetKindID()
+	}
+	return ""
+}
+
+func addID[K comparable](sets map[K]idSet, key K, id topoapi.ID) {
+	ids, ok := sets[key]
+	if !ok {
+		ids = make(idSet)
+		sets[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removeID[K comparable](sets map[K]idSet, key K, id topoapi.ID) {
+	if ids, ok := sets[key]; ok {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(sets, key)
+		}
+	}
+}
+
+func union(ids idSet, other idSet) {
+	for id := range other {
+		ids[id] = struct{}{}
+	}
+}
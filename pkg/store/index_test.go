@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"testing"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectIndex(t *testing.T) {
+	index := newObjectIndex()
+	index.update(&topo.Object{
+		ID:     "s1",
+		Type:   topo.Object_ENTITY,
+		Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: "switch"}},
+		Labels: map[string]string{"pod": "pod-01", "role": "leaf"},
+	})
+	index.update(&topo.Object{
+		ID:     "s2",
+		Type:   topo.Object_ENTITY,
+		Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: "switch"}},
+		Labels: map[string]string{"pod": "pod-02", "role": "spine"},
+	})
+	index.update(&topo.Object{
+		ID:     "r1",
+		Type:   topo.Object_RELATION,
+		Obj:    &topo.Object_Relation{Relation: &topo.Relation{KindID: "contains", SrcEntityID: "s1", TgtEntityID: "s2"}},
+		Labels: map[string]string{"pod": "pod-01"},
+	})
+	index.update(&topo.Object{
+		ID:   "switch",
+		Type: topo.Object_KIND,
+		Obj:  &topo.Object_Kind{Kind: &topo.Kind{Name: "switch"}},
+	})
+	assert.Equal(t, 4, index.size())
+
+	_, ok := index.candidates(nil)
+	assert.False(t, ok)
+
+	ids, ok := index.candidates(&topo.Filters{ObjectTypes: []topo.Object_Type{topo.Object_RELATION, topo.Object_KIND}})
+	assert.True(t, ok)
+	assert.Equal(t, idSet{"r1": {}, "switch": {}}, ids)
+
+	ids, ok = index.candidates(&topo.Filters{KindFilter: &topo.Filter{
+		Filter: &topo.Filter_In{In: &topo.InFilter{Values: []string{"switch", "contains"}}},
+	}})
+	assert.True(t, ok)
+	assert.Equal(t, idSet{"s1": {}, "s2": {}, "r1": {}}, ids)
+
+	ids, ok = index.candidates(&topo.Filters{
+		KindFilter: &topo.Filter{Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "switch"}}},
+		LabelFilters: []*topo.Filter{
+			{Key: "pod", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "pod-01"}}},
+		},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, idSet{"s1": {}}, ids)
+
+	// Negations and comparisons to empty values cannot be answered from the index
+	_, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{
+		{Key: "pod", Filter: &topo.Filter_Not{Not: &topo.NotFilter{Inner: &topo.Filter{
+			Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "pod-01"}},
+		}}}},
+	}})
+	assert.False(t, ok)
+	_, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{
+		{Key: "pod", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: ""}}},
+	}})
+	assert.False(t, ok)
+
+	// Label operators must be applied to the indexed label values
+	ids, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{NewLabelFilter(LabelPrefix, "pod", "pod-0")}})
+	assert.True(t, ok)
+	assert.Equal(t, idSet{"s1": {}, "s2": {}, "r1": {}}, ids)
+	ids, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{NewLabelFilter(LabelExists, "role", "")}})
+	assert.True(t, ok)
+	assert.Equal(t, idSet{"s1": {}, "s2": {}}, ids)
+	ids, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{NewLabelFilter(LabelRegex, "role", "^sp")}})
+	assert.True(t, ok)
+	assert.Equal(t, idSet{"s2": {}}, ids)
+	_, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{{
+		Filter: &topo.Filter_Not{Not: &topo.NotFilter{Inner: NewLabelFilter(LabelExists, "role", "")}},
+	}}})
+	assert.False(t, ok)
+
+	// Aspect fields are not indexed
+	_, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{
+		{Key: AspectKey("onos.topo.Switch", "role"), Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "leaf"}}},
+		NewLabelFilter(LabelExists, AspectKey("onos.topo.Switch", "role"), ""),
+	}})
+	assert.False(t, ok)
+
+	// Filter expressions are applied to the candidates
+	expr, err := NewExpressionFilter(&FilterExpr{Label: &LabelPredicate{Key: "pod", Values: []string{"pod-01"}}})
+	assert.NoError(t, err)
+	_, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{expr}})
+	assert.False(t, ok)
+
+	// Updates must re-index the changed labels
+	index.update(&topo.Object{
+		ID:     "s1",
+		Type:   topo.Object_ENTITY,
+		Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: "switch"}},
+		Labels: map[string]string{"pod": "pod-02"},
+	})
+	ids, ok = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{
+		{Key: "pod", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "pod-02"}}},
+	}})
+	assert.True(t, ok)
+	assert.Equal(t, idSet{"s1": {}, "s2": {}}, ids)
+	ids, _ = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{
+		{Key: "role", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "leaf"}}},
+	}})
+	assert.Empty(t, ids)
+
+	index.delete("s1")
+	index.delete("r1")
+	assert.Equal(t, 2, index.size())
+	ids, _ = index.candidates(&topo.Filters{LabelFilters: []*topo.Filter{
+		{Key: "pod", Filter: &topo.Filter_In{In: &topo.InFilter{Values: []string{"pod-01", "pod-02"}}}},
+	}})
+	assert.Equal(t, idSet{"s2": {}}, ids)
+}
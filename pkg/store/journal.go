@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// DefaultJournalSize is the default number of recent events kept by a store for resuming watches
+const DefaultJournalSize = 10000
+
+// journal is a bounded log of the most recent changes delivered by a store, in delivery order
+type journal struct {
+	// events is a ring buffer of the journaled changes; first is the index of the oldest change
+	events []change
+	first  int
+	size   int
+	// compacted is the highest revision of the changes that are no longer in the journal
+	compacted topoapi.Revision
+	// loaded is the highest revision of the objects loaded when the store started. Changes up to it were made
+	// before the journal started, but watchers that observed it have not missed any of them.
+	loaded topoapi.Revision
+}
+
+func newJournal(capacity int) *journal {
+	return &journal{
+		events: make([]change, capacity),
+	}
+}
+
+// record appends a change to the journal, compacting the oldest change if the journal is full
+func (j *journal) record(c change) {
+	if len(j.events) == 0 {
+		j.compact(c.event.Object.Revision)
+		return
+	}
+	if j.size == len(j.events) {
+		j.compact(j.events[j.first].event.Object.Revision)
+		j.events[j.first] = c
+		j.first = (j.first + 1) % len(j.events)
+		return
+	}
+	j.events[(j.first+j.size)%len(j.events)] = c
+	j.size++
+}
+
+// load records that an object was loaded at the given revision when the store started
+func (j *journal) load(revision topoapi.Revision) {
+	if revision > j.loaded {
+		j.loaded = revision
+	}
+}
+
+// compact records that the changes up to the given revision cannot be replayed from the journal
+func (j *journal) compact(revision topoapi.Revision) {
+	if revision > j.compacted {
+		j.compacted = revision
+	}
+}
+
+// since returns the journaled changes following the event at the given revision. Revisions of the Atomix store
+// are only ordered per partition, so events are returned from the first event at or after the revision, and
+// may include events already delivered to the watcher. If changes at or after the revision have been compacted,
+// or were made before the store started, since returns a Conflict error.
+func (j *journal) since(revision topoapi.Revision) ([]change, error) {
+	if revision < j.loaded || j.compacted > 0 && j.compacted >= revision {
+		return nil, errors.NewConflict("revision %d has been compacted; the topology must be listed again", revision)
+	}
+
+	// Find the first event at or after the revision, skipping the event at the revision itself
+	start := j.size
+	for i := 0; i < j.size; i++ {
+		if j.at(i).event.Object.Revision >= revision {
+			start = i
+			break
+		}
+	}
+	if start < j.size && j.at(start).event.Object.Revision == revision {
+		start++
+	}
+
+	events := make([]change, 0, j.size-start)
+	for i := start; i < j.size; i++ {
+		events = append(events, j.at(i))
+	}
+	return events, nil
+}
+
+// at returns the i-th oldest change in the journal
+func (j *journal) at(i int) change {
+	return j.events[(j.first+i)%len(j.events)]
+}
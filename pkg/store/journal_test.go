@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"testing"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func journalEvent(id topo.ID, revision topo.Revision) change {
+	return change{event: topo.Event{
+		Type:   topo.EventType_UPDATED,
+		Object: topo.Object{ID: id, Revision: revision},
+	}}
+}
+
+func journalIDs(changes []change) []topo.ID {
+	ids := make([]topo.ID, 0, len(changes))
+	for _, c := range changes {
+		ids = append(ids, c.event.Object.ID)
+	}
+	return ids
+}
+
+func TestJournal(t *testing.T) {
+	j := newJournal(4)
+	events, err := j.since(0)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	j.record(journalEvent("a", 1))
+	j.record(journalEvent("b", 2))
+	j.record(journalEvent("c", 3))
+
+	events, err = j.since(0)
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"a", "b", "c"}, journalIDs(events))
+	events, err = j.since(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"b", "c"}, journalIDs(events))
+	events, err = j.since(3)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	// Compacting the oldest events must fail resuming from them
+	j.record(journalEvent("d", 4))
+	j.record(journalEvent("e", 5))
+	j.record(journalEvent("f", 6))
+	_, err = j.since(1)
+	assert.True(t, errors.IsConflict(err))
+	_, err = j.since(2)
+	assert.True(t, errors.IsConflict(err))
+	events, err = j.since(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"d", "e", "f"}, journalIDs(events))
+
+	// Revisions from different partitions may interleave; events recorded after the first event at the
+	// requested revision must be replayed
+	j.record(journalEvent("g", 8))
+	j.record(journalEvent("h", 7))
+	events, err = j.since(8)
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"h"}, journalIDs(events))
+
+	// Watches can be resumed from the objects loaded when the store started, but not from earlier revisions
+	j = newJournal(4)
+	j.load(3)
+	j.load(2)
+	_, err = j.since(2)
+	assert.True(t, errors.IsConflict(err))
+	events, err = j.since(3)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	j.record(journalEvent("a", 4))
+	events, err = j.since(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"a"}, journalIDs(events))
+
+	// A journal without capacity cannot resume watches once changes have been made
+	j = newJournal(0)
+	j.record(journalEvent("a", 1))
+	_, err = j.since(0)
+	assert.True(t, errors.IsConflict(err))
+}
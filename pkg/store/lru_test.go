@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	cache := newLRU[int](2)
+	cache.add("a", 1)
+	cache.add("b", 2)
+
+	// Reading a value makes it the most recently used
+	value, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	// Adding a value to a full cache evicts the least recently used one only
+	cache.add("c", 3)
+	assert.Equal(t, 2, cache.len())
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	value, ok = cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	value, ok = cache.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+
+	// Adding a cached key replaces its value without evicting others
+	cache.add("a", 4)
+	assert.Equal(t, 2, cache.len())
+	value, ok = cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 4, value)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+}
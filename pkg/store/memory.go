@@ -0,0 +1,435 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// NewMemoryStore returns a new Store backed by local memory; it is intended for standalone and test use.
+// Reads from the memory store are always served locally, so WithCachedReads and WithIndexedReads have no effect.
+func NewMemoryStore(opts ...StoreOption) Store {
+	storeOpts := newStoreOptions(opts)
+	store := &memoryStore{
+		objects:   make(map[topoapi.ID]*topoapi.Object),
+		watchers:  newWatchers(storeOpts.journalSize),
+		relations: newRelationMaps(),
+	}
+	return store
+}
+
+// memoryStore is an in-memory implementation of the Store
+type memoryStore struct {
+	objects   map[topoapi.ID]*topoapi.Object
+	revision  topoapi.Revision
+	mu        sync.RWMutex
+	relations relationMaps
+	watchers  *watchers
+	// committed holds the changes made by the transaction being committed, if any, until they are published as
+	// a group
+	committed *[]change
+}
+
+// publish queues an event for delivery to the watchers along with the previous state of the object, if any; it
+// must be called with the store lock held so that events are queued in revision order
+func (s *memoryStore) publish(eventType topoapi.EventType, object *topoapi.Object, previous *topoapi.Object) {
+	if previous != nil {
+		previous = clone(previous)
+	}
+	event := topoapi.Event{
+		Type:   eventType,
+		Object: *clone(object),
+	}
+	if s.committed != nil {
+		*s.committed = append(*s.committed, change{event: event, previous: previous})
+		return
+	}
+	s.watchers.send(event, previous)
+}
+
+func (s *memoryStore) Create(ctx context.Context, object *topoapi.Object) error {
+	if object.Type == topoapi.Object_UNSPECIFIED {
+		return errors.NewInvalid("Type cannot be unspecified")
+	}
+	if err := checkReserved(object); err != nil {
+		return err
+	}
+
+	// set a uuid
+	uuid, err := uuid.NewRandom()
+	if err != nil {
+		return errors.NewInternal(err.Error())
+	}
+	object.UUID = topoapi.UUID(uuid.String())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// If an object is a relation and its ID is empty, build one.
+	if object.Type == topoapi.Object_RELATION {
+		if object.ID == "" {
+			object.ID = topoapi.ID("uuid:" + string(object.UUID))
+		}
+		if err := s.checkEndpoints(object); err != nil {
+			return err
+		}
+	} else if object.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Creating Object %+v", object)
+
+	if _, ok := s.objects[object.ID]; ok {
+		err := errors.NewAlreadyExists("Object '%s' already exists", object.ID)
+		log.Warnf("Failed to create Object %+v: %v", object, err)
+		return err
+	}
+
+	s.revision++
+	object.Revision = s.revision
+	stored := clone(object)
+	s.objects[object.ID] = stored
+	s.relations.register(stored)
+	s.publish(topoapi.EventType_ADDED, stored, nil)
+	return nil
+}
+
+func (s *memoryStore) Update(ctx context.Context, object *topoapi.Object) error {
+	if object.ID == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+	if object.Type == topoapi.Object_UNSPECIFIED {
+		return errors.NewInvalid("Type cannot be unspecified")
+	}
+	if object.Revision == 0 {
+		return errors.NewInvalid("object must contain a revision on update")
+	}
+	if err := checkReserved(object); err != nil {
+		return err
+	}
+
+	log.Infof("Updating Object %+v", object)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.objects[object.ID]
+	if !ok {
+		err := errors.NewNotFound("Object '%s' not found", object.ID)
+		log.Warnf("Failed to update Object %+v: %v", object, err)
+		return err
+	}
+	if stored.Revision != object.Revision {
+		err := errors.NewConflict("Object '%s' revision %d does not match %d", object.ID, object.Revision, stored.Revision)
+		log.Warnf("Failed to update Object %+v: %v", object, err)
+		return err
+	}
+	if object.Type == topoapi.Object_RELATION && endpointsChanged(stored, object) {
+		if err := s.checkEndpoints(object); err != nil {
+			return err
+		}
+	}
+
+	s.revision++
+	object.Revision = s.revision
+	previous := stored
+	stored = clone(object)
+	s.objects[object.ID] = stored
+	s.relations.reindex(previous, stored)
+	s.publish(topoapi.EventType_UPDATED, stored, previous)
+	return nil
+}
+
+// Get retrieves an object from the store; reads from the memory store always observe the latest revision
+func (s *memoryStore) Get(ctx context.Context, id topoapi.ID, opts ...ReadOption) (*topoapi.Object, error) {
+	if id == "" {
+		return nil, errors.NewInvalid("ID cannot be empty")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, ok := s.objects[id]
+	if !ok {
+		err := errors.NewNotFound("Object '%s' not found", id)
+		log.Warnf("Failed to get Object '%s': %v", id, err)
+		return nil, err
+	}
+	return s.read(stored), nil
+}
+
+// read returns a copy of the given stored object with its relation IDs populated
+func (s *memoryStore) read(stored *topoapi.Object) *topoapi.Object {
+	obj := clone(stored)
+	s.relations.addSrcTgts(obj)
+	return obj
+}
+
+func (s *memoryStore) Delete(ctx context.Context, id topoapi.ID, revision topoapi.Revision) error {
+	if id == "" {
+		return errors.NewInvalid("ID cannot be empty")
+	}
+
+	log.Infof("Deleting Object '%s'", id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.objects[id]
+	if !ok {
+		err := errors.NewNotFound("Object '%s' not found", id)
+		log.Warnf("Failed to delete Object '%s': %v", id, err)
+		return err
+	}
+	if revision != 0 && stored.Revision != revision {
+		err := errors.NewConflict("Object '%s' revision %d does not match %d", id, revision, stored.Revision)
+		log.Warnf("Failed to delete Object '%s': %v", id, err)
+		return err
+	}
+
+	// delete the relations of an entity before the entity itself
+	if stored.GetEntity() != nil {
+		for _, rid := range s.relations.related(id) {
+			s.remove(rid)
+		}
+	}
+	s.remove(id)
+	return nil
+}
+
+func (s *memoryStore) Transaction(ctx context.Context, operations ...Operation) error {
+	tx, err := newTransaction(operations)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check the preconditions of all operations before applying any of them
+	for _, op := range tx.operations {
+		object := op.Object
+		stored, ok := s.objects[object.ID]
+		switch op.Type {
+		case OperationCreate:
+			if ok {
+				return errors.NewAlreadyExists("Object '%s' already exists", object.ID)
+			}
+		case OperationUpdate, OperationDelete:
+			if !ok {
+				return errors.NewNotFound("Object '%s' not found", object.ID)
+			}
+			if object.Revision != 0 && stored.Revision != object.Revision {
+				return errors.NewConflict("Object '%s' revision %d does not match %d", object.ID, object.Revision, stored.Revision)
+			}
+		}
+	}
+
+	// Delete the relations of deleted entities in the same transaction
+	for _, id := range tx.deletes() {
+		if s.objects[id].GetEntity() != nil {
+			if err := tx.cascade(id, s.relations.related(id)); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tx.validateRelations(func(id topoapi.ID) (bool, error) {
+		_, ok := s.objects[id]
+		return ok, nil
+	}, func(object *topoapi.Object) bool {
+		stored, ok := s.objects[object.ID]
+		return ok && stored.Revision == object.Revision && !endpointsChanged(stored, object)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Committing transaction with %d operations", len(tx.operations))
+
+	// The events are published as a group under the store lock, so watchers observe the changes together
+	var committed []change
+	s.committed = &committed
+	defer func() {
+		s.committed = nil
+		s.watchers.sendGroup(uuid.New().String(), committed)
+	}()
+	for _, op := range tx.operations {
+		object := op.Object
+		switch op.Type {
+		case OperationCreate:
+			s.revision++
+			object.Revision = s.revision
+			stored := clone(object)
+			s.objects[object.ID] = stored
+			s.relations.register(stored)
+			s.publish(topoapi.EventType_ADDED, stored, nil)
+		case OperationUpdate:
+			s.revision++
+			object.Revision = s.revision
+			previous := s.objects[object.ID]
+			stored := clone(object)
+			s.objects[object.ID] = stored
+			s.relations.reindex(previous, stored)
+			s.publish(topoapi.EventType_UPDATED, stored, previous)
+		case OperationDelete:
+			s.remove(object.ID)
+		}
+	}
+	return nil
+}
+
+// checkEndpoints checks that the source and target entities of a relation being written exist; it must be
+// called with the store lock held
+func (s *memoryStore) checkEndpoints(object *topoapi.Object) error {
+	if _, ok := s.objects[object.GetRelation().GetSrcEntityID()]; !ok {
+		log.Warnf("Source Entity does not exist")
+		return errors.NewInvalid("Source Entity does not exist")
+	}
+	if _, ok := s.objects[object.GetRelation().GetTgtEntityID()]; !ok {
+		log.Warnf("Target Entity does not exist")
+		return errors.NewInvalid("Target Entity does not exist")
+	}
+	return nil
+}
+
+// remove removes the object with the given ID; it must be called with the store lock held
+func (s *memoryStore) remove(id topoapi.ID) {
+	stored, ok := s.objects[id]
+	if !ok {
+		return
+	}
+	delete(s.objects, id)
+	previous := *stored
+	s.revision++
+	stored.Revision = s.revision
+	s.relations.unregister(stored)
+	s.publish(topoapi.EventType_REMOVED, stored, &previous)
+}
+
+// Locate returns the objects whose location matches the given geo query
+func (s *memoryStore) Locate(ctx context.Context, query GeoQuery, opts ...ReadOption) ([]Located, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	var candidates []geoCandidate
+	s.mu.RLock()
+	for id, stored := range s.objects {
+		if point, ok := objectLocation(stored); ok && query.contains(point) {
+			candidates = append(candidates, geoCandidate{id: id, point: point})
+		}
+	}
+	s.mu.RUnlock()
+	return locate(ctx, s.Get, query, candidates, opts...)
+}
+
+// Count returns the number of objects matching the given filters grouped by the given fields
+func (s *memoryStore) Count(ctx context.Context, filters *topoapi.Filters, groupBy []string, opts ...ReadOption) ([]GroupCount, error) {
+	if err := validateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+	objects, err := s.List(ctx, filters, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return countObjects(objects, groupBy), nil
+}
+
+// Query streams objects to the given channel, closing it once all objects have been sent or an error occurs
+func (s *memoryStore) Query(ctx context.Context, ch chan<- *topoapi.Object, filters *topoapi.Filters, opts ...ReadOption) error {
+	defer close(ch)
+	objects, err := s.List(ctx, filters, opts...)
+	if err != nil {
+		return err
+	}
+	for i := range objects {
+		ch <- &objects[i]
+	}
+	return nil
+}
+
+// Traverse streams the objects reached by the given traversal to the given channel
+func (s *memoryStore) Traverse(ctx context.Context, ch chan<- *topoapi.Object, traversal Traversal, opts ...ReadOption) error {
+	defer close(ch)
+	if err := streamTraversal(ctx, s.Get, ch, traversal, opts...); err != nil {
+		if err != ErrTraversalTruncated {
+			log.Warnf("Failed to traverse from '%s': %v", traversal.Start, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Paths returns the shortest paths between the entities of the given query
+func (s *memoryStore) Paths(ctx context.Context, query PathQuery, opts ...ReadOption) ([]Path, error) {
+	paths, err := shortestPaths(ctx, s.Get, query, opts...)
+	if err != nil {
+		log.Warnf("Failed to find paths from '%s' to '%s': %v", query.Src, query.Dst, err)
+		return nil, err
+	}
+	return paths, nil
+}
+
+func (s *memoryStore) List(ctx context.Context, filters *topoapi.Filters, opts ...ReadOption) ([]topoapi.Object, error) {
+	if err := validateFilters(filters); err != nil {
+		return nil, err
+	}
+	if filters != nil && filters.RelationFilter != nil {
+		return listRelationFilter(ctx, s.Get, filters, opts...)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	eps := make([]topoapi.Object, 0)
+	for _, stored := range s.objects {
+		if filters == nil || (match(stored, filters) && matchType(stored, filters.ObjectTypes) && matchAspects(stored, filters.WithAspects)) {
+			eps = append(eps, *s.read(stored))
+		}
+	}
+	return eps, nil
+}
+
+func (s *memoryStore) Watch(ctx context.Context, ch chan<- topoapi.Event, filters *topoapi.Filters, opts ...WatchOption) error {
+	// Events are published with the store lock held, so holding the lock while the watcher is registered makes
+	// the snapshot consistent with the events queued for the watcher
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	get := func(ctx context.Context, id topoapi.ID) (*topoapi.Object, error) {
+		return s.Get(ctx, id)
+	}
+	return s.watchers.watch(ctx, ch, filters, s.snapshot, get, opts...)
+}
+
+func (s *memoryStore) WatchStats() WatchStats {
+	return s.watchers.stats()
+}
+
+// snapshot returns a copy of the objects in the store; it must be called with the store lock held. Events are
+// queued for the watchers before the lock is released, so no event is pending when the snapshot is taken.
+func (s *memoryStore) snapshot() storeSnapshot {
+	snapshot := storeSnapshot{
+		objects: make([]topoapi.Object, 0, len(s.objects)),
+	}
+	for _, stored := range s.objects {
+		snapshot.objects = append(snapshot.objects, *clone(stored))
+	}
+	return snapshot
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// clone returns a deep copy of the given object
+func clone(object *topoapi.Object) *topoapi.Object {
+	return proto.Clone(object).(*topoapi.Object)
+}
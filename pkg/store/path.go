@@ -0,0 +1,477 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// MaxPaths is the highest number of shortest paths a path query can find. Each path beyond the first one is found
+// by searching the graph once for every entity of the previous path, so K is capped to bound the cost of a query.
+const MaxPaths = 32
+
+// MaxPathVisits is the highest number of entities a path query can visit, counting an entity once for each search
+// of the graph reaching it. A query exceeding it fails rather than searching much of a large graph.
+const MaxPathVisits = 100000
+
+// PathWeight determines the weight of each hop of a path. The weight is read from the relation followed by the
+// hop, or from the entity it reaches if the relation does not carry it; hops carrying no weight weigh Default.
+type PathWeight struct {
+	// Label is the label holding the weight; labels are used if no aspect is set
+	Label string `json:"label,omitempty"`
+	// Aspect is the type of the aspect holding the weight
+	Aspect string `json:"aspect,omitempty"`
+	// Field is the dot separated path of the weight in the JSON value of the aspect
+	Field string `json:"field,omitempty"`
+	// Default is the weight of hops that carry no weight; it is 1 if zero
+	Default float64 `json:"default,omitempty"`
+}
+
+// PathQuery is a query of the shortest paths between two entities
+type PathQuery struct {
+	// Src is the ID of the entity the paths start from
+	Src topoapi.ID `json:"src"`
+	// Dst is the ID of the entity the paths end at
+	Dst topoapi.ID `json:"dst"`
+	// RelationKinds are the kinds of the relations the paths may follow; relations of any kind are followed if empty
+	RelationKinds []topoapi.ID `json:"relationKinds,omitempty"`
+	// Direction is the direction in which relations are followed
+	Direction Direction `json:"direction,omitempty"`
+	// Weight determines the weight of each hop; each hop weighs 1 if not set
+	Weight *PathWeight `json:"weight,omitempty"`
+	// K is the number of shortest paths to find, up to MaxPaths; a single path is found if zero
+	K int `json:"k,omitempty"`
+}
+
+// Path is a loopless path between two entities
+type Path struct {
+	// EntityIDs are the IDs of the entities of the path, from its source to its destination
+	EntityIDs []topoapi.ID `json:"entityIDs"`
+	// RelationIDs are the IDs of the relations followed between consecutive entities of the path
+	RelationIDs []topoapi.ID `json:"relationIDs"`
+	// Weight is the sum of the weights of the hops of the path
+	Weight float64 `json:"weight"`
+}
+
+func (q PathQuery) validate() error {
+	if q.Src == topoapi.NullID || q.Dst == topoapi.NullID {
+		return errors.NewInvalid("path query must have a source and a destination entity")
+	}
+	if q.Direction < DirectionOutbound || q.Direction > DirectionBoth {
+		return errors.NewInvalid("unknown direction %d", q.Direction)
+	}
+	if q.K < 0 {
+		return errors.NewInvalid("number of paths cannot be negative")
+	}
+	if q.K > MaxPaths {
+		return errors.NewInvalid("number of paths cannot exceed %d", MaxPaths)
+	}
+	if q.Weight != nil {
+		if q.Weight.Label == "" && q.Weight.Aspect == "" {
+			return errors.NewInvalid("path weight must have a label or an aspect")
+		}
+		if q.Weight.Aspect != "" && q.Weight.Field == "" {
+			return errors.NewInvalid("path weight aspect must have a field")
+		}
+		if q.Weight.Default < 0 {
+			return errors.NewInvalid("path weight default cannot be negative")
+		}
+	}
+	return nil
+}
+
+// pathEdge is a relation that can be followed from an entity
+type pathEdge struct {
+	relationID topoapi.ID
+	to         topoapi.ID
+	weight     float64
+}
+
+// pathGraph lazily loads the edges of the entities explored by a path query
+type pathGraph struct {
+	ctx    context.Context
+	get    getFunc
+	opts   []ReadOption
+	query  PathQuery
+	kinds  idSet
+	edges  map[topoapi.ID][]pathEdge
+	visits int
+}
+
+func newPathGraph(ctx context.Context, get getFunc, query PathQuery, opts ...ReadOption) *pathGraph {
+	g := &pathGraph{
+		ctx:   ctx,
+		get:   cachedGet(get),
+		opts:  opts,
+		query: query,
+		edges: make(map[topoapi.ID][]pathEdge),
+	}
+	if len(query.RelationKinds) > 0 {
+		g.kinds = make(idSet, len(query.RelationKinds))
+		for _, kind := range query.RelationKinds {
+			g.kinds[kind] = struct{}{}
+		}
+	}
+	return g
+}
+
+// edgesOf returns the edges that can be followed from the given entity
+func (g *pathGraph) edgesOf(id topoapi.ID) ([]pathEdge, error) {
+	if edges, ok := g.edges[id]; ok {
+		return edges, nil
+	}
+	entity, err := g.get(g.ctx, id, g.opts...)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			g.edges[id] = nil
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var edges []pathEdge
+	step := TraversalStep{Direction: g.query.Direction}
+	for _, relationID := range step.relationIDs(entity) {
+		relation, err := g.get(g.ctx, relationID, g.opts...)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		rel := relation.GetRelation()
+		if rel == nil {
+			continue
+		}
+		if _, ok := g.kinds[rel.KindID]; g.kinds != nil && !ok {
+			continue
+		}
+		otherID := rel.TgtEntityID
+		if rel.TgtEntityID == id && (g.query.Direction == DirectionInbound || rel.SrcEntityID != id) {
+			otherID = rel.SrcEntityID
+		}
+		if otherID == id {
+			continue
+		}
+		weight, err := g.weight(relation, otherID)
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, pathEdge{relationID: relation.ID, to: otherID, weight: weight})
+	}
+	g.edges[id] = edges
+	return edges, nil
+}
+
+// weight returns the weight of the hop following the given relation to the given entity
+func (g *pathGraph) weight(relation *topoapi.Object, to topoapi.ID) (float64, error) {
+	w := g.query.Weight
+	if w == nil {
+		return 1, nil
+	}
+	if weight, ok, err := objectWeight(relation, w); ok || err != nil {
+		return weight, err
+	}
+	entity, err := g.get(g.ctx, to, g.opts...)
+	if err != nil && !errors.IsNotFound(err) {
+		return 0, err
+	}
+	if entity != nil {
+		if weight, ok, err := objectWeight(entity, w); ok || err != nil {
+			return weight, err
+		}
+	}
+	if w.Default > 0 {
+		return w.Default, nil
+	}
+	return 1, nil
+}
+
+// objectWeight returns the weight carried by the given object, if any
+func objectWeight(object *topoapi.Object, w *PathWeight) (float64, bool, error) {
+	var weight float64
+	if w.Aspect != "" {
+		value, ok, err := aspectField(object, w.Aspect, w.Field)
+		if err != nil || !ok {
+			return 0, false, err
+		}
+		switch v := value.(type) {
+		case json.Number:
+			parsed, err := v.Float64()
+			if err != nil {
+				return 0, false, errors.NewInvalid("weight '%s' of Object '%s' is not a number", w.Field, object.ID)
+			}
+			weight = parsed
+		case string:
+			// 64-bit integers are encoded as strings in the JSON encoding of protobuf messages
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0, false, errors.NewInvalid("weight '%s' of Object '%s' is not a number", w.Field, object.ID)
+			}
+			weight = parsed
+		default:
+			return 0, false, errors.NewInvalid("weight '%s' of Object '%s' is not a number", w.Field, object.ID)
+		}
+	} else {
+		label, ok := object.Labels[w.Label]
+		if !ok {
+			return 0, false, nil
+		}
+		parsed, err := strconv.ParseFloat(label, 64)
+		if err != nil {
+			return 0, false, errors.NewInvalid("weight label '%s' of Object '%s' is not a number", w.Label, object.ID)
+		}
+		weight = parsed
+	}
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return 0, false, errors.NewInvalid("weight of Object '%s' must be a non-negative number", object.ID)
+	}
+	return weight, true, nil
+}
+
+// shortestPaths returns up to K loopless paths between the entities of the query in order of increasing weight,
+// using Yen's algorithm
+func shortestPaths(ctx context.Context, get getFunc, query PathQuery, opts ...ReadOption) ([]Path, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	k := query.K
+	if k == 0 {
+		k = 1
+	}
+	graph := newPathGraph(ctx, get, query, opts...)
+	if _, err := graph.get(ctx, query.Src, opts...); err != nil {
+		return nil, err
+	}
+	if _, err := graph.get(ctx, query.Dst, opts...); err != nil {
+		return nil, err
+	}
+
+	first, err := graph.shortestPath(query.Src, query.Dst, nil, nil)
+	if err != nil || first == nil {
+		return []Path{}, err
+	}
+	paths := []Path{*first}
+	var candidates []Path
+	for len(paths) < k {
+		previous := paths[len(paths)-1]
+		for i := 0; i < len(previous.EntityIDs)-1; i++ {
+			spur := previous.EntityIDs[i]
+			root := Path{
+				EntityIDs:   previous.EntityIDs[:i+1],
+				RelationIDs: previous.RelationIDs[:i],
+			}
+
+			// Exclude the hops of the known paths sharing the root, and the entities of the root
+			excludedEdges := make(map[pathEdgeKey]bool)
+			for _, path := range paths {
+				if len(path.EntityIDs) > i+1 && path.hasRoot(root) {
+					excludedEdges[pathEdgeKey{from: path.EntityIDs[i], relationID: path.RelationIDs[i]}] = true
+				}
+			}
+			excludedEntities := make(idSet, i)
+			for _, id := range root.EntityIDs[:i] {
+				excludedEntities[id] = struct{}{}
+			}
+
+			spurPath, err := graph.shortestPath(spur, query.Dst, excludedEntities, excludedEdges)
+			if err != nil {
+				return nil, err
+			}
+			if spurPath == nil {
+				continue
+			}
+			rootWeight, err := graph.pathWeight(root)
+			if err != nil {
+				return nil, err
+			}
+			candidate := Path{
+				EntityIDs:   append(append([]topoapi.ID{}, root.EntityIDs[:i]...), spurPath.EntityIDs...),
+				RelationIDs: append(append([]topoapi.ID{}, root.RelationIDs...), spurPath.RelationIDs...),
+				Weight:      rootWeight + spurPath.Weight,
+			}
+			if !containsPath(paths, candidate) && !containsPath(candidates, candidate) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		// The next path is the lightest candidate, or the shortest of the lightest candidates
+		best := 0
+		for i, candidate := range candidates {
+			if candidate.Weight < candidates[best].Weight ||
+				(candidate.Weight == candidates[best].Weight && len(candidate.EntityIDs) < len(candidates[best].EntityIDs)) {
+				best = i
+			}
+		}
+		paths = append(paths, candidates[best])
+		candidates = append(candidates[:best], candidates[best+1:]...)
+	}
+	return paths, nil
+}
+
+// pathEdgeKey identifies a relation followed from an entity
+type pathEdgeKey struct {
+	from       topoapi.ID
+	relationID topoapi.ID
+}
+
+// shortestPath returns the lightest path between two entities avoiding the given entities and edges using
+// Dijkstra's algorithm, or nil if there is no such path
+func (g *pathGraph) shortestPath(src, dst topoapi.ID, excludedEntities idSet, excludedEdges map[pathEdgeKey]bool) (*Path, error) {
+	type previous struct {
+		from       topoapi.ID
+		relationID topoapi.ID
+	}
+	weights := map[topoapi.ID]float64{src: 0}
+	hops := map[topoapi.ID]int{src: 0}
+	previouses := make(map[topoapi.ID]previous)
+	done := make(idSet)
+	queue := &pathQueue{{id: src}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(pathQueueItem)
+		if _, ok := done[item.id]; ok {
+			continue
+		}
+		done[item.id] = struct{}{}
+		if item.id == dst {
+			break
+		}
+		if err := g.ctx.Err(); err != nil {
+			return nil, errors.NewTimeout(err.Error())
+		}
+		if g.visits++; g.visits > MaxPathVisits {
+			return nil, errors.NewInvalid("path query visits more than %d entities", MaxPathVisits)
+		}
+		edges, err := g.edgesOf(item.id)
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range edges {
+			if _, ok := excludedEntities[edge.to]; ok {
+				continue
+			}
+			if excludedEdges[pathEdgeKey{from: item.id, relationID: edge.relationID}] {
+				continue
+			}
+			if _, ok := done[edge.to]; ok {
+				continue
+			}
+			weight := weights[item.id] + edge.weight
+			current, ok := weights[edge.to]
+			if !ok || weight < current || (weight == current && hops[item.id]+1 < hops[edge.to]) {
+				weights[edge.to] = weight
+				hops[edge.to] = hops[item.id] + 1
+				previouses[edge.to] = previous{from: item.id, relationID: edge.relationID}
+				heap.Push(queue, pathQueueItem{id: edge.to, weight: weight, hops: hops[edge.to]})
+			}
+		}
+	}
+	if _, ok := done[dst]; !ok {
+		return nil, nil
+	}
+
+	path := &Path{Weight: weights[dst]}
+	for id := dst; id != src; id = previouses[id].from {
+		path.EntityIDs = append(path.EntityIDs, id)
+		path.RelationIDs = append(path.RelationIDs, previouses[id].relationID)
+	}
+	path.EntityIDs = append(path.EntityIDs, src)
+	reverseIDs(path.EntityIDs)
+	reverseIDs(path.RelationIDs)
+	return path, nil
+}
+
+// pathWeight returns the weight of the given path
+func (g *pathGraph) pathWeight(path Path) (float64, error) {
+	var weight float64
+	for i, relationID := range path.RelationIDs {
+		edges, err := g.edgesOf(path.EntityIDs[i])
+		if err != nil {
+			return 0, err
+		}
+		for _, edge := range edges {
+			if edge.relationID == relationID && edge.to == path.EntityIDs[i+1] {
+				weight += edge.weight
+				break
+			}
+		}
+	}
+	return weight, nil
+}
+
+// hasRoot returns whether the path starts with the given root path
+func (p Path) hasRoot(root Path) bool {
+	if len(p.EntityIDs) < len(root.EntityIDs) || len(p.RelationIDs) < len(root.RelationIDs) {
+		return false
+	}
+	for i, id := range root.EntityIDs {
+		if p.EntityIDs[i] != id {
+			return false
+		}
+	}
+	for i, id := range root.RelationIDs {
+		if p.RelationIDs[i] != id {
+			return false
+		}
+	}
+	return true
+}
+
+// containsPath returns whether the given paths contain a path following the same relations
+func containsPath(paths []Path, path Path) bool {
+	for _, p := range paths {
+		if len(p.RelationIDs) == len(path.RelationIDs) && p.hasRoot(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func reverseIDs(ids []topoapi.ID) {
+	for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+}
+
+// pathQueueItem is an entity reached by Dijkstra's algorithm
+type pathQueueItem struct {
+	id     topoapi.ID
+	weight float64
+	hops   int
+}
+
+// pathQueue is a priority queue of the lightest reached entities, with the fewest hops first
+type pathQueue []pathQueueItem
+
+func (q pathQueue) Len() int { return len(q) }
+
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].weight != q[j].weight {
+		return q[i].weight < q[j].weight
+	}
+	return q[i].hops < q[j].hops
+}
+
+func (q pathQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathQueueItem)) }
+
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaths(t *testing.T) {
+	forEachBackend(t, testPaths)
+}
+
+// createLinkedDevices creates three devices owning ports linked by link entities as in topo-scale; the direct
+// link between d1 and d2 has a higher latency than the links through d3:
+//
+//	d1/1 -> L12 (latency=10) <- d2/1
+//	d1/2 -> L13 (latency=1) <- d3/1
+//	d3/2 -> L32 (latency=1) <- d2/2
+func createLinkedDevices(t *testing.T, store Store) {
+	createEntity := func(id topo.ID, kind topo.ID, labels map[string]string) {
+		err := store.Create(context.TODO(), &topo.Object{
+			ID:     id,
+			Type:   topo.Object_ENTITY,
+			Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: kind}},
+			Labels: labels,
+		})
+		assert.NoError(t, err)
+	}
+	createRelation := func(src, tgt, kind topo.ID) {
+		err := store.Create(context.TODO(), &topo.Object{
+			ID:   src + "-" + kind + "-" + tgt,
+			Type: topo.Object_RELATION,
+			Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: kind, SrcEntityID: src, TgtEntityID: tgt}},
+		})
+		assert.NoError(t, err)
+	}
+	for _, device := range []topo.ID{"d1", "d2", "d3"} {
+		createEntity(device, "switch", nil)
+		for _, port := range []topo.ID{device + "/1", device + "/2"} {
+			createEntity(port, "port", nil)
+			createRelation(device, port, "has")
+		}
+	}
+	for _, link := range []struct {
+		id, src, tgt topo.ID
+		latency      string
+	}{
+		{"L12", "d1/1", "d2/1", "10"},
+		{"L13", "d1/2", "d3/1", "1"},
+		{"L32", "d3/2", "d2/2", "1"},
+	} {
+		createEntity(link.id, "link", map[string]string{"latency": link.latency})
+		createRelation(link.src, link.id, "originates")
+		createRelation(link.tgt, link.id, "terminates")
+	}
+	waitForRelations(t, store, "L32", 0, 2)
+	for _, device := range []topo.ID{"d1", "d2", "d3"} {
+		waitForRelations(t, store, device, 2, 0)
+		waitForRelations(t, store, device+"/1", 1, 1)
+		waitForRelations(t, store, device+"/2", 1, 1)
+	}
+}
+
+func testPaths(t *testing.T, newStore func() Store) {
+	store := newStore()
+	createLinkedDevices(t, store)
+
+	direct := Path{
+		EntityIDs:   []topo.ID{"d1", "d1/1", "L12", "d2/1", "d2"},
+		RelationIDs: []topo.ID{"d1-has-d1/1", "d1/1-originates-L12", "d2/1-terminates-L12", "d2-has-d2/1"},
+	}
+	indirect := Path{
+		EntityIDs: []topo.ID{"d1", "d1/2", "L13", "d3/1", "d3", "d3/2", "L32", "d2/2", "d2"},
+		RelationIDs: []topo.ID{"d1-has-d1/2", "d1/2-originates-L13", "d3/1-terminates-L13", "d3-has-d3/1",
+			"d3-has-d3/2", "d3/2-originates-L32", "d2/2-terminates-L32", "d2-has-d2/2"},
+	}
+
+	// Without weights, paths must be ordered by their number of hops
+	paths, err := store.Paths(context.TODO(), PathQuery{
+		Src:           "d1",
+		Dst:           "d2",
+		RelationKinds: []topo.ID{"has", "originates", "terminates"},
+		Direction:     DirectionBoth,
+		K:             3,
+	})
+	assert.NoError(t, err)
+	direct.Weight, indirect.Weight = 4, 8
+	assert.Equal(t, []Path{direct, indirect}, paths)
+
+	// Weights must be taken from the relations or the entities they reach
+	paths, err = store.Paths(context.TODO(), PathQuery{
+		Src:       "d1",
+		Dst:       "d2",
+		Direction: DirectionBoth,
+		Weight:    &PathWeight{Label: "latency"},
+		K:         2,
+	})
+	assert.NoError(t, err)
+	direct.Weight, indirect.Weight = 13, 8
+	assert.Equal(t, []Path{indirect, direct}, paths)
+
+	// A single path must be returned by default
+	paths, err = store.Paths(context.TODO(), PathQuery{Src: "d1", Dst: "d2", Direction: DirectionBoth})
+	assert.NoError(t, err)
+	direct.Weight = 4
+	assert.Equal(t, []Path{direct}, paths)
+
+	// Paths must only follow the allowed relation kinds in the requested direction
+	paths, err = store.Paths(context.TODO(), PathQuery{
+		Src:           "d1",
+		Dst:           "d2",
+		RelationKinds: []topo.ID{"has", "originates"},
+		Direction:     DirectionBoth,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, paths, 0)
+	paths, err = store.Paths(context.TODO(), PathQuery{Src: "d1", Dst: "d2"})
+	assert.NoError(t, err)
+	assert.Len(t, paths, 0)
+
+	// Invalid queries must be rejected
+	_, err = store.Paths(context.TODO(), PathQuery{Src: "d1", Dst: "d4"})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = store.Paths(context.TODO(), PathQuery{Src: "d1"})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.Paths(context.TODO(), PathQuery{Src: "d1", Dst: "d2", K: -1})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.Paths(context.TODO(), PathQuery{Src: "d1", Dst: "d2", K: MaxPaths + 1})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.Paths(context.TODO(), PathQuery{Src: "d1", Dst: "d2", Weight: &PathWeight{Aspect: "onos.topo.Location"}})
+	assert.True(t, errors.IsInvalid(err))
+}
+
+func TestPathVisits(t *testing.T) {
+	// The path between the ends of a chain of entities longer than the visit limit must not be searched for
+	length := 2 * MaxPathVisits
+	gets := 0
+	get := func(ctx context.Context, id topo.ID, opts ...ReadOption) (*topo.Object, error) {
+		gets++
+		var kind string
+		var i int
+		if _, err := fmt.Sscanf(string(id), "%1s-%d", &kind, &i); err != nil || i < 0 || i >= length {
+			return nil, errors.NewNotFound("Object '%s' not found", id)
+		}
+		if kind == "r" {
+			return &topo.Object{
+				ID:   id,
+				Type: topo.Object_RELATION,
+				Obj: &topo.Object_Relation{Relation: &topo.Relation{
+					SrcEntityID: topo.ID(fmt.Sprintf("e-%d", i)),
+					TgtEntityID: topo.ID(fmt.Sprintf("e-%d", i+1)),
+				}},
+			}, nil
+		}
+		return &topo.Object{
+			ID:   id,
+			Type: topo.Object_ENTITY,
+			Obj: &topo.Object_Entity{Entity: &topo.Entity{
+				SrcRelationIDs: []topo.ID{topo.ID(fmt.Sprintf("r-%d", i))},
+			}},
+		}, nil
+	}
+	_, err := shortestPaths(context.TODO(), get, PathQuery{Src: "e-0", Dst: topo.ID(fmt.Sprintf("e-%d", length-1)), K: MaxPaths})
+	assert.True(t, errors.IsInvalid(err))
+	assert.Less(t, gets, 3*MaxPathVisits)
+
+	// Paths within the visit limit must be found
+	paths, err := shortestPaths(context.TODO(), get, PathQuery{Src: "e-0", Dst: "e-10"})
+	assert.NoError(t, err)
+	assert.Len(t, paths, 1)
+	assert.Len(t, paths[0].RelationIDs, 10)
+}
+
+func TestObjectWeight(t *testing.T) {
+	object := &topo.Object{ID: "L12"}
+	assert.NoError(t, object.SetAspectBytes("onos.topo.LinkStats", []byte(`{"stats": {"latency": "5", "loss": 0.5, "name": "x"}}`)))
+
+	weight, ok, err := objectWeight(object, &PathWeight{Aspect: "onos.topo.LinkStats", Field: "stats.latency"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 5.0, weight)
+	weight, ok, err = objectWeight(object, &PathWeight{Aspect: "onos.topo.LinkStats", Field: "stats.loss"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, weight)
+
+	_, ok, err = objectWeight(object, &PathWeight{Aspect: "onos.topo.LinkStats", Field: "stats.jitter"})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = objectWeight(object, &PathWeight{Aspect: "onos.topo.Location", Field: "lat"})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, _, err = objectWeight(object, &PathWeight{Aspect: "onos.topo.LinkStats", Field: "stats.name"})
+	assert.True(t, errors.IsInvalid(err))
+
+	object.Labels = map[string]string{"latency": "-1"}
+	_, _, err = objectWeight(object, &PathWeight{Label: "latency"})
+	assert.True(t, errors.IsInvalid(err))
+}
@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+type relationMaps struct {
+	// map of entity IDs to list of relations where that entity is a source of the relation
+	sources map[topoapi.ID][]topoapi.ID
+	// map of entity IDs to list of relations where that entity is a target of the relation
+	targets map[topoapi.ID][]topoapi.ID
+	lock    sync.RWMutex
+}
+
+func newRelationMaps() relationMaps {
+	return relationMaps{
+		targets: make(map[topoapi.ID][]topoapi.ID),
+		sources: make(map[topoapi.ID][]topoapi.ID),
+		lock:    sync.RWMutex{},
+	}
+}
+
+// addSrcTgts populates the relation IDs of the given entity with copies of its entries in the relation maps
+func (r *relationMaps) addSrcTgts(obj *topoapi.Object) {
+	if obj.GetEntity() != nil {
+		r.lock.RLock()
+		defer r.lock.RUnlock()
+		obj.GetEntity().SrcRelationIDs = copyIDs(r.sources[obj.ID])
+		obj.GetEntity().TgtRelationIDs = copyIDs(r.targets[obj.ID])
+	}
+}
+
+// register adds the given relation to the source and target maps of its entities
+func (r *relationMaps) register(obj *topoapi.Object) {
+	if relation := obj.GetRelation(); relation != nil {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.sources[relation.SrcEntityID] = add(r.sources[relation.SrcEntityID], obj.ID)
+		r.targets[relation.TgtEntityID] = add(r.targets[relation.TgtEntityID], obj.ID)
+	}
+}
+
+// unregister removes the given relation from the source and target maps of its entities. The entries of an
+// entity are only removed with its last relation, so relations left behind by a deleted entity can be found.
+func (r *relationMaps) unregister(obj *topoapi.Object) {
+	if relation := obj.GetRelation(); relation != nil {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		unregisterID(r.sources, relation.SrcEntityID, obj.ID)
+		unregisterID(r.targets, relation.TgtEntityID, obj.ID)
+	}
+}
+
+// reindex moves an updated relation from the entries of its previous endpoints to those of its new endpoints.
+// Relations whose endpoints did not change keep their position in the maps.
+func (r *relationMaps) reindex(previous, obj *topoapi.Object) {
+	if !endpointsChanged(previous, obj) {
+		return
+	}
+	r.unregister(previous)
+	r.register(obj)
+}
+
+// endpointsChanged returns whether the source or target of a relation differ between two versions of an object
+func endpointsChanged(previous, obj *topoapi.Object) bool {
+	prev, next := previous.GetRelation(), obj.GetRelation()
+	if prev == nil || next == nil {
+		return prev != next
+	}
+	return prev.SrcEntityID != next.SrcEntityID || prev.TgtEntityID != next.TgtEntityID
+}
+
+// related returns the IDs of the relations of which the given entity is a source or a target
+func (r *relationMaps) related(id topoapi.ID) []topoapi.ID {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	relationIDs := make([]topoapi.ID, 0, len(r.sources[id])+len(r.targets[id]))
+	sources := make(idSet, len(r.sources[id]))
+	for _, rid := range r.sources[id] {
+		sources[rid] = struct{}{}
+		relationIDs = append(relationIDs, rid)
+	}
+	for _, rid := range r.targets[id] {
+		// relations from an entity to itself are both sources and targets
+		if _, ok := sources[rid]; !ok {
+			relationIDs = append(relationIDs, rid)
+		}
+	}
+	return relationIDs
+}
+
+// getFunc retrieves a single object, populating the relation IDs of entities
+type getFunc func(ctx context.Context, id topoapi.ID, opts ...ReadOption) (*topoapi.Object, error)
+
+func listRelationFilter(ctx context.Context, get getFunc, filters *topoapi.Filters, opts ...ReadOption) ([]topoapi.Object, error) {
+	anchor, inbound, err := relationAnchor(filters.RelationFilter)
+	if err != nil {
+		return nil, err
+	}
+	return filterRelationEntities(ctx, get, anchor, filters, inbound, opts...)
+}
+
+// relationAnchor returns the ID of the entity whose relations are selected by a relation filter, and whether the
+// entity is the target rather than the source of the relations
+func relationAnchor(filter *topoapi.RelationFilter) (topoapi.ID, bool, error) {
+	if len(filter.GetSrcId()) > 0 {
+		return topoapi.ID(filter.GetSrcId()), false, nil
+	} else if len(filter.GetTargetId()) > 0 {
+		return topoapi.ID(filter.GetTargetId()), true, nil
+	}
+	return "", false, errors.NewInvalid("filter must contain either srcID or targetID")
+}
+
+func filterRelationEntities(ctx context.Context, get getFunc, id topoapi.ID, filters *topoapi.Filters, useSrc bool, opts ...ReadOption) ([]topoapi.Object, error) {
+	results := make([]topoapi.Object, 0)
+	obj, err := get(ctx, id, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rfilter := filters.RelationFilter
+	if scopeIncludesSource(rfilter.Scope) {
+		results = append(results, *obj)
+	}
+
+	relations := obj.GetEntity().SrcRelationIDs
+	if useSrc {
+		relations = obj.GetEntity().TgtRelationIDs
+	}
+
+	for _, rid := range relations {
+		robj, err := get(ctx, rid, opts...)
+		if err == nil && robj.Type == topoapi.Object_RELATION {
+			if matchRelationKind(robj, rfilter) {
+				ent, err := get(ctx, relatedEntityID(robj, useSrc), opts...)
+				if err == nil && matchRelatedEntity(ent, filters) {
+					if scopeIncludesRelations(rfilter.Scope) {
+						results = append(results, *robj)
+					}
+
+					if scopeIncludesTargets(rfilter.Scope) {
+						results = append(results, *ent)
+					}
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// scopeIncludesSource returns whether the anchor entity of a relation filter is selected by the given scope
+func scopeIncludesSource(scope topoapi.RelationFilterScope) bool {
+	return scope == topoapi.RelationFilterScope_ALL || scope == topoapi.RelationFilterScope_SOURCE_AND_TARGETS
+}
+
+// scopeIncludesRelations returns whether the relations of a relation filter are selected by the given scope
+func scopeIncludesRelations(scope topoapi.RelationFilterScope) bool {
+	return scope == topoapi.RelationFilterScope_ALL ||
+		scope == topoapi.RelationFilterScope_RELATIONS_ONLY ||
+		scope == topoapi.RelationFilterScope_RELATIONS_AND_TARGETS
+}
+
+// scopeIncludesTargets returns whether the related entities of a relation filter are selected by the given scope
+func scopeIncludesTargets(scope topoapi.RelationFilterScope) bool {
+	return scope != topoapi.RelationFilterScope_RELATIONS_ONLY
+}
+
+// matchRelationKind returns whether the given relation has the relation kind of a relation filter
+func matchRelationKind(relation *topoapi.Object, filter *topoapi.RelationFilter) bool {
+	return len(filter.RelationKind) == 0 || string(relation.GetRelation().GetKindID()) == filter.RelationKind
+}
+
+// matchRelatedEntity returns whether an entity related to the anchor of a relation filter is selected by the filters
+func matchRelatedEntity(entity *topoapi.Object, filters *topoapi.Filters) bool {
+	return (len(filters.RelationFilter.TargetKind) == 0 || string(entity.GetEntity().GetKindID()) == filters.RelationFilter.TargetKind) &&
+		matchAspects(entity, filters.WithAspects)
+}
+
+// relatedEntityID returns the entity at the other end of a relation from the anchor of a relation filter
+func relatedEntityID(relation *topoapi.Object, inbound bool) topoapi.ID {
+	if inbound {
+		return relation.GetRelation().GetSrcEntityID()
+	}
+	return relation.GetRelation().GetTgtEntityID()
+}
+
+func add(ids []topoapi.ID, id topoapi.ID) []topoapi.ID {
+	for _, eid := range ids {
+		if eid == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func unregisterID(relations map[topoapi.ID][]topoapi.ID, entityID topoapi.ID, id topoapi.ID) {
+	if ids := remove(relations[entityID], id); len(ids) > 0 {
+		relations[entityID] = ids
+	} else {
+		delete(relations, entityID)
+	}
+}
+
+func copyIDs(ids []topoapi.ID) []topoapi.ID {
+	if ids == nil {
+		return nil
+	}
+	return append(make([]topoapi.ID, 0, len(ids)), ids...)
+}
+
+func remove(ids []topoapi.ID, id topoapi.ID) []topoapi.ID {
+	for i, eid := range ids {
+		if eid == id {
+			ids[i] = ids[len(ids)-1]
+			return ids[:len(ids)-1]
+		}
+	}
+	return ids
+}
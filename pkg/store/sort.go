@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+const (
+	// SortByID sorts objects by their ID
+	SortByID = "id"
+	// SortByKind sorts entities and relations by their kind ID
+	SortByKind = "kind"
+	// SortByType sorts objects by their type, i.e. entities, then relations, then kinds
+	SortByType = "type"
+	// SortByRevision sorts objects by their revision
+	SortByRevision = "revision"
+	// sortByLabelPrefix is the prefix of the fields sorting objects by the value of a label
+	sortByLabelPrefix = "label:"
+)
+
+// SortKey is a key by which objects are sorted
+type SortKey struct {
+	// Field is the field to sort by: SortByID, SortByKind, SortByType, SortByRevision, a label as "label:<key>",
+	// or an aspect field as "aspect:<aspect type>/<field path>"
+	Field string
+	// Descending sorts the field in descending order
+	Descending bool
+}
+
+// ParseSortKeys parses a comma-separated list of fields to sort by, each of them prefixed by '-' to sort it in
+// descending order, e.g. "kind,-label:rack,aspect:onos.topo.PortInfo/number"
+func ParseSortKeys(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, field := range strings.Split(spec, ",") {
+		key := SortKey{Field: strings.TrimSpace(field)}
+		if strings.HasPrefix(key.Field, "-") {
+			key.Field, key.Descending = key.Field[1:], true
+		}
+		switch {
+		case key.Field == SortByID, key.Field == SortByKind, key.Field == SortByType, key.Field == SortByRevision:
+		case strings.HasPrefix(key.Field, sortByLabelPrefix) && len(key.Field) > len(sortByLabelPrefix):
+		case strings.HasPrefix(key.Field, aspectKeyPrefix):
+			if aspectType, path, _ := parseAspectField(key.Field); aspectType == "" || path == "" {
+				return nil, errors.NewInvalid("invalid sort field '%s': expected '%s<aspect type>/<field path>'", key.Field, aspectKeyPrefix)
+			}
+		default:
+			return nil, errors.NewInvalid("invalid sort field '%s'", field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortValue is the value of a sort field of an object
+type sortValue struct {
+	present bool
+	numeric bool
+	number  float64
+	text    string
+}
+
+// compare orders missing values after present values, and numbers before other values
+func (v sortValue) compare(other sortValue) int {
+	switch {
+	case v.present != other.present:
+		if v.present {
+			return -1
+		}
+		return 1
+	case v.numeric && other.numeric:
+		if v.number < other.number {
+			return -1
+		} else if v.number > other.number {
+			return 1
+		}
+		return 0
+	case v.numeric != other.numeric:
+		if v.numeric {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(v.text, other.text)
+}
+
+func textValue(text string) sortValue {
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		return sortValue{present: true, numeric: true, number: number, text: text}
+	}
+	return sortValue{present: true, text: text}
+}
+
+// objectSortValue returns the value of the given sort field of an object
+func objectSortValue(object *topoapi.Object, field string) sortValue {
+	switch field {
+	case SortByID:
+		return sortValue{present: true, text: string(object.ID)}
+	case SortByKind:
+		if kindID := kindID(object); kindID != topoapi.NullID {
+			return sortValue{present: true, text: string(kindID)}
+		}
+		return sortValue{}
+	case SortByType:
+		return sortValue{present: true, numeric: true, number: float64(object.Type)}
+	case SortByRevision:
+		return sortValue{present: true, numeric: true, number: float64(object.Revision)}
+	}
+	key := strings.TrimPrefix(field, sortByLabelPrefix)
+	if strings.HasPrefix(field, aspectKeyPrefix) {
+		key = filterKeyPrefix + field
+	}
+	if value, _, ok := labelValue(object, key); ok {
+		return textValue(value)
+	}
+	return sortValue{}
+}
+
+// SortObjects sorts the given objects by the given keys in order; missing labels and aspect fields are sorted last
+// in both directions, and values are compared as numbers if both are numeric
+func SortObjects(objects []topoapi.Object, keys []SortKey) {
+	values := make([][]sortValue, len(objects))
+	for i := range objects {
+		values[i] = make([]sortValue, len(keys))
+		for j, key := range keys {
+			values[i][j] = objectSortValue(&objects[i], key.Field)
+		}
+	}
+	sort.Sort(objectSorter{objects: objects, values: values, keys: keys})
+}
+
+// objectSorter sorts objects along with their sort values
+type objectSorter struct {
+	objects []topoapi.Object
+	values  [][]sortValue
+	keys    []SortKey
+}
+
+func (s objectSorter) Len() int {
+	return len(s.objects)
+}
+
+func (s objectSorter) Less(i, j int) bool {
+	for k, key := range s.keys {
+		a, b := s.values[i][k], s.values[j][k]
+		c := a.compare(b)
+		if key.Descending && a.present && b.present {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+func (s objectSorter) Swap(i, j int) {
+	s.objects[i], s.objects[j] = s.objects[j], s.objects[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
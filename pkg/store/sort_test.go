@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"testing"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortObjects(t *testing.T) {
+	port := func(id topo.ID, revision topo.Revision, rack string, number string) topo.Object {
+		object := topo.Object{
+			ID:       id,
+			Revision: revision,
+			Type:     topo.Object_ENTITY,
+			Obj:      &topo.Object_Entity{Entity: &topo.Entity{KindID: "port"}},
+		}
+		if rack != "" {
+			object.Labels = map[string]string{"rack": rack}
+		}
+		if number != "" {
+			assert.NoError(t, object.SetAspectBytes("onos.topo.PortInfo", []byte(`{"number": `+number+`}`)))
+		}
+		return object
+	}
+	objects := []topo.Object{
+		port("p1", 5, "r10", "10"),
+		port("p2", 4, "r9", "9"),
+		port("p3", 3, "", "2"),
+		port("p4", 2, "r10", ""),
+		{ID: "switch", Revision: 1, Type: topo.Object_KIND, Obj: &topo.Object_Kind{Kind: &topo.Kind{Name: "switch"}}},
+		{ID: "l1", Revision: 6, Type: topo.Object_RELATION, Obj: &topo.Object_Relation{Relation: &topo.Relation{KindID: "link"}}},
+	}
+	ids := func(keys ...SortKey) []topo.ID {
+		SortObjects(objects, append(keys, SortKey{Field: SortByID}))
+		result := make([]topo.ID, 0, len(objects))
+		for _, object := range objects {
+			result = append(result, object.ID)
+		}
+		return result
+	}
+
+	assert.Equal(t, []topo.ID{"switch", "p4", "p3", "p2", "p1", "l1"}, ids(SortKey{Field: SortByRevision}))
+	assert.Equal(t, []topo.ID{"l1", "p1", "p2", "p3", "p4", "switch"}, ids(SortKey{Field: SortByKind}))
+	assert.Equal(t, []topo.ID{"switch", "l1", "p1", "p2", "p3", "p4"}, ids(SortKey{Field: SortByType, Descending: true}))
+
+	// Missing values must be sorted last in both directions, and numeric values must be compared as numbers
+	assert.Equal(t, []topo.ID{"p3", "p2", "p1", "l1", "p4", "switch"}, ids(SortKey{Field: "aspect:onos.topo.PortInfo/number"}))
+	assert.Equal(t, []topo.ID{"p1", "p2", "p3", "l1", "p4", "switch"}, ids(SortKey{Field: "aspect:onos.topo.PortInfo/number", Descending: true}))
+	assert.Equal(t, []topo.ID{"p1", "p4", "p2", "l1", "p3", "switch"}, ids(SortKey{Field: "label:rack"}))
+
+	// Later keys must break the ties of earlier keys
+	assert.Equal(t, []topo.ID{"p4", "p1", "p2", "switch", "p3", "l1"}, ids(SortKey{Field: "label:rack"}, SortKey{Field: SortByRevision}))
+}
+
+func TestParseSortKeys(t *testing.T) {
+	keys, err := ParseSortKeys("kind, -label:rack,aspect:onos.topo.PortInfo/number,-revision")
+	assert.NoError(t, err)
+	assert.Equal(t, []SortKey{
+		{Field: SortByKind},
+		{Field: "label:rack", Descending: true},
+		{Field: "aspect:onos.topo.PortInfo/number"},
+		{Field: SortByRevision, Descending: true},
+	}, keys)
+
+	for _, spec := range []string{"", "name", "label:", "aspect:onos.topo.PortInfo", "kind,,id"} {
+		_, err := ParseSortKeys(spec)
+		assert.True(t, errors.IsInvalid(err), spec)
+	}
+}
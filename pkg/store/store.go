@@ -23,7 +23,9 @@ import (
 var log = logging.GetLogger()
 
 // NewAtomixStore returns a new persistent Store
-func NewAtomixStore(client primitive.Client) (Store, error) {
+func NewAtomixStore(client primitive.Client, opts ...StoreOption) (Store, error) {
+	storeOpts := newStoreOptions(opts)
+
 	objects, err := _map.NewBuilder[topoapi.ID, *topoapi.Object](client, "onos-topo-objects").
 		Tag("onos-topo", "objects").
 		Codec(types.Proto[*topoapi.Object](&topoapi.Object{})).
@@ -32,16 +34,25 @@ func NewAtomixStore(client primitive.Client) (Store, error) {
 		return nil, errors.FromAtomix(err)
 	}
 
+	watchers := newWatchers(storeOpts.journalSize)
 	store := &atomixStore{
-		objects:  objects,
-		cache:    make(map[topoapi.ID]topoapi.Object),
-		watchers: make(map[uuid.UUID]chan<- topoapi.Event),
-		relations: relationMaps{
-			targets: make(map[topoapi.ID][]topoapi.ID),
-			sources: make(map[topoapi.ID][]topoapi.ID),
-			lock:    sync.RWMutex{},
-		},
+		objects:          objects,
+		cachedReads:      storeOpts.cachedReads,
+		indexedReads:     storeOpts.indexedReads,
+		cache:            make(map[topoapi.ID]topoapi.Object),
+		versions:         make(map[topoapi.ID]topoapi.Revision),
+		pending:          make(map[topoapi.ID]topoapi.Revision),
+		pendingRelations: make(map[topoapi.ID][2]topoapi.ID),
+		unsent:           make(map[topoapi.ID]int),
+		evicted:          make(map[topoapi.ID]topoapi.Revision),
+		maxRemoved:       storeOpts.journalSize,
+		watchers:         watchers,
+		transactions:     newTransactionEvents(watchers),
+		relations:        newRelationMaps(),
+		index:            newObjectIndex(),
+		applied:          make(chan struct{}),
 	}
+	watchers.sent = store.sent
 
 	// watch the atomixStore for changes
 	// when relations are deleted, remove the implied relations from the store target asnd source maps
@@ -56,7 +67,10 @@ func NewAtomixStore(client primitive.Client) (Store, error) {
 	if err != nil {
 		return nil, errors.FromAtomix(err)
 	}
-	go store.watchStoreEvents(entries, events)
+	// load the existing objects before returning, so that cached reads and the event journal start from
+	// a complete view of the store
+	store.loadStoreEntries(entries)
+	go store.watchStoreEvents(events)
 	return store, nil
 }
 
@@ -71,19 +85,45 @@ type Store interface {
 	Update(ctx context.Context, object *topoapi.Object) error
 
 	// Get retrieves an object from the store
-	Get(ctx context.Context, id topoapi.ID) (*topoapi.Object, error)
+	Get(ctx context.Context, id topoapi.ID, opts ...ReadOption) (*topoapi.Object, error)
 
 	// Delete deletes a object from the store
 	Delete(ctx context.Context, id topoapi.ID, revision topoapi.Revision) error
 
-	// DEPRECATED: List returns an array of objects
-	List(ctx context.Context, filters *topoapi.Filters) ([]topoapi.Object, error)
+	// Transaction applies the given operations atomically; either all operations succeed or none is applied.
+	// Watchers receive the events of the changes as a group marked with the TransactionGroupAspect. Atomix only
+	// delivers the events of a transaction separately, so the Atomix store groups the events of the transactions
+	// committed through it; the events of transactions committed through other replicas are sent individually.
+	Transaction(ctx context.Context, operations ...Operation) error
+
+	// DEPRECATED: List returns an array of objects. Lists from the Atomix store reflect all the changes committed to
+	// Atomix, unless the store is created WithCachedReads or WithIndexedReads: List then reads the local replica
+	// of the store, which reflects the writes made through the same store, but may not yet reflect changes made
+	// through other replicas unless WithMinRevision is given. Query and Count have the same consistency.
+	List(ctx context.Context, filters *topoapi.Filters, opts ...ReadOption) ([]topoapi.Object, error)
+
+	// Query streams objects to the given channel, closing it once all objects have been sent or an error occurs
+	Query(ctx context.Context, ch chan<- *topoapi.Object, filters *topoapi.Filters, opts ...ReadOption) error
+
+	// Traverse streams the objects reached by a multi-hop traversal of relations to the given channel, and returns
+	// ErrTraversalTruncated if the traversal stops at its limits
+	Traverse(ctx context.Context, ch chan<- *topoapi.Object, traversal Traversal, opts ...ReadOption) error
+
+	// Paths returns the shortest paths between two entities
+	Paths(ctx context.Context, query PathQuery, opts ...ReadOption) ([]Path, error)
+
+	// Locate returns the objects whose location matches a geo query
+	Locate(ctx context.Context, query GeoQuery, opts ...ReadOption) ([]Located, error)
 
-	// Query streams objects to the given channel
-	Query(ctx context.Context, ch chan<- *topoapi.Object, filters *topoapi.Filters) error
+	// Count returns the number of objects matching the given filters, grouped by the given fields
+	Count(ctx context.Context, filters *topoapi.Filters, groupBy []string, opts ...ReadOption) ([]GroupCount, error)
 
 	// Watch streams object events to the given channel
 	Watch(ctx context.Context, ch chan<- topoapi.Event, filters *topoapi.Filters, opts ...WatchOption) error
+
+	// WatchStats returns the event queue statistics of the active watchers, and the number of events coalesced by
+	// the watchers of the store
+	WatchStats() WatchStats
 }
 
 // WatchOption is a configuration option for Watch calls
@@ -105,33 +145,338 @@ func WithReplay() WatchOption {
 	return watchReplayOption{true}
 }
 
+// OverflowPolicy determines how a watcher is handled when its event queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowDrop closes a watch whose queue is full with an Unavailable error
+	OverflowDrop OverflowPolicy = iota
+	// OverflowCoalesce merges the events of a watch into the queued events for the same object, so that a slow
+	// watcher skips intermediate changes but eventually observes the latest state. Once the queue is full, events
+	// for objects that are not queued cannot be merged, and the watch is then closed as by OverflowResync.
+	OverflowCoalesce
+	// OverflowResync closes a watch whose queue is full with a Conflict error, as the watcher has missed changes
+	// and must list the topology again, e.g. by watching it again WithReplay
+	OverflowResync
+)
+
+// String returns the name of the overflow policy
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDrop:
+		return "drop"
+	case OverflowCoalesce:
+		return "coalesce"
+	case OverflowResync:
+		return "resync"
+	}
+	return "unknown"
+}
+
+// ParseOverflowPolicy returns the overflow policy with the given name
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch name {
+	case OverflowDrop.String():
+		return OverflowDrop, nil
+	case OverflowCoalesce.String():
+		return OverflowCoalesce, nil
+	case OverflowResync.String():
+		return OverflowResync, nil
+	}
+	return OverflowDrop, errors.NewInvalid("unknown overflow policy '%s'", name)
+}
+
+// watchQueueOption is an option to bound the event queue of a watcher
+type watchQueueOption struct {
+	size     int
+	overflow OverflowPolicy
+}
+
+func (o watchQueueOption) apply(opts *watchOptions) {
+	opts.queueSize = o.size
+	opts.overflow = o.overflow
+}
+
+// WithQueue returns a WatchOption that queues up to the given number of events for a watcher that is not
+// keeping up, and applies the given overflow policy once the queue is full
+func WithQueue(size int, overflow OverflowPolicy) WatchOption {
+	return watchQueueOption{size: size, overflow: overflow}
+}
+
+// watchErrorOption is an option to report the error closing a watch
+type watchErrorOption struct {
+	onError func(error)
+}
+
+func (o watchErrorOption) apply(opts *watchOptions) {
+	opts.onError = o.onError
+}
+
+// WithErrorHandler returns a WatchOption that calls the given function with the error when the store closes a
+// watch before its context is done, e.g. when the watcher is dropped for falling behind
+func WithErrorHandler(onError func(error)) WatchOption {
+	return watchErrorOption{onError: onError}
+}
+
+// watchResumeOption is an option to resume a watch from the journal
+type watchResumeOption struct {
+	revision topoapi.Revision
+}
+
+func (o watchResumeOption) apply(opts *watchOptions) {
+	opts.resume = true
+	opts.resumeAfter = o.revision
+}
+
+// WithResumeAfter returns a WatchOption that replays the journaled events following the given revision, i.e.
+// the revision of the last event observed by a watcher before it reconnected, instead of the current state.
+// Revisions of the Atomix store are only ordered per partition, so events the watcher has already observed
+// may be delivered again. If the events following the revision are no longer in the journal, Watch fails with
+// a Conflict error and the watcher must list the topology again. Watches with a relation filter cannot be resumed.
+func WithResumeAfter(revision topoapi.Revision) WatchOption {
+	return watchResumeOption{revision: revision}
+}
+
+// watchCoalesceOption is an option to coalesce the events of a watch
+type watchCoalesceOption struct {
+	window time.Duration
+}
+
+func (o watchCoalesceOption) apply(opts *watchOptions) {
+	opts.coalesceWindow = o.window
+}
+
+// WithCoalesceWindow returns a WatchOption that holds each event for the given window before it is sent, merging
+// the later events for the same object into it, so that the watcher receives a single event carrying the latest
+// state of the object. An object added and removed within the window is not sent at all.
+func WithCoalesceWindow(window time.Duration) WatchOption {
+	return watchCoalesceOption{window: window}
+}
+
+// watchRateOption is an option to limit the rate of the events of a watch
+type watchRateOption struct {
+	maxRate float64
+}
+
+func (o watchRateOption) apply(opts *watchOptions) {
+	opts.maxRate = o.maxRate
+}
+
+// WithMaxEventRate returns a WatchOption that sends at most the given number of events per second to the watcher.
+// Events queued while the watcher is rate limited are coalesced like events within a coalescing window.
+func WithMaxEventRate(maxRate float64) WatchOption {
+	return watchRateOption{maxRate: maxRate}
+}
+
+// watchChangesOption is an option to describe the changes to objects in watch events
+type watchChangesOption struct{}
+
+func (o watchChangesOption) apply(opts *watchOptions) {
+	opts.changes = true
+}
+
+// WithChanges returns a WatchOption that adds a ChangesAspect to the UPDATED and REMOVED events of the watch,
+// describing how the labels, aspects and kind, source or target of the object changed since its previous state.
+// Coalesced events describe the changes since the state last delivered to the watcher.
+func WithChanges() WatchOption {
+	return watchChangesOption{}
+}
+
+// watchBookmarksOption is an option to mark the end of the replay of a watch
+type watchBookmarksOption struct{}
+
+func (o watchBookmarksOption) apply(opts *watchOptions) {
+	opts.bookmarks = true
+}
+
+// WithBookmarks returns a WatchOption that sends a BookmarkSynced event once the objects or journaled events
+// replayed to the watcher have been sent, so that the watcher knows when its view of the topology is complete
+func WithBookmarks() WatchOption {
+	return watchBookmarksOption{}
+}
+
+// watchHeartbeatOption is an option to send periodic heartbeats to a watch
+type watchHeartbeatOption struct {
+	interval time.Duration
+}
+
+func (o watchHeartbeatOption) apply(opts *watchOptions) {
+	opts.heartbeat = o.interval
+}
+
+// WithHeartbeat returns a WatchOption that queues a BookmarkHeartbeat event for the watcher at the given interval,
+// carrying the revision of the store, so that idle watchers can detect a dead stream and track their progress
+func WithHeartbeat(interval time.Duration) WatchOption {
+	return watchHeartbeatOption{interval: interval}
+}
+
 type watchOptions struct {
-	replay bool
+	replay         bool
+	resume         bool
+	resumeAfter    topoapi.Revision
+	queueSize      int
+	overflow       OverflowPolicy
+	onError        func(error)
+	coalesceWindow time.Duration
+	maxRate        float64
+	changes        bool
+	bookmarks      bool
+	heartbeat      time.Duration
+}
+
+// ReadOption is a configuration option for Get, List and Query calls
+type ReadOption interface {
+	applyRead(*readOptions)
+}
+
+// readMinRevisionOption is an option to bound the staleness of reads
+type readMinRevisionOption struct {
+	id       topoapi.ID
+	revision topoapi.Revision
+}
+
+func (o readMinRevisionOption) applyRead(opts *readOptions) {
+	if opts.minRevisions == nil {
+		opts.minRevisions = make(map[topoapi.ID]topoapi.Revision)
+	}
+	if o.revision > opts.minRevisions[o.id] {
+		opts.minRevisions[o.id] = o.revision
+	}
+}
+
+// WithMinRevision returns a ReadOption that waits until the store has observed the given revision of an object
+// before reading; it allows callers to read their own writes when reads are served from a cache. Object revisions
+// are not ordered across objects, so the option may be given once for each object written.
+func WithMinRevision(id topoapi.ID, revision topoapi.Revision) ReadOption {
+	return readMinRevisionOption{id: id, revision: revision}
+}
+
+type readOptions struct {
+	minRevisions map[topoapi.ID]topoapi.Revision
+}
+
+func newReadOptions(opts []ReadOption) readOptions {
+	var readOpts readOptions
+	for _, opt := range opts {
+		opt.applyRead(&readOpts)
+	}
+	return readOpts
+}
+
+// StoreOption is a configuration option for a Store
+type StoreOption interface {
+	apply(*storeOptions)
+}
+
+// storeCachedReadsOption is an option to serve reads from the local cache
+type storeCachedReadsOption struct {
+	cachedReads bool
+}
+
+func (o storeCachedReadsOption) apply(opts *storeOptions) {
+	opts.cachedReads = o.cachedReads
+}
+
+// WithCachedReads returns a StoreOption that serves Get, List and Query calls from the local replica of the
+// store rather than from Atomix. Cached reads always reflect the writes made through the same store; use
+// WithMinRevision to read changes made through other replicas.
+func WithCachedReads() StoreOption {
+	return storeCachedReadsOption{true}
+}
+
+// storeIndexedReadsOption is an option to narrow reads with the local indexes
+type storeIndexedReadsOption struct {
+	indexedReads bool
+}
+
+func (o storeIndexedReadsOption) apply(opts *storeOptions) {
+	opts.indexedReads = o.indexedReads
+}
+
+// WithIndexedReads returns a StoreOption that uses the local indexes of the store to find the objects matching the
+// filters of List, Query and Count calls and reads them from the local replica, rather than scanning the objects in
+// Atomix, whenever the indexes narrow the candidates. Like cached reads, indexed reads reflect the writes made
+// through the same store; use WithMinRevision to read changes made through other replicas.
+func WithIndexedReads() StoreOption {
+	return storeIndexedReadsOption{true}
+}
+
+// storeJournalSizeOption is an option to bound the journal of recent events
+type storeJournalSizeOption struct {
+	size int
+}
+
+func (o storeJournalSizeOption) apply(opts *storeOptions) {
+	opts.journalSize = o.size
+}
+
+// WithJournalSize returns a StoreOption that keeps the given number of recent events for resuming watches
+func WithJournalSize(size int) StoreOption {
+	return storeJournalSizeOption{size}
+}
+
+type storeOptions struct {
+	cachedReads  bool
+	indexedReads bool
+	journalSize  int
+}
+
+func newStoreOptions(opts []StoreOption) storeOptions {
+	storeOpts := storeOptions{
+		journalSize: DefaultJournalSize,
+	}
+	for _, opt := range opts {
+		opt.apply(&storeOpts)
+	}
+	if storeOpts.journalSize < 0 {
+		storeOpts.journalSize = 0
+	}
+	return storeOpts
 }
 
+// indexScanRatio is the minimum ratio of stored objects to index candidates for which a query reads the
+// candidates individually rather than scanning the whole map
+const indexScanRatio = 4
+
 // atomixStore is the object implementation of the Store
 type atomixStore struct {
-	objects    _map.Map[topoapi.ID, *topoapi.Object]
-	cache      map[topoapi.ID]topoapi.Object
+	objects      _map.Map[topoapi.ID, *topoapi.Object]
+	cachedReads  bool
+	indexedReads bool
+	cache        map[topoapi.ID]topoapi.Object
+	// versions is the latest version of each key applied to the cache and indexes, including removed keys, and
+	// pending is the version of each key written by this store that has not been applied yet. Atomix versions
+	// are only ordered per partition, so read-your-writes is tracked per key. applied is closed and replaced
+	// whenever a change is applied.
+	versions map[topoapi.ID]topoapi.Revision
+	pending  map[topoapi.ID]topoapi.Revision
+	applied  chan struct{}
+	// pendingRelations are the endpoints of the pending relations
+	pendingRelations map[topoapi.ID][2]topoapi.ID
+	// unsent is the number of events of each key applied to the cache but not sent to the watchers yet, and
+	// evicted is the version of each key removed by this store whose removal event has not been received yet.
+	// Only the versions of these keys are needed by the snapshots of new watchers to discard the events of
+	// changes already reflected in them.
+	unsent  map[topoapi.ID]int
+	evicted map[topoapi.ID]topoapi.Revision
+	// removed are the keys whose removal has been sent to the watchers, in order. The versions of the oldest are
+	// pruned once there are more than maxRemoved, and pruned is the highest pruned version.
+	removed    []removedKey
+	maxRemoved int
+	pruned     topoapi.Revision
 	cacheMu    sync.RWMutex
 	relations  relationMaps
-	watchers   map[uuid.UUID]chan<- topoapi.Event
-	watchersMu sync.RWMutex
+	watchers   *watchers
+	// transactions sends the events of the store to the watchers, grouping the events of its transactions
+	transactions *transactionEvents
+	index        *objectIndex
 }
 
-type relationMaps struct {
-	// map of entity IDs to list of relations where that entity is a source of the relation
-	sources map[topoapi.ID][]topoapi.ID
-	// map of entity IDs to list of relations where that entity is a target of the relation
-	targets map[topoapi.ID][]topoapi.ID
-	lock    sync.RWMutex
-}
-
-func (s *atomixStore) watchStoreEvents(entries _map.EntryStream[topoapi.ID, *topoapi.Object], events _map.EventStream[topoapi.ID, *topoapi.Object]) {
+func (s *atomixStore) loadStoreEntries(entries _map.EntryStream[topoapi.ID, *topoapi.Object]) {
 	for {
 		entry, err := entries.Next()
 		if err == io.EOF {
-			break
+			return
 		}
 		if err != nil {
 			log.Error(err)
@@ -140,23 +485,16 @@ func (s *atomixStore) watchStoreEvents(entries _map.EntryStream[topoapi.ID, *top
 
 		object := entry.Value
 		object.Revision = topoapi.Revision(entry.Version)
+		s.apply(topoapi.EventType_NONE, object, false)
 
-		s.cacheMu.Lock()
-		s.cache[object.ID] = *object
-		s.cacheMu.Unlock()
-
-		s.registerSrcTgt(object, true)
-
-		s.watchersMu.RLock()
-		for _, watcher := range s.watchers {
-			watcher <- topoapi.Event{
-				Type:   topoapi.EventType_NONE,
-				Object: *object,
-			}
-		}
-		s.watchersMu.RUnlock()
+		s.watchers.send(topoapi.Event{
+			Type:   topoapi.EventType_NONE,
+			Object: *object,
+		}, nil)
 	}
+}
 
+func (s *atomixStore) watchStoreEvents(events _map.EventStream[topoapi.ID, *topoapi.Object]) {
 	for {
 		event, err := events.Next()
 		if err == io.EOF {
@@ -168,48 +506,113 @@ func (s *atomixStore) watchStoreEvents(entries _map.EntryStream[topoapi.ID, *top
 		}
 
 		var eventType topoapi.EventType
-		var object *topoapi.Object
+		var object, previous *topoapi.Object
 		switch e := event.(type) {
 		case *_map.Inserted[topoapi.ID, *topoapi.Object]:
 			object = e.Entry.Value
 			object.Revision = topoapi.Revision(e.Entry.Version)
 			eventType = topoapi.EventType_ADDED
-			s.cacheMu.Lock()
-			s.cache[object.ID] = *object
-			s.cacheMu.Unlock()
-			s.registerSrcTgt(object, true)
 		case *_map.Updated[topoapi.ID, *topoapi.Object]:
 			object = e.Entry.Value
 			object.Revision = topoapi.Revision(e.Entry.Version)
 			eventType = topoapi.EventType_UPDATED
-			s.cacheMu.Lock()
-			s.cache[object.ID] = *object
-			s.cacheMu.Unlock()
+			if e.PrevEntry != nil && e.PrevEntry.Value != nil {
+				previous = e.PrevEntry.Value
+				previous.Revision = topoapi.Revision(e.PrevEntry.Version)
+			}
 		case *_map.Removed[topoapi.ID, *topoapi.Object]:
 			object = e.Entry.Value
 			object.Revision = topoapi.Revision(e.Entry.Version)
 			eventType = topoapi.EventType_REMOVED
-			s.cacheMu.Lock()
-			delete(s.cache, e.Entry.Key)
-			s.cacheMu.Unlock()
-			s.unregisterSrcTgt(object)
+			// The removed entry is the last state of the object, which may already be evicted from the cache
+			removed := *object
+			previous = &removed
+		}
+		if previous == nil && eventType == topoapi.EventType_UPDATED {
+			// Fall back to the state of the object in the cache before it is overwritten
+			s.cacheMu.RLock()
+			if cached, ok := s.cache[object.ID]; ok {
+				previous = &cached
+			}
+			s.cacheMu.RUnlock()
 		}
+		s.apply(eventType, object, true)
 
-		s.watchersMu.RLock()
-		for _, watcher := range s.watchers {
-			watcher <- topoapi.Event{
-				Type:   eventType,
-				Object: *object,
+		s.transactions.send(topoapi.Event{
+			Type:   eventType,
+			Object: *object,
+		}, previous)
+	}
+}
+
+// apply updates the local cache and indexes with a change to the given object; received is true for the changes
+// received from the map events, whose events are then sent to the watchers. Changes older than the version already
+// applied for the object, such as events for entries evicted by this store, are ignored.
+func (s *atomixStore) apply(eventType topoapi.EventType, object *topoapi.Object, received bool) {
+	// check the endpoints of new and re-pointed relations before taking the cache lock, as this may remove a
+	// dangling relation
+	removed := eventType == topoapi.EventType_REMOVED
+	valid := removed
+	if !valid && eventType == topoapi.EventType_UPDATED {
+		s.cacheMu.RLock()
+		previous, cached := s.cache[object.ID]
+		s.cacheMu.RUnlock()
+		valid = cached && !endpointsChanged(&previous, object)
+	}
+	valid = valid || s.validateSrcTgt(object)
+
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	version, ok := s.versions[object.ID]
+	if received {
+		s.unsent[object.ID]++
+		if evicted, ok := s.evicted[object.ID]; ok && removed && evicted <= object.Revision {
+			delete(s.evicted, object.ID)
+		}
+	}
+	// A removal by this store has the version of the last change, which may already have been removed
+	_, cached := s.cache[object.ID]
+	if ok && (version > object.Revision || (version == object.Revision && (!removed || (!received && !cached)))) {
+		return
+	}
+	if removed && !received {
+		s.evicted[object.ID] = object.Revision
+	}
+	s.versions[object.ID] = object.Revision
+	if pending, ok := s.pending[object.ID]; ok && pending <= object.Revision {
+		delete(s.pending, object.ID)
+		delete(s.pendingRelations, object.ID)
+	}
+
+	if removed {
+		delete(s.cache, object.ID)
+		s.relations.unregister(object)
+		s.index.delete(object.ID)
+	} else {
+		if previous, exists := s.cache[object.ID]; !exists {
+			if valid {
+				s.relations.register(object)
 			}
+		} else if valid {
+			s.relations.reindex(&previous, object)
+		} else {
+			s.relations.unregister(&previous)
 		}
-		s.watchersMu.RUnlock()
+		s.cache[object.ID] = *object
+		s.index.update(object)
 	}
+	close(s.applied)
+	s.applied = make(chan struct{})
 }
 
 func (s *atomixStore) Create(ctx context.Context, object *topoapi.Object) error {
 	if object.Type == topoapi.Object_UNSPECIFIED {
 		return errors.NewInvalid("Type cannot be unspecified")
 	}
+	if err := checkReserved(object); err != nil {
+		return err
+	}
 
 	// set a uuid
 	uuid, err := uuid.NewRandom()
@@ -222,23 +625,11 @@ func (s *atomixStore) Create(ctx context.Context, object *topoapi.Object) error
 		if object.ID == "" {
 			object.ID = topoapi.ID("uuid:" + string(object.UUID))
 		}
-		if _, err := s.objects.Get(ctx, object.GetRelation().SrcEntityID); err != nil {
-			err = errors.FromAtomix(err)
-			if !errors.IsNotFound(err) {
+		if err := s.checkEndpoints(ctx, object); err != nil {
+			if !errors.IsInvalid(err) {
 				log.Errorf("Failed to create Object %+v: %v", object, err)
-				return err
 			}
-			log.Warnf("Source Entity does not exist")
-			return errors.NewInvalid("Source Entity does not exist")
-		}
-		if _, err := s.objects.Get(ctx, object.GetRelation().TgtEntityID); err != nil {
-			err = errors.FromAtomix(err)
-			if !errors.IsNotFound(err) {
-				log.Errorf("Failed to create Object %+v: %v", object, err)
-				return err
-			}
-			log.Warnf("Target Entity does not exist")
-			return errors.NewInvalid("Target Entity does not exist")
+			return err
 		}
 	} else if object.ID == "" {
 		return errors.NewInvalid("ID cannot be empty")
@@ -259,6 +650,7 @@ func (s *atomixStore) Create(ctx context.Context, object *topoapi.Object) error
 	}
 
 	object.Revision = topoapi.Revision(entry.Version)
+	s.write(object)
 	return nil
 }
 
@@ -272,6 +664,18 @@ func (s *atomixStore) Update(ctx context.Context, object *topoapi.Object) error
 	if object.Revision == 0 {
 		return errors.NewInvalid("object must contain a revision on update")
 	}
+	if err := checkReserved(object); err != nil {
+		return err
+	}
+
+	if object.Type == topoapi.Object_RELATION && !s.endpointsUnchanged(object) {
+		if err := s.checkEndpoints(ctx, object); err != nil {
+			if !errors.IsInvalid(err) {
+				log.Errorf("Failed to update Object %+v: %v", object, err)
+			}
+			return err
+		}
+	}
 
 	log.Infof("Updating Object %+v", object)
 
@@ -287,14 +691,32 @@ func (s *atomixStore) Update(ctx context.Context, object *topoapi.Object) error
 		return err
 	}
 	object.Revision = topoapi.Revision(entry.Version)
+	s.write(object)
 	return nil
 }
 
-func (s *atomixStore) Get(ctx context.Context, id topoapi.ID) (*topoapi.Object, error) {
+func (s *atomixStore) Get(ctx context.Context, id topoapi.ID, opts ...ReadOption) (*topoapi.Object, error) {
 	if id == "" {
 		return nil, errors.NewInvalid("ID cannot be empty")
 	}
 
+	if s.cachedReads {
+		if err := s.awaitRead(ctx, newReadOptions(opts)); err != nil {
+			return nil, err
+		}
+		s.cacheMu.RLock()
+		cached, ok := s.cache[id]
+		s.cacheMu.RUnlock()
+		if !ok {
+			err := errors.NewNotFound("Object '%s' not found", id)
+			log.Warnf("Failed to get Object '%s': %v", id, err)
+			return nil, err
+		}
+		obj := clone(&cached)
+		s.relations.addSrcTgts(obj)
+		return obj, nil
+	}
+
 	entry, err := s.objects.Get(ctx, id)
 	if err != nil {
 		err = errors.FromAtomix(err)
@@ -307,7 +729,7 @@ func (s *atomixStore) Get(ctx context.Context, id topoapi.ID) (*topoapi.Object,
 	}
 	obj := entry.Value
 	obj.Revision = topoapi.Revision(entry.Version)
-	s.addSrcTgts(obj)
+	s.relations.addSrcTgts(obj)
 	return obj, nil
 }
 
@@ -322,10 +744,11 @@ func (s *atomixStore) Delete(ctx context.Context, id topoapi.ID, revision topoap
 	}
 	log.Infof("Deleting Object '%s'", id)
 
+	var entry *_map.Entry[topoapi.ID, *topoapi.Object]
 	if revision == 0 {
-		_, err = s.objects.Remove(ctx, id)
+		entry, err = s.objects.Remove(ctx, id)
 	} else {
-		_, err = s.objects.Remove(ctx, id, _map.IfVersion(primitive.Version(revision)))
+		entry, err = s.objects.Remove(ctx, id, _map.IfVersion(primitive.Version(revision)))
 	}
 	if err != nil {
 		err = errors.FromAtomix(err)
@@ -336,58 +759,313 @@ func (s *atomixStore) Delete(ctx context.Context, id topoapi.ID, revision topoap
 		}
 		return err
 	}
+	s.evict(entry)
+	s.deleteDanglingRelations(ctx, id)
 	return nil
 }
 
+// evict removes an entry deleted by this store from the local cache and indexes without waiting for the
+// removal event, so that subsequent reads through this store do not observe the deleted object
+func (s *atomixStore) evict(entry *_map.Entry[topoapi.ID, *topoapi.Object]) {
+	object := entry.Value
+	object.Revision = topoapi.Revision(entry.Version)
+	s.apply(topoapi.EventType_REMOVED, object, false)
+}
+
+// removedKey is the version of a key at its removal
+type removedKey struct {
+	id      topoapi.ID
+	version topoapi.Revision
+}
+
+// sent records that the event of a change received from the map events has been sent to the watchers. The version
+// of a removed key is kept to ignore the older changes still in flight and to discard the events of the removal
+// for new watchers until it has been sent, and then pruned after maxRemoved other removals.
+func (s *atomixStore) sent(c change) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	id := c.event.Object.ID
+	if s.unsent[id] > 1 {
+		s.unsent[id]--
+		return
+	}
+	delete(s.unsent, id)
+	if s.tombstone(id) {
+		s.removed = append(s.removed, removedKey{id: id, version: s.versions[id]})
+	}
+	for len(s.removed) > s.maxRemoved {
+		key := s.removed[0]
+		s.removed = s.removed[1:]
+		if s.tombstone(key.id) && s.versions[key.id] == key.version {
+			delete(s.versions, key.id)
+			if key.version > s.pruned {
+				s.pruned = key.version
+			}
+		}
+	}
+}
+
+// tombstone returns whether the version of a removed key is no longer needed by the snapshots of new watchers;
+// it must be called with the cache lock held
+func (s *atomixStore) tombstone(id topoapi.ID) bool {
+	_, ok := s.versions[id]
+	_, cached := s.cache[id]
+	_, unsent := s.unsent[id]
+	_, evicted := s.evicted[id]
+	return ok && !cached && !unsent && !evicted
+}
+
 func (s *atomixStore) deleteRelatedRelations(ctx context.Context, id topoapi.ID) error {
+	relationIDs, err := s.listRelatedRelations(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, relationID := range relationIDs {
+		// the deletion of the relation should trigger the watch to update the store maps
+		removed, err := s.objects.Remove(ctx, relationID)
+		if err != nil {
+			err = errors.FromAtomix(err)
+			if !errors.IsNotFound(err) {
+				return err
+			}
+		} else {
+			s.evict(removed)
+		}
+	}
+	return nil
+}
+
+// listRelatedRelations returns the IDs of the relations of the object with the given ID if it is an entity.
+// The relations are read from the local relation index once it reflects the entity, unless relations of the entity
+// written through this store are not indexed yet, in which case the map is scanned; relations created concurrently
+// through other replicas are removed by deleteDanglingRelations.
+func (s *atomixStore) listRelatedRelations(ctx context.Context, id topoapi.ID) ([]topoapi.ID, error) {
 	// access the object to determine its properties
 	entry, err := s.objects.Get(ctx, id)
 	if err != nil {
-		return errors.FromAtomix(err)
+		return nil, errors.FromAtomix(err)
 	}
-	obj := entry.Value
-	if obj.GetEntity() != nil {
-		// delete the relations
-		objs, err := s.objects.List(ctx)
+	if entry.Value.GetEntity() == nil {
+		return nil, nil
+	}
+	if err := s.awaitRevisions(ctx, map[topoapi.ID]topoapi.Revision{id: topoapi.Revision(entry.Version)}); err != nil {
+		return nil, err
+	}
+	if !s.pendingRelation(id) {
+		return s.relations.related(id), nil
+	}
+
+	list, err := s.objects.List(ctx)
+	if err != nil {
+		return nil, errors.FromAtomix(err)
+	}
+	var relationIDs []topoapi.ID
+	for {
+		entry, err := list.Next()
+		if err == io.EOF {
+			return relationIDs, nil
+		}
 		if err != nil {
-			return errors.FromAtomix(err)
+			return nil, errors.FromAtomix(err)
 		}
-		for {
-			entry, err := objs.Next()
-			if err == io.EOF {
-				return nil
+		if relation := entry.Value.GetRelation(); relation != nil && (relation.SrcEntityID == id || relation.TgtEntityID == id) {
+			relationIDs = append(relationIDs, entry.Key)
+		}
+	}
+}
+
+// pendingRelation returns whether a relation of the entity with the given ID written through this store has not
+// been applied to the relation index yet
+func (s *atomixStore) pendingRelation(id topoapi.ID) bool {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	for _, endpoints := range s.pendingRelations {
+		if endpoints[0] == id || endpoints[1] == id {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteDanglingRelations removes the relations of a deleted entity that were indexed after its relations were
+// listed for deletion. Relations indexed later are removed when their events are applied, as their endpoint
+// no longer exists.
+func (s *atomixStore) deleteDanglingRelations(ctx context.Context, id topoapi.ID) {
+	for _, relationID := range s.relations.related(id) {
+		log.Warnf("Deleting dangling Relation '%s' of deleted Entity '%s'", relationID, id)
+		removed, err := s.objects.Remove(ctx, relationID)
+		if err != nil {
+			err = errors.FromAtomix(err)
+			if !errors.IsNotFound(err) {
+				log.Errorf("Failed to delete dangling Relation '%s': %v", relationID, err)
 			}
-			if err != nil {
-				return errors.FromAtomix(err)
+			continue
+		}
+		s.evict(removed)
+	}
+}
+
+func (s *atomixStore) Transaction(ctx context.Context, operations ...Operation) error {
+	tx, err := newTransaction(operations)
+	if err != nil {
+		return err
+	}
+
+	// Delete the relations of deleted entities in the same transaction
+	for _, id := range tx.deletes() {
+		relationIDs, err := s.listRelatedRelations(ctx, id)
+		if err != nil {
+			log.Warnf("Failed to commit transaction: %v", err)
+			return err
+		}
+		if err := tx.cascade(id, relationIDs); err != nil {
+			return err
+		}
+	}
+
+	err = tx.validateRelations(func(id topoapi.ID) (bool, error) {
+		if _, err := s.objects.Get(ctx, id); err != nil {
+			err = errors.FromAtomix(err)
+			if errors.IsNotFound(err) {
+				return false, nil
 			}
-			ep := entry.Value
-			// if object is a relation and its kind and src id matches the filter, create blank entry for its target id
-			if ep.Type == topoapi.Object_RELATION && (ep.GetRelation().GetSrcEntityID() == obj.ID || ep.GetRelation().GetTgtEntityID() == obj.ID) {
-				// the deletion of the relation should trigger the watch to update the store maps
-				_, err = s.objects.Remove(ctx, ep.ID)
-				if err != nil {
-					err = errors.FromAtomix(err)
-					if !errors.IsNotFound(err) {
-						return err
-					}
-				}
+			return false, err
+		}
+		return true, nil
+	}, s.endpointsUnchanged)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Committing transaction with %d operations", len(tx.operations))
+	pending := s.transactions.begin(tx)
+	commit := s.objects.Transaction(ctx)
+	for _, op := range tx.operations {
+		object := op.Object
+		switch op.Type {
+		case OperationCreate:
+			commit = commit.Insert(object.ID, object)
+		case OperationUpdate:
+			commit = commit.Update(object.ID, object, _map.IfVersion(primitive.Version(object.Revision)))
+		case OperationDelete:
+			if object.Revision == 0 {
+				commit = commit.Remove(object.ID)
+			} else {
+				commit = commit.Remove(object.ID, _map.IfVersion(primitive.Version(object.Revision)))
 			}
 		}
 	}
+	entries, err := commit.Commit()
+	if err != nil {
+		s.transactions.abort(pending)
+		err = errors.FromAtomix(err)
+		if !errors.IsNotFound(err) && !errors.IsAlreadyExists(err) && !errors.IsConflict(err) {
+			log.Errorf("Failed to commit transaction: %v", err)
+		} else {
+			log.Warnf("Failed to commit transaction: %v", err)
+		}
+		return err
+	}
+
+	for i, op := range tx.operations {
+		entry := entries[i]
+		switch op.Type {
+		case OperationCreate, OperationUpdate:
+			op.Object.Revision = topoapi.Revision(entry.Version)
+			s.write(op.Object)
+		case OperationDelete:
+			s.evict(entry)
+		}
+	}
+	s.transactions.commit(pending, tx.operations)
+	for _, id := range tx.deletes() {
+		s.deleteDanglingRelations(ctx, id)
+	}
 	return nil
 }
 
-// Query streams objects to the given channel
-func (s *atomixStore) Query(ctx context.Context, ch chan<- *topoapi.Object, filters *topoapi.Filters) error {
-	if filters != nil && filters.RelationFilter != nil {
-		objects, err := s.listRelationFilter(ctx, filters)
+// Traverse streams the objects reached by the given traversal to the given channel
+func (s *atomixStore) Traverse(ctx context.Context, ch chan<- *topoapi.Object, traversal Traversal, opts ...ReadOption) error {
+	defer close(ch)
+	if err := streamTraversal(ctx, s.Get, ch, traversal, opts...); err != nil {
+		if err != ErrTraversalTruncated {
+			log.Warnf("Failed to traverse from '%s': %v", traversal.Start, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Paths returns the shortest paths between the entities of the given query
+func (s *atomixStore) Paths(ctx context.Context, query PathQuery, opts ...ReadOption) ([]Path, error) {
+	paths, err := shortestPaths(ctx, s.Get, query, opts...)
+	if err != nil {
+		log.Warnf("Failed to find paths from '%s' to '%s': %v", query.Src, query.Dst, err)
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Locate returns the objects whose location matches the given geo query, using the location index to find them
+func (s *atomixStore) Locate(ctx context.Context, query GeoQuery, opts ...ReadOption) ([]Located, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	if err := s.awaitRead(ctx, newReadOptions(opts)); err != nil {
+		return nil, err
+	}
+	ids, narrowed := s.index.candidates(query.Filters)
+	return locate(ctx, s.Get, query, s.index.locate(query, ids, narrowed), opts...)
+}
+
+// Count returns the number of objects matching the given filters grouped by the given fields; if the store reads
+// its local replica, the objects are counted from the index unless the filters apply to aspects or relations
+func (s *atomixStore) Count(ctx context.Context, filters *topoapi.Filters, groupBy []string, opts ...ReadOption) ([]GroupCount, error) {
+	if err := validateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+	if err := validateFilters(filters); err != nil {
+		return nil, err
+	}
+	if !(s.cachedReads || s.indexedReads) || !indexedFilters(filters) {
+		objects, err := s.List(ctx, filters, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return countObjects(objects, groupBy), nil
+	}
+	if err := s.awaitRead(ctx, newReadOptions(opts)); err != nil {
+		return nil, err
+	}
+	ids, narrowed := s.index.candidates(filters)
+	return s.index.count(filters, groupBy, ids, narrowed), nil
+}
+
+// Query streams objects to the given channel, closing it once all objects have been sent or an error occurs
+func (s *atomixStore) Query(ctx context.Context, ch chan<- *topoapi.Object, filters *topoapi.Filters, opts ...ReadOption) error {
+	defer close(ch)
+	if err := validateFilters(filters); err != nil {
+		return err
+	}
+	if (filters != nil && filters.RelationFilter != nil) || s.cachedReads {
+		objects, err := s.List(ctx, filters, opts...)
 		if err != nil {
 			return err
 		}
 		for i := range objects {
 			ch <- &objects[i]
 		}
-		close(ch)
+		return nil
+	}
+
+	objects, ok, err := s.lookup(ctx, filters, newReadOptions(opts))
+	if err != nil {
+		return err
+	}
+	if ok {
+		for _, object := range objects {
+			ch <- object
+		}
 		return nil
 	}
 
@@ -401,12 +1079,12 @@ func (s *atomixStore) Query(ctx context.Context, ch chan<- *topoapi.Object, filt
 		for {
 			entry, err := stream.Next()
 			if err == io.EOF {
-				close(ch)
 				return nil
 			}
 			if err != nil {
 				return errors.FromAtomix(err)
 			}
+			entry.Value.Revision = topoapi.Revision(entry.Version)
 			ch <- entry.Value
 		}
 	}
@@ -415,25 +1093,44 @@ func (s *atomixStore) Query(ctx context.Context, ch chan<- *topoapi.Object, filt
 	for {
 		entry, err := stream.Next()
 		if err == io.EOF {
-			close(ch)
 			return nil
 		}
 		if err != nil {
 			return errors.FromAtomix(err)
 		}
+		entry.Value.Revision = topoapi.Revision(entry.Version)
 
 		if match(entry.Value, filters) {
 			if matchType(entry.Value, filters.ObjectTypes) && matchAspects(entry.Value, filters.WithAspects) {
-				s.addSrcTgts(entry.Value)
+				s.relations.addSrcTgts(entry.Value)
 				ch <- entry.Value
 			}
 		}
 	}
 }
 
-func (s *atomixStore) List(ctx context.Context, filters *topoapi.Filters) ([]topoapi.Object, error) {
+func (s *atomixStore) List(ctx context.Context, filters *topoapi.Filters, opts ...ReadOption) ([]topoapi.Object, error) {
+	if err := validateFilters(filters); err != nil {
+		return nil, err
+	}
 	if filters != nil && filters.RelationFilter != nil {
-		return s.listRelationFilter(ctx, filters)
+		return listRelationFilter(ctx, s.Get, filters, opts...)
+	}
+
+	if s.cachedReads {
+		return s.listCached(ctx, filters, newReadOptions(opts))
+	}
+
+	objects, ok, err := s.lookup(ctx, filters, newReadOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		eps := make([]topoapi.Object, 0, len(objects))
+		for _, object := range objects {
+			eps = append(eps, *object)
+		}
+		return eps, nil
 	}
 
 	list, err := s.objects.List(ctx)
@@ -453,6 +1150,7 @@ func (s *atomixStore) List(ctx context.Context, filters *topoapi.Filters) ([]top
 			if err != nil {
 				return nil, errors.FromAtomix(err)
 			}
+			entry.Value.Revision = topoapi.Revision(entry.Version)
 			eps = append(eps, *entry.Value)
 		}
 	}
@@ -465,170 +1163,182 @@ func (s *atomixStore) List(ctx context.Context, filters *topoapi.Filters) ([]top
 		if err != nil {
 			return nil, errors.FromAtomix(err)
 		}
+		entry.Value.Revision = topoapi.Revision(entry.Version)
 		if match(entry.Value, filters) {
 			if matchType(entry.Value, filters.ObjectTypes) && matchAspects(entry.Value, filters.WithAspects) {
-				s.addSrcTgts(entry.Value)
+				s.relations.addSrcTgts(entry.Value)
 				eps = append(eps, *entry.Value)
 			}
 		}
 	}
 }
 
-func (s *atomixStore) listRelationFilter(ctx context.Context, filters *topoapi.Filters) ([]topoapi.Object, error) {
-	filter := filters.RelationFilter
+// lookup reads the objects matching the given filters from the local replica, using the local indexes to narrow
+// the candidates. If the store is not created WithIndexedReads, or the indexes cannot narrow the candidates enough
+// to avoid a scan of the map, lookup returns false.
+func (s *atomixStore) lookup(ctx context.Context, filters *topoapi.Filters, readOpts readOptions) ([]*topoapi.Object, bool, error) {
+	if !s.indexedReads || filters == nil {
+		return nil, false, nil
+	}
 
-	if len(filter.GetSrcId()) > 0 {
-		return s.filterRelationEntities(ctx, topoapi.ID(filter.GetSrcId()), filters, false)
-	} else if len(filter.GetTargetId()) > 0 {
-		return s.filterRelationEntities(ctx, topoapi.ID(filter.GetTargetId()), filters, true)
+	// Wait for the indexes to reflect the writes made through this store
+	if err := s.awaitRead(ctx, readOpts); err != nil {
+		return nil, false, err
 	}
-	return nil, errors.NewInvalid("filter must contain either srcID or targetID")
-}
 
-func (s *atomixStore) filterRelationEntities(ctx context.Context, id topoapi.ID, filters *topoapi.Filters, useSrc bool) ([]topoapi.Object, error) {
-	results := make([]topoapi.Object, 0)
-	obj, err := s.Get(ctx, id)
-	if err != nil {
-		return nil, err
+	ids, ok := s.index.candidates(filters)
+	if !ok || len(ids)*indexScanRatio > s.index.size() {
+		return nil, false, nil
 	}
 
-	rfilter := filters.RelationFilter
-	if rfilter.Scope == topoapi.RelationFilterScope_ALL || rfilter.Scope == topoapi.RelationFilterScope_SOURCE_AND_TARGETS {
-		results = append(results, *obj)
-	}
-
-	relations := obj.GetEntity().SrcRelationIDs
-	if useSrc {
-		relations = obj.GetEntity().TgtRelationIDs
-	}
-
-	for _, rid := range relations {
-		robj, err := s.Get(ctx, rid)
-		if err == nil && robj.Type == topoapi.Object_RELATION {
-			rel := robj.GetRelation()
-			if len(rfilter.RelationKind) == 0 || string(rel.KindID) == rfilter.RelationKind {
-				oid := rel.GetSrcEntityID()
-				if !useSrc {
-					oid = rel.GetTgtEntityID()
-				}
-				ent, err := s.Get(ctx, oid)
-				if err == nil && (len(rfilter.TargetKind) == 0 || string(ent.GetEntity().KindID) == rfilter.TargetKind) && matchAspects(ent, filters.WithAspects) {
-					if rfilter.Scope == topoapi.RelationFilterScope_ALL ||
-						rfilter.Scope == topoapi.RelationFilterScope_RELATIONS_ONLY ||
-						rfilter.Scope == topoapi.RelationFilterScope_RELATIONS_AND_TARGETS {
-						results = append(results, *robj)
-					}
-
-					if rfilter.Scope != topoapi.RelationFilterScope_RELATIONS_ONLY {
-						results = append(results, *ent)
-					}
-				}
-			}
+	// The candidates are read from the cache, which is consistent with the indexes, rather than one by one from
+	// the map
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	objects := make([]*topoapi.Object, 0, len(ids))
+	for id := range ids {
+		cached, ok := s.cache[id]
+		if !ok {
+			continue
+		}
+		if match(&cached, filters) && matchType(&cached, filters.ObjectTypes) && matchAspects(&cached, filters.WithAspects) {
+			object := clone(&cached)
+			s.relations.addSrcTgts(object)
+			objects = append(objects, object)
 		}
 	}
-	return results, nil
+	return objects, true, nil
 }
 
-func (s *atomixStore) Watch(ctx context.Context, ch chan<- topoapi.Event, filters *topoapi.Filters, opts ...WatchOption) error {
-	var watchOpts watchOptions
-	for _, opt := range opts {
-		opt.apply(&watchOpts)
+// listCached returns the objects in the local cache that match the given filters
+func (s *atomixStore) listCached(ctx context.Context, filters *topoapi.Filters, readOpts readOptions) ([]topoapi.Object, error) {
+	if err := s.awaitRead(ctx, readOpts); err != nil {
+		return nil, err
 	}
 
-	// Create separate channels for replay and watch events
-	replayCh := make(chan topoapi.Object)
-	eventCh := make(chan topoapi.Event)
+	ids, narrowed := s.index.candidates(filters)
 
-	// Create a goroutine to first replay existing state to the watcher and then send events
-	go func() {
-		defer close(ch)
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
 
-	replayLoop:
-		// Process the replay channel first
-		for {
-			select {
-			case object, ok := <-replayCh:
-				// If the replay channel is closed, break out of the replay loop
-				if !ok {
-					break replayLoop
-				}
-				// If an object is received on the replay channel, write it to
-				// the watch channel if it matches the watch filter
-				if match(&object, filters) {
-					ch <- topoapi.Event{
-						Type:   topoapi.EventType_NONE,
-						Object: object,
-					}
-				}
-			case <-ctx.Done():
-				// If the watch context is closed, drain the replay channel and break out of the replay loop
-				go func() {
-					for range replayCh { //revive:disable-line:empty-block
-					}
-				}()
-				break replayLoop
-			}
+	eps := make([]topoapi.Object, 0)
+	read := func(cached topoapi.Object) {
+		if filters == nil || (match(&cached, filters) && matchType(&cached, filters.ObjectTypes) && matchAspects(&cached, filters.WithAspects)) {
+			obj := clone(&cached)
+			s.relations.addSrcTgts(obj)
+			eps = append(eps, *obj)
 		}
-
-	eventLoop:
-		// Once the replay channel is processed, process the event channel
-		for {
-			select {
-			case event, ok := <-eventCh:
-				// If the event channel is closed, break out of the event loop
-				if !ok {
-					break eventLoop
-				}
-				// If an event is received on the replay channel, write it to
-				// the watch channel if it matches the watch filter
-				if match(&event.Object, filters) {
-					ch <- event
-				}
-			case <-ctx.Done():
-				// If the watch context is closed, drain the event channel and break out of the event loop
-				go func() {
-					for range eventCh { //revive:disable-line:empty-block
-					}
-				}()
-				break eventLoop
+	}
+	if narrowed {
+		for id := range ids {
+			if cached, ok := s.cache[id]; ok {
+				read(cached)
 			}
 		}
-	}()
+	} else {
+		for _, cached := range s.cache {
+			read(cached)
+		}
+	}
+	return eps, nil
+}
 
-	// Add the watcher's event channel
-	watcherID := uuid.New()
-	s.watchersMu.Lock()
-	s.watchers[watcherID] = eventCh
-	s.watchersMu.Unlock()
+// awaitRead waits until the local cache and indexes are recent enough to serve a read with the given options,
+// i.e. until they reflect the writes made through this store and the minimum revisions requested by the caller
+func (s *atomixStore) awaitRead(ctx context.Context, readOpts readOptions) error {
+	s.cacheMu.RLock()
+	revisions := make(map[topoapi.ID]topoapi.Revision, len(s.pending)+len(readOpts.minRevisions))
+	for id, revision := range s.pending {
+		revisions[id] = revision
+	}
+	s.cacheMu.RUnlock()
+	for id, revision := range readOpts.minRevisions {
+		if revision > revisions[id] {
+			revisions[id] = revision
+		}
+	}
+	return s.awaitRevisions(ctx, revisions)
+}
 
-	// Get the objects to replay
-	var objects []topoapi.Object
-	if watchOpts.replay {
+// awaitRevisions waits until the local cache and indexes reflect the given revisions of objects, removing the
+// revisions from the map as they are applied
+func (s *atomixStore) awaitRevisions(ctx context.Context, revisions map[topoapi.ID]topoapi.Revision) error {
+	for {
 		s.cacheMu.RLock()
-		objects = make([]topoapi.Object, 0, len(s.cache))
-		for _, object := range s.cache {
-			objects = append(objects, object)
+		for id, revision := range revisions {
+			// The versions of the keys removed long ago are pruned; like the journal, the highest pruned version
+			// is compared to the requested revision even though Atomix versions are only ordered per partition
+			if version, ok := s.versions[id]; version >= revision || (!ok && revision <= s.pruned) {
+				delete(revisions, id)
+			}
 		}
+		applied := s.applied
 		s.cacheMu.RUnlock()
+		if len(revisions) == 0 {
+			return nil
+		}
+		select {
+		case <-applied:
+		case <-ctx.Done():
+			return errors.NewTimeout("timed out waiting for %d object revisions", len(revisions))
+		}
+	}
+}
+
+// write records the revision of an object written by this store until it has been applied to the local cache,
+// along with the endpoints of a relation
+func (s *atomixStore) write(object *topoapi.Object) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	id, revision := object.ID, object.Revision
+	if s.versions[id] < revision && s.pending[id] < revision {
+		s.pending[id] = revision
+		if relation := object.GetRelation(); relation != nil {
+			s.pendingRelations[id] = [2]topoapi.ID{relation.SrcEntityID, relation.TgtEntityID}
+		}
 	}
+}
 
-	// Replay existing objects in the cache and then close the replay channel
-	go func() {
-		defer close(replayCh)
-		for _, object := range objects {
-			replayCh <- object
-		}
-	}()
-
-	// Remove the watcher and close the event channel once the watch context is done
-	go func() {
-		<-ctx.Done()
-		s.watchersMu.Lock()
-		delete(s.watchers, watcherID)
-		s.watchersMu.Unlock()
-		close(eventCh)
-	}()
-	return nil
+func (s *atomixStore) Watch(ctx context.Context, ch chan<- topoapi.Event, filters *topoapi.Filters, opts ...WatchOption) error {
+	get := func(ctx context.Context, id topoapi.ID) (*topoapi.Object, error) {
+		return s.Get(ctx, id)
+	}
+	return s.watchers.watch(ctx, ch, filters, s.snapshot, get, opts...)
+}
+
+func (s *atomixStore) WatchStats() WatchStats {
+	return s.watchers.stats()
+}
+
+// snapshot returns a copy of the objects in the cache, and the versions applied to the cache of the keys whose
+// events have not been sent to the watchers yet. Changes are applied to the cache before their events are sent to
+// the watchers, so these versions, including those of removed keys, discard the events of the changes already
+// reflected in the snapshot. The removals by this store are applied before their events are received.
+func (s *atomixStore) snapshot() storeSnapshot {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	snapshot := storeSnapshot{
+		objects:   make([]topoapi.Object, 0, len(s.cache)),
+		revisions: make(map[topoapi.ID]snapshotRevision, len(s.unsent)+len(s.evicted)),
+	}
+	for _, object := range s.cache {
+		snapshot.objects = append(snapshot.objects, object)
+	}
+	pending := func(id topoapi.ID, events int) {
+		revision := snapshot.revisions[id]
+		_, cached := s.cache[id]
+		revision.revision = s.versions[id]
+		revision.removed = !cached
+		revision.pending += events
+		snapshot.revisions[id] = revision
+	}
+	for id, events := range s.unsent {
+		pending(id, events)
+	}
+	for id := range s.evicted {
+		pending(id, 1)
+	}
+	return snapshot
 }
 
 func (s *atomixStore) Close() error {
@@ -641,78 +1351,58 @@ func (s *atomixStore) Close() error {
 	return nil
 }
 
-func (s *atomixStore) addSrcTgts(obj *topoapi.Object) {
-	if obj.GetEntity() != nil {
-		s.relations.lock.RLock()
-		defer s.relations.lock.RUnlock()
-		obj.GetEntity().SrcRelationIDs = s.relations.sources[obj.ID]
-		obj.GetEntity().TgtRelationIDs = s.relations.targets[obj.ID]
-	}
+// endpointsUnchanged returns whether the cache holds the revision of a relation being updated and the update does
+// not change its source and target. Updates are conditional on the revision, so the endpoints of such an update
+// need not be read again: an update of a stale revision fails, and deleting an entity deletes its relations.
+func (s *atomixStore) endpointsUnchanged(object *topoapi.Object) bool {
+	s.cacheMu.RLock()
+	previous, ok := s.cache[object.ID]
+	s.cacheMu.RUnlock()
+	return ok && previous.Revision == object.Revision && !endpointsChanged(&previous, object)
 }
 
-// when creating a relation, create the corresponding entries in the store
-func (s *atomixStore) registerSrcTgt(obj *topoapi.Object, strict bool) {
-	if relation := obj.GetRelation(); relation != nil {
-		if strict {
-			// check that the connection is valid (src and tgt are in the store). otherwise remove the dangling relation
-			if _, err := s.objects.Get(context.Background(), relation.SrcEntityID); err != nil {
-				err = errors.FromAtomix(err)
-				if errors.IsNotFound(err) {
-					_, _ = s.objects.Remove(context.Background(), obj.ID)
-				} else {
-					log.Error(err)
-				}
-				return
-			}
-			if _, err := s.objects.Get(context.Background(), relation.TgtEntityID); err != nil {
-				err = errors.FromAtomix(err)
-				if errors.IsNotFound(err) {
-					_, _ = s.objects.Remove(context.Background(), obj.ID)
-				} else {
-					log.Error(err)
-				}
-				return
-			}
+// checkEndpoints checks that the source and target entities of a relation being written exist
+func (s *atomixStore) checkEndpoints(ctx context.Context, object *topoapi.Object) error {
+	if _, err := s.objects.Get(ctx, object.GetRelation().GetSrcEntityID()); err != nil {
+		err = errors.FromAtomix(err)
+		if !errors.IsNotFound(err) {
+			return err
 		}
-
-		s.relations.lock.Lock()
-		defer s.relations.lock.Unlock()
-		s.relations.sources[relation.SrcEntityID] = add(s.relations.sources[relation.SrcEntityID], obj.ID)
-		s.relations.targets[relation.TgtEntityID] = add(s.relations.targets[relation.TgtEntityID], obj.ID)
-	}
-}
-
-// when deleting either a relation or entity, remove the corresponding entries in the store
-func (s *atomixStore) unregisterSrcTgt(obj *topoapi.Object) {
-	if entity := obj.GetEntity(); entity != nil {
-		s.relations.lock.Lock()
-		defer s.relations.lock.Unlock()
-		delete(s.relations.sources, obj.ID)
-		delete(s.relations.targets, obj.ID)
-
-	} else if relation := obj.GetRelation(); relation != nil {
-		s.relations.lock.Lock()
-		defer s.relations.lock.Unlock()
-		s.relations.sources[relation.SrcEntityID] = remove(s.relations.sources[relation.SrcEntityID], obj.ID)
-		s.relations.targets[relation.TgtEntityID] = remove(s.relations.targets[relation.TgtEntityID], obj.ID)
+		log.Warnf("Source Entity does not exist")
+		return errors.NewInvalid("Source Entity does not exist")
 	}
-}
-
-func add(ids []topoapi.ID, id topoapi.ID) []topoapi.ID {
-	for _, eid := range ids {
-		if eid == id {
-			return ids
+	if _, err := s.objects.Get(ctx, object.GetRelation().GetTgtEntityID()); err != nil {
+		err = errors.FromAtomix(err)
+		if !errors.IsNotFound(err) {
+			return err
 		}
+		log.Warnf("Target Entity does not exist")
+		return errors.NewInvalid("Target Entity does not exist")
 	}
-	return append(ids, id)
+	return nil
 }
 
-func remove(ids []topoapi.ID, id topoapi.ID) []topoapi.ID {
-	for i, eid := range ids {
-		if eid == id {
-			ids[i] = ids[len(ids)-1]
-			return ids[:len(ids)-1]
+// validateSrcTgt checks that the source and target of a relation are in the store; dangling relations are removed
+func (s *atomixStore) validateSrcTgt(obj *topoapi.Object) bool {
+	if relation := obj.GetRelation(); relation != nil {
+		if _, err := s.objects.Get(context.Background(), relation.SrcEntityID); err != nil {
+			err = errors.FromAtomix(err)
+			if errors.IsNotFound(err) {
+				_, _ = s.objects.Remove(context.Background(), obj.ID)
+			} else {
+				log.Error(err)
+			}
+			return false
+		}
+		if _, err := s.objects.Get(context.Background(), relation.TgtEntityID); err != nil {
+			err = errors.FromAtomix(err)
+			if errors.IsNotFound(err) {
+				_, _ = s.objects.Remove(context.Background(), obj.ID)
+			} else {
+				log.Error(err)
+			}
+			return false
 		}
 	}
-	return ids
+	return true
 }
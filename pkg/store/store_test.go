@@ -6,8 +6,11 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"github.com/atomix/go-sdk/pkg/test"
 	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,14 +19,12 @@ import (
 )
 
 func TestTopoStore(t *testing.T) {
-	cluster := test.NewClient()
-	defer cluster.Close()
-
-	store1, err := NewAtomixStore(cluster)
-	assert.NoError(t, err)
+	forEachBackend(t, testTopoStore)
+}
 
-	store2, err := NewAtomixStore(cluster)
-	assert.NoError(t, err)
+func testTopoStore(t *testing.T, newStore func() Store) {
+	store1 := newStore()
+	store2 := newStore()
 
 	// List the objects; there should be none
 	noobjects, err := store1.List(context.TODO(), nil)
@@ -189,21 +190,17 @@ func TestTopoStore(t *testing.T) {
 }
 
 func nextEvent(t *testing.T, ch chan topo.Event) *topo.Object {
-	select {
-	case c := <-ch:
-		return &c.Object
-	case <-time.After(5 * time.Second):
-		t.FailNow()
-	}
-	return nil
+	event := nextWatchEvent(t, ch)
+	return &event.Object
 }
 
 func TestList(t *testing.T) {
-	cluster := test.NewClient()
-	defer cluster.Close()
+	forEachBackend(t, testList)
+}
 
+func testList(t *testing.T, newStore func() Store) {
 	// Store def
-	store, _ := NewAtomixStore(cluster)
+	store := newStore()
 
 	// Objects def:
 	// - node 1234
@@ -363,11 +360,12 @@ func TestList(t *testing.T) {
 const depth = 512
 
 func TestQuery(t *testing.T) {
-	cluster := test.NewClient()
-	defer cluster.Close()
+	forEachBackend(t, testQuery)
+}
 
+func testQuery(t *testing.T, newStore func() Store) {
 	// Store def
-	store, _ := NewAtomixStore(cluster)
+	store := newStore()
 
 	// Objects def:
 	// - node 1234
@@ -638,3 +636,531 @@ func createCellNeighbors(t *testing.T, s Store, a auxCellNeighbor) {
 	})
 	assert.NoError(t, err)
 }
+
+func TestCachedReads(t *testing.T) {
+	newCachedStore := func(t *testing.T) (func() Store, func()) {
+		cluster := test.NewClient()
+		return func() Store {
+			store, err := NewAtomixStore(cluster, WithCachedReads())
+			assert.NoError(t, err)
+			return store
+		}, cluster.Close
+	}
+
+	t.Run("List", func(t *testing.T) {
+		newStore, closer := newCachedStore(t)
+		defer closer()
+		testList(t, newStore)
+	})
+	t.Run("Query", func(t *testing.T) {
+		newStore, closer := newCachedStore(t)
+		defer closer()
+		testQuery(t, newStore)
+	})
+	t.Run("Revisions", func(t *testing.T) {
+		newStore, closer := newCachedStore(t)
+		defer closer()
+		testRevisions(t, newStore)
+	})
+	t.Run("Replicas", func(t *testing.T) {
+		newStore, closer := newCachedStore(t)
+		defer closer()
+		testCachedReplicas(t, newStore)
+	})
+}
+
+func TestIndexedReads(t *testing.T) {
+	newIndexedStore := func(t *testing.T) (func() Store, func()) {
+		cluster := test.NewClient()
+		return func() Store {
+			store, err := NewAtomixStore(cluster, WithIndexedReads())
+			assert.NoError(t, err)
+			return store
+		}, cluster.Close
+	}
+
+	t.Run("List", func(t *testing.T) {
+		newStore, closer := newIndexedStore(t)
+		defer closer()
+		testList(t, newStore)
+	})
+	t.Run("Query", func(t *testing.T) {
+		newStore, closer := newIndexedStore(t)
+		defer closer()
+		testQuery(t, newStore)
+	})
+	t.Run("IndexedList", func(t *testing.T) {
+		newStore, closer := newIndexedStore(t)
+		defer closer()
+		testIndexedList(t, newStore)
+	})
+	t.Run("Count", func(t *testing.T) {
+		newStore, closer := newIndexedStore(t)
+		defer closer()
+		testCount(t, newStore)
+	})
+}
+
+func TestUncachedReplicas(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+	store1, err := NewAtomixStore(cluster)
+	assert.NoError(t, err)
+	store2, err := NewAtomixStore(cluster)
+	assert.NoError(t, err)
+
+	// Reads that are not served from the local replica must reflect the writes made through other replicas
+	for i := 0; i < 10; i++ {
+		createCell(t, store1, auxCell{id: fmt.Sprintf("c%d", i), labels: map[string]string{"pod": fmt.Sprintf("pod-%d", i)}})
+		filters := &topo.Filters{
+			LabelFilters: []*topo.Filter{{Key: "pod", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: fmt.Sprintf("pod-%d", i)}}}},
+		}
+		objects, err := store2.List(context.TODO(), filters)
+		assert.NoError(t, err)
+		assert.Len(t, objects, 1)
+		counts, err := store2.Count(context.TODO(), filters, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []GroupCount{{Count: 1}}, counts)
+	}
+}
+
+func testCachedReplicas(t *testing.T, newStore func() Store) {
+	store1 := newStore()
+	store2 := newStore()
+
+	node := &topo.Object{
+		ID:     "n1",
+		Type:   topo.Object_ENTITY,
+		Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: "e2-node"}},
+		Labels: map[string]string{"env": "test"},
+	}
+	err := store1.Create(context.TODO(), node)
+	assert.NoError(t, err)
+	cell := &topo.Object{
+		ID:   "c1",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "e2-cell"}},
+	}
+	err = store1.Create(context.TODO(), cell)
+	assert.NoError(t, err)
+	relation := &topo.Object{
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "e2-node-cell", SrcEntityID: "n1", TgtEntityID: "c1"}},
+	}
+	err = store1.Create(context.TODO(), relation)
+	assert.NoError(t, err)
+
+	// Reading from another replica at the revisions of the writes must observe them
+	object, err := store2.Get(context.TODO(), "n1", WithMinRevision(node.ID, node.Revision), WithMinRevision(relation.ID, relation.Revision))
+	assert.NoError(t, err)
+	assert.Equal(t, "test", object.Labels["env"])
+	assert.Equal(t, []topo.ID{relation.ID}, object.GetEntity().SrcRelationIDs)
+
+	objects, err := store2.List(context.TODO(), &topo.Filters{
+		RelationFilter: &topo.RelationFilter{SrcId: "n1", RelationKind: "e2-node-cell"},
+	}, WithMinRevision(cell.ID, cell.Revision), WithMinRevision(relation.ID, relation.Revision))
+	assert.NoError(t, err)
+	if assert.Len(t, objects, 1) {
+		assert.Equal(t, topo.ID("c1"), objects[0].ID)
+	}
+
+	object.Labels["env"] = "production"
+	err = store2.Update(context.TODO(), object)
+	assert.NoError(t, err)
+	objects, err = store1.List(context.TODO(), &topo.Filters{LabelFilters: []*topo.Filter{
+		{Key: "env", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "production"}}},
+	}}, WithMinRevision(object.ID, object.Revision))
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	// Reads must wait for the requested revision
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = store1.Get(ctx, "n1", WithMinRevision(object.ID, object.Revision+100))
+	assert.True(t, errors.IsTimeout(err))
+
+	// Deletes must be visible to subsequent reads through the same replica
+	err = store2.Delete(context.TODO(), "c1", 0)
+	assert.NoError(t, err)
+	_, err = store2.Get(context.TODO(), "c1")
+	assert.True(t, errors.IsNotFound(err))
+	object, err = store2.Get(context.TODO(), "n1")
+	assert.NoError(t, err)
+	assert.Empty(t, object.GetEntity().SrcRelationIDs)
+}
+
+func TestRemovedVersions(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+	s, err := NewAtomixStore(cluster, WithJournalSize(4))
+	assert.NoError(t, err)
+	store := s.(*atomixStore)
+
+	revisions := make(map[topo.ID]topo.Revision)
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("c%d", i)
+		createCell(t, store, auxCell{id: id})
+		object, err := store.Get(context.TODO(), topo.ID(id))
+		assert.NoError(t, err)
+		revisions[object.ID] = object.Revision
+		assert.NoError(t, store.Delete(context.TODO(), object.ID, 0))
+	}
+
+	// Once their removals have been sent, only the versions of the most recently removed keys must be kept, and
+	// snapshots must only hold the keys whose events are in flight
+	assert.Eventually(t, func() bool {
+		store.cacheMu.RLock()
+		defer store.cacheMu.RUnlock()
+		return len(store.versions) <= 4 && len(store.unsent) == 0 && len(store.evicted) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, store.snapshot().revisions)
+
+	// Reads at the revision of a removed object must not wait once its version has been pruned
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = store.Get(ctx, "c0", WithMinRevision("c0", revisions["c0"]))
+	assert.True(t, errors.IsNotFound(err))
+
+	// Watchers must not be sent the events of changes made before they were registered
+	ch := make(chan topo.Event)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, store.Watch(ctx, ch, nil, WithReplay()))
+	createCell(t, store, auxCell{id: "c0"})
+	event := nextWatchEvent(t, ch)
+	assert.Equal(t, topo.EventType_ADDED, event.Type)
+	assert.Equal(t, topo.ID("c0"), event.Object.ID)
+}
+
+func TestDanglingRelations(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+	s, err := NewAtomixStore(cluster)
+	assert.NoError(t, err)
+	store := s.(*atomixStore)
+
+	createNode(t, store, auxNode{id: "n1"})
+	createCell(t, store, auxCell{id: "c1"})
+	createNodeToCell(t, store, auxNodeToCell{srcID: "n1", tgtID: "c1"})
+
+	// Relations created by other replicas must be deleted with their entities once they are indexed
+	relation := &topo.Object{
+		ID:   "n1-c1",
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "e2-node-cell", SrcEntityID: "n1", TgtEntityID: "c1"}},
+	}
+	_, err = store.objects.Insert(context.TODO(), relation.ID, relation)
+	assert.NoError(t, err)
+	waitForRelations(t, store, "n1", 2, 0)
+	err = store.Delete(context.TODO(), "n1", 0)
+	assert.NoError(t, err)
+	objects, err := store.List(context.TODO(), &topo.Filters{ObjectTypes: []topo.Object_Type{topo.Object_RELATION}})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 0)
+
+	// Relations indexed after the relations of an entity were deleted must be found by the consistency check
+	createNode(t, store, auxNode{id: "n2"})
+	_, err = store.objects.Insert(context.TODO(), relation.ID, &topo.Object{
+		ID:   relation.ID,
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "e2-node-cell", SrcEntityID: "n2", TgtEntityID: "c1"}},
+	})
+	assert.NoError(t, err)
+	waitForRelations(t, store, "n2", 1, 0)
+	entry, err := store.objects.Remove(context.TODO(), "n2")
+	assert.NoError(t, err)
+	store.evict(entry)
+	store.deleteDanglingRelations(context.TODO(), "n2")
+	_, err = store.objects.Get(context.TODO(), relation.ID)
+	assert.True(t, errors.IsNotFound(errors.FromAtomix(err)))
+	waitForRelations(t, store, "c1", 0, 0)
+}
+
+func TestRelatedRelations(t *testing.T) {
+	cluster := test.NewClient()
+	defer cluster.Close()
+	s, err := NewAtomixStore(cluster)
+	assert.NoError(t, err)
+	store := s.(*atomixStore)
+
+	createNode(t, store, auxNode{id: "n1"})
+	createCell(t, store, auxCell{id: "c1"})
+	createNodeToCell(t, store, auxNodeToCell{srcID: "n1", tgtID: "c1"})
+	waitForRelations(t, store, "n1", 1, 0)
+
+	// Listing the relations of an entity must not wait for unrelated writes
+	store.cacheMu.Lock()
+	store.pending["n2"] = topo.Revision(1 << 40)
+	store.cacheMu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	relationIDs, err := store.listRelatedRelations(ctx, "n1")
+	assert.NoError(t, err)
+	assert.Len(t, relationIDs, 1)
+
+	// Relations of the entity that are not indexed yet must be found by scanning the map
+	relation := &topo.Object{
+		ID:   "n1-c1",
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "e2-node-cell", SrcEntityID: "c1", TgtEntityID: "n1"}},
+	}
+	_, err = store.objects.Insert(context.TODO(), relation.ID, relation)
+	assert.NoError(t, err)
+	store.cacheMu.Lock()
+	store.pendingRelations["n1-c1"] = [2]topo.ID{"c1", "n1"}
+	store.cacheMu.Unlock()
+	relationIDs, err = store.listRelatedRelations(ctx, "n1")
+	assert.NoError(t, err)
+	assert.Len(t, relationIDs, 2)
+	assert.Contains(t, relationIDs, topo.ID("n1-c1"))
+}
+
+func TestLabelOperators(t *testing.T) {
+	forEachBackend(t, testLabelOperators)
+}
+
+func testLabelOperators(t *testing.T, newStore func() Store) {
+	store := newStore()
+	for id, labels := range map[topo.ID]map[string]string{
+		"pod-01": {"name": "pod-01", "rack": "r1", "priority": "3"},
+		"pod-02": {"name": "pod-02", "rack": "r2", "priority": "7.5"},
+		"pod-10": {"name": "pod-10", "priority": "10"},
+		"pod-11": {"name": "pod-11", "priority": "high"},
+		"sw-1":   {"rack": "r1"},
+	} {
+		err := store.Create(context.TODO(), &topo.Object{
+			ID:     id,
+			Type:   topo.Object_ENTITY,
+			Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: "pod"}},
+			Labels: labels,
+		})
+		assert.NoError(t, err)
+	}
+
+	listIDs := func(filters ...*topo.Filter) []topo.ID {
+		objects, err := store.List(context.TODO(), &topo.Filters{LabelFilters: filters})
+		assert.NoError(t, err)
+		ids := make([]topo.ID, 0, len(objects))
+		for _, object := range objects {
+			ids = append(ids, object.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return ids[i] < ids[j]
+		})
+		return ids
+	}
+
+	// Existence and absence must only consider the presence of the label
+	assert.Equal(t, []topo.ID{"pod-01", "pod-02", "sw-1"}, listIDs(NewLabelFilter(LabelExists, "rack", "")))
+	assert.Equal(t, []topo.ID{"pod-10", "pod-11"}, listIDs(&topo.Filter{
+		Filter: &topo.Filter_Not{Not: &topo.NotFilter{Inner: NewLabelFilter(LabelExists, "rack", "")}},
+	}))
+
+	// Prefixes and regular expressions must match the label values
+	assert.Equal(t, []topo.ID{"pod-01", "pod-02"}, listIDs(NewLabelFilter(LabelPrefix, "name", "pod-0")))
+	assert.Equal(t, []topo.ID{"pod-02", "pod-10"}, listIDs(NewLabelFilter(LabelRegex, "name", "^pod-(02|10)$")))
+	assert.Equal(t, []topo.ID{"pod-01", "pod-10", "pod-11"}, listIDs(&topo.Filter{
+		Key:    NewLabelFilter(LabelPrefix, "name", "").Key,
+		Filter: &topo.Filter_In{In: &topo.InFilter{Values: []string{"pod-01", "pod-1"}}},
+	}))
+
+	// Numeric comparisons must ignore non-numeric values, and must be combined into ranges
+	assert.Equal(t, []topo.ID{"pod-02", "pod-10"}, listIDs(NewLabelFilter(LabelGreaterThan, "priority", "5")))
+	assert.Equal(t, []topo.ID{"pod-01"}, listIDs(NewLabelFilter(LabelLessOrEqual, "priority", "3")))
+	assert.Equal(t, []topo.ID{"pod-02", "pod-10"}, listIDs(
+		NewLabelFilter(LabelGreaterOrEqual, "priority", "7.5"),
+		NewLabelFilter(LabelLessThan, "priority", "100"),
+	))
+
+	// Keys that do not start with an operator must be matched as plain label keys
+	assert.Len(t, listIDs(&topo.Filter{Key: "size:rack", Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "r1"}}}), 0)
+
+	// Queries must apply the same operators
+	ch := make(chan *topo.Object)
+	go func() {
+		assert.NoError(t, store.Query(context.TODO(), ch, &topo.Filters{
+			LabelFilters: []*topo.Filter{NewLabelFilter(LabelPrefix, "name", "pod-1")},
+		}))
+	}()
+	var queried []topo.ID
+	for object := range ch {
+		queried = append(queried, object.ID)
+	}
+	assert.ElementsMatch(t, []topo.ID{"pod-10", "pod-11"}, queried)
+
+	// Watches must apply the same operators to replayed and new objects
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan topo.Event)
+	err := store.Watch(ctx, events, &topo.Filters{
+		LabelFilters: []*topo.Filter{NewLabelFilter(LabelGreaterThan, "priority", "5")},
+	}, WithReplay())
+	assert.NoError(t, err)
+	var watched []topo.ID
+	for i := 0; i < 2; i++ {
+		event := <-events
+		watched = append(watched, event.Object.ID)
+	}
+	assert.ElementsMatch(t, []topo.ID{"pod-02", "pod-10"}, watched)
+	err = store.Create(context.TODO(), &topo.Object{
+		ID:     "pod-20",
+		Type:   topo.Object_ENTITY,
+		Labels: map[string]string{"priority": "1"},
+	})
+	assert.NoError(t, err)
+	err = store.Create(context.TODO(), &topo.Object{
+		ID:     "pod-21",
+		Type:   topo.Object_ENTITY,
+		Labels: map[string]string{"priority": "20"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, topo.ID("pod-21"), nextWatchEvent(t, events).Object.ID)
+
+	// Plain label filters must match the label with their key verbatim, even if it looks like an operator, an aspect
+	// field or a filter expression
+	labels := map[string]string{"prefix:name": "pod-0", "expr:": "{}", "aspect:onos.topo.E2Cell/pci": "42"}
+	err = store.Create(context.TODO(), &topo.Object{
+		ID:     "pod-30",
+		Type:   topo.Object_ENTITY,
+		Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: "pod"}},
+		Labels: labels,
+	})
+	assert.NoError(t, err)
+	for key, value := range labels {
+		assert.Equal(t, []topo.ID{"pod-30"}, listIDs(&topo.Filter{
+			Key:    key,
+			Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: value}},
+		}))
+	}
+
+	// Label keys reserved for filters must be rejected
+	err = store.Create(context.TODO(), &topo.Object{
+		ID:     "pod-31",
+		Type:   topo.Object_ENTITY,
+		Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: "pod"}},
+		Labels: map[string]string{NewLabelFilter(LabelPrefix, "name", "").Key: "pod-0"},
+	})
+	assert.True(t, errors.IsInvalid(err))
+
+	// Invalid operands must be rejected
+	_, err = store.List(context.TODO(), &topo.Filters{LabelFilters: []*topo.Filter{NewLabelFilter(LabelRegex, "name", "[")}})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.List(context.TODO(), &topo.Filters{LabelFilters: []*topo.Filter{NewLabelFilter(LabelRegex, "name", strings.Repeat("a", maxRegexLength+1))}})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.List(context.TODO(), &topo.Filters{LabelFilters: []*topo.Filter{NewLabelFilter(LabelGreaterThan, "priority", "high")}})
+	assert.True(t, errors.IsInvalid(err))
+	ch = make(chan *topo.Object)
+	err = store.Query(context.TODO(), ch, &topo.Filters{LabelFilters: []*topo.Filter{{
+		Filter: &topo.Filter_Not{Not: &topo.NotFilter{Inner: NewLabelFilter(LabelRegex, "name", "(")}},
+	}}})
+	assert.True(t, errors.IsInvalid(err))
+	_, ok := <-ch
+	assert.False(t, ok)
+	err = store.Watch(ctx, make(chan topo.Event), &topo.Filters{LabelFilters: []*topo.Filter{NewLabelFilter(LabelLessThan, "priority", "")}})
+	assert.True(t, errors.IsInvalid(err))
+}
+
+func TestAspectFilters(t *testing.T) {
+	forEachBackend(t, testAspectFilters)
+}
+
+func testAspectFilters(t *testing.T, newStore func() Store) {
+	store := newStore()
+	for id, aspect := range map[topo.ID]string{
+		"cell-1": `{"pci": 42, "arfcn": 630000, "neighbors": [{"pci": 7}], "tac": "87893172902461441"}`,
+		"cell-2": `{"pci": 7.0, "arfcn": 640000, "neighbors": []}`,
+		"cell-3": `{"pci": null, "arfcn": 650000}`,
+		"cell-4": `{"arfcn": "n/a"}`,
+	} {
+		object := &topo.Object{
+			ID:   id,
+			Type: topo.Object_ENTITY,
+			Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "cell"}},
+		}
+		assert.NoError(t, object.SetAspectBytes("onos.topo.E2Cell", []byte(aspect)))
+		assert.NoError(t, store.Create(context.TODO(), object))
+	}
+	sw := &topo.Object{
+		ID:   "sw-1",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "switch"}},
+	}
+	assert.NoError(t, sw.SetAspectBytes("onos.topo.Switch", []byte(`{"role": "leaf", "enabled": true}`)))
+	assert.NoError(t, store.Create(context.TODO(), sw))
+
+	listIDs := func(filters ...*topo.Filter) []topo.ID {
+		objects, err := store.List(context.TODO(), &topo.Filters{LabelFilters: filters})
+		assert.NoError(t, err)
+		ids := make([]topo.ID, 0, len(objects))
+		for _, object := range objects {
+			ids = append(ids, object.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return ids[i] < ids[j]
+		})
+		return ids
+	}
+	equal := func(key string, value string) *topo.Filter {
+		return &topo.Filter{Key: key, Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: value}}}
+	}
+	pci := AspectKey("onos.topo.E2Cell", "pci")
+
+	// Equality must compare strings, booleans and numbers whatever their encoding
+	assert.Equal(t, []topo.ID{"cell-1"}, listIDs(equal(pci, "42")))
+	assert.Equal(t, []topo.ID{"cell-2"}, listIDs(equal(pci, "7")))
+	assert.Equal(t, []topo.ID{"sw-1"}, listIDs(equal(AspectKey("onos.topo.Switch", "role"), "leaf")))
+	assert.Equal(t, []topo.ID{"sw-1"}, listIDs(equal(AspectKey("onos.topo.Switch", "enabled"), "true")))
+	assert.Equal(t, []topo.ID{"cell-1"}, listIDs(equal(AspectKey("onos.topo.E2Cell", "tac"), "87893172902461441")))
+	assert.Equal(t, []topo.ID{"cell-1", "cell-2"}, listIDs(&topo.Filter{
+		Key:    pci,
+		Filter: &topo.Filter_In{In: &topo.InFilter{Values: []string{"42", "7", "3"}}},
+	}))
+
+	// Paths must address nested fields and array elements
+	assert.Equal(t, []topo.ID{"cell-1"}, listIDs(equal(AspectKey("onos.topo.E2Cell", "neighbors.0.pci"), "7")))
+
+	// Missing and null fields must only match negations and absence
+	assert.Equal(t, []topo.ID{"cell-1", "cell-2"}, listIDs(NewLabelFilter(LabelExists, pci, "")))
+	assert.Equal(t, []topo.ID{"cell-3", "cell-4", "sw-1"}, listIDs(&topo.Filter{
+		Filter: &topo.Filter_Not{Not: &topo.NotFilter{Inner: NewLabelFilter(LabelExists, pci, "")}},
+	}))
+	assert.Equal(t, []topo.ID{"cell-2", "cell-3", "cell-4", "sw-1"}, listIDs(&topo.Filter{
+		Filter: &topo.Filter_Not{Not: &topo.NotFilter{Inner: equal(pci, "42")}},
+	}))
+
+	// Ranges must ignore non-numeric values
+	arfcn := AspectKey("onos.topo.E2Cell", "arfcn")
+	assert.Equal(t, []topo.ID{"cell-2", "cell-3"}, listIDs(
+		NewLabelFilter(LabelGreaterThan, arfcn, "630000"),
+		NewLabelFilter(LabelLessOrEqual, arfcn, "650000"),
+	))
+
+	// Aspect predicates must be combined with label and kind filters
+	objects, err := store.List(context.TODO(), &topo.Filters{
+		KindFilter:   &topo.Filter{Filter: &topo.Filter_Equal_{Equal_: &topo.EqualFilter{Value: "cell"}}},
+		LabelFilters: []*topo.Filter{NewLabelFilter(LabelLessThan, pci, "10")},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, topo.ID("cell-2"), objects[0].ID)
+
+	// Watches must apply aspect predicates to replayed and new objects
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan topo.Event)
+	err = store.Watch(ctx, events, &topo.Filters{LabelFilters: []*topo.Filter{equal(pci, "42")}}, WithReplay())
+	assert.NoError(t, err)
+	event := <-events
+	assert.Equal(t, topo.ID("cell-1"), event.Object.ID)
+	object, err := store.Get(context.TODO(), "cell-2")
+	assert.NoError(t, err)
+	assert.NoError(t, object.SetAspectBytes("onos.topo.E2Cell", []byte(`{"pci": 42}`)))
+	assert.NoError(t, store.Update(context.TODO(), object))
+	assert.Equal(t, topo.ID("cell-2"), nextWatchEvent(t, events).Object.ID)
+
+	// Malformed aspect keys must be rejected
+	_, err = store.List(context.TODO(), &topo.Filters{LabelFilters: []*topo.Filter{equal(AspectKey("onos.topo.E2Cell", ""), "42")}})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.List(context.TODO(), &topo.Filters{LabelFilters: []*topo.Filter{NewLabelFilter(LabelGreaterThan, AspectKey("", "pci"), "1")}})
+	assert.True(t, errors.IsInvalid(err))
+}
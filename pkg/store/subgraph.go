@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"sort"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// subgraph tracks the objects selected by a relation filter from the events of a watch, so that the watch follows
+// the relations added to or removed from the anchor entity of the filter. The objects are selected like List does:
+// the anchor, the relations of the anchor of the filtered kind, and the entities at the other end of those relations,
+// depending on the scope of the filter. The subgraph is seeded from the snapshot taken when the watcher registered,
+// and follows the events that follow the snapshot; the entities linked to the anchor by a new relation are read
+// from the store, and the events already reflected in the state read are discarded.
+type subgraph struct {
+	filters *topoapi.Filters
+	anchor  topoapi.ID
+	inbound bool
+	// get reads an entity linked to the anchor by a new relation
+	get func(id topoapi.ID) (*topoapi.Object, error)
+	// relations are the relations of the anchor of the filtered kind
+	relations map[topoapi.ID]*topoapi.Object
+	// entities are the anchor and the entities at the other end of its relations, whether they match the filters
+	// or not
+	entities map[topoapi.ID]*topoapi.Object
+	// members are the objects of the subgraph sent to the watcher
+	members map[topoapi.ID]*topoapi.Object
+}
+
+func newSubgraph(filters *topoapi.Filters, get func(id topoapi.ID) (*topoapi.Object, error)) (*subgraph, error) {
+	anchor, inbound, err := relationAnchor(filters.RelationFilter)
+	if err != nil {
+		return nil, err
+	}
+	return &subgraph{
+		filters:   filters,
+		anchor:    anchor,
+		inbound:   inbound,
+		get:       get,
+		relations: make(map[topoapi.ID]*topoapi.Object),
+		entities:  make(map[topoapi.ID]*topoapi.Object),
+		members:   make(map[topoapi.ID]*topoapi.Object),
+	}, nil
+}
+
+// seed initializes the subgraph from a snapshot of the objects and returns its members, the anchor first and each
+// relation followed by its related entity
+func (g *subgraph) seed(objects []topoapi.Object) []topoapi.Object {
+	for i := range objects {
+		if object := &objects[i]; object.Type == topoapi.Object_RELATION && g.selects(object) {
+			g.relations[object.ID] = object
+		}
+	}
+	for i := range objects {
+		if object := &objects[i]; object.Type == topoapi.Object_ENTITY && (object.ID == g.anchor || g.related(object.ID)) {
+			g.entities[object.ID] = object
+		}
+	}
+
+	var members []topoapi.Object
+	add := func(id topoapi.ID) {
+		if _, ok := g.members[id]; ok {
+			return
+		}
+		if object, ok := g.member(id); ok {
+			g.members[id] = object
+			members = append(members, *object)
+		}
+	}
+	add(g.anchor)
+	for _, id := range g.relationIDs() {
+		add(id)
+		add(relatedEntityID(g.relations[id], g.inbound))
+	}
+	return members
+}
+
+// apply updates the subgraph with the given event and returns the events to send to the watcher: objects joining
+// the subgraph are sent as added, objects leaving it as removed, and changes to members as they are. Events must be
+// applied in the order they follow the snapshot the subgraph was seeded from.
+func (g *subgraph) apply(event topoapi.Event) []topoapi.Event {
+	object := &event.Object
+	removed := event.Type == topoapi.EventType_REMOVED
+	var affected []topoapi.ID
+	switch object.Type {
+	case topoapi.Object_RELATION:
+		if previous, ok := g.relations[object.ID]; ok {
+			// The related entity of a relation whose endpoints changed may leave the subgraph
+			affected = append(affected, relatedEntityID(previous, g.inbound))
+		}
+		if !removed && g.selects(object) {
+			g.relations[object.ID] = object
+			end := relatedEntityID(object, g.inbound)
+			g.resolve(end)
+			affected = append(affected, end)
+		} else {
+			delete(g.relations, object.ID)
+		}
+		affected = append([]topoapi.ID{object.ID}, affected...)
+	case topoapi.Object_ENTITY:
+		if _, wasMember := g.members[object.ID]; !wasMember && object.ID != g.anchor && !g.related(object.ID) {
+			return nil
+		}
+		// The state read for an entity linked to the anchor may be ahead of the events of the watch
+		if known, ok := g.entities[object.ID]; ok && (object.Revision < known.Revision || (object.Revision == known.Revision && !removed)) {
+			return nil
+		}
+		if removed {
+			delete(g.entities, object.ID)
+		} else {
+			g.entities[object.ID] = object
+		}
+		affected = append(affected, object.ID)
+		for _, id := range g.relationIDs() {
+			// The whole subgraph depends on the anchor
+			if end := relatedEntityID(g.relations[id], g.inbound); object.ID == g.anchor {
+				affected = append(affected, id, end)
+			} else if end == object.ID {
+				affected = append(affected, id)
+			}
+		}
+	default:
+		return nil
+	}
+
+	var events []topoapi.Event
+	done := make(map[topoapi.ID]bool)
+	for _, id := range affected {
+		if done[id] {
+			continue
+		}
+		done[id] = true
+		current, isMember := g.member(id)
+		previous, wasMember := g.members[id]
+		switch {
+		case isMember && !wasMember:
+			events = append(events, topoapi.Event{Type: topoapi.EventType_ADDED, Object: *current})
+			g.members[id] = current
+		case isMember && id == object.ID:
+			events = append(events, topoapi.Event{Type: event.Type, Object: *current})
+			g.members[id] = current
+		case !isMember && wasMember:
+			if id == object.ID {
+				previous = object
+			}
+			events = append(events, topoapi.Event{Type: topoapi.EventType_REMOVED, Object: *previous})
+			delete(g.members, id)
+		}
+		// Entities no longer linked to the anchor are forgotten
+		if _, ok := g.entities[id]; ok && id != g.anchor && !g.related(id) {
+			delete(g.entities, id)
+		}
+	}
+	return events
+}
+
+// resolve reads an entity linked to the anchor by a new relation, unless it is already known. An entity that is
+// not found joins the subgraph with its own event.
+func (g *subgraph) resolve(id topoapi.ID) {
+	if _, ok := g.entities[id]; ok || g.get == nil {
+		return
+	}
+	entity, err := g.get(id)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Warnf("Failed to read Object %s linked to the anchor of a watch: %v", id, err)
+		}
+		return
+	}
+	g.entities[id] = entity
+}
+
+// selects returns whether the given relation is a relation of the anchor of the filtered kind
+func (g *subgraph) selects(relation *topoapi.Object) bool {
+	r := relation.GetRelation()
+	if r == nil {
+		return false
+	}
+	end := r.SrcEntityID
+	if g.inbound {
+		end = r.TgtEntityID
+	}
+	return end == g.anchor && matchRelationKind(relation, g.filters.RelationFilter)
+}
+
+// member returns the object with the given ID if it belongs to the subgraph
+func (g *subgraph) member(id topoapi.ID) (*topoapi.Object, bool) {
+	if relation, ok := g.relations[id]; ok {
+		entity, ok := g.entities[relatedEntityID(relation, g.inbound)]
+		_, anchored := g.entities[g.anchor]
+		return relation, ok && anchored && scopeIncludesRelations(g.filters.RelationFilter.Scope) && matchRelatedEntity(entity, g.filters)
+	}
+	entity, ok := g.entities[id]
+	if !ok {
+		return nil, false
+	}
+	if id == g.anchor && scopeIncludesSource(g.filters.RelationFilter.Scope) {
+		return entity, true
+	}
+	if _, anchored := g.entities[g.anchor]; !anchored || !scopeIncludesTargets(g.filters.RelationFilter.Scope) || !matchRelatedEntity(entity, g.filters) {
+		return entity, false
+	}
+	return entity, g.related(id)
+}
+
+// related returns whether the entity with the given ID is at the other end of a relation of the anchor
+func (g *subgraph) related(id topoapi.ID) bool {
+	for _, relation := range g.relations {
+		if relatedEntityID(relation, g.inbound) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// relationIDs returns the IDs of the relations of the anchor in order
+func (g *subgraph) relationIDs() []topoapi.ID {
+	ids := make([]topoapi.ID, 0, len(g.relations))
+	for id := range g.relations {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
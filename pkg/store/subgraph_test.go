@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchFilters(t *testing.T) {
+	forEachBackend(t, testWatchFilters)
+}
+
+// watchEvent is the type and object ID of an event
+type watchEvent struct {
+	eventType topo.EventType
+	id        topo.ID
+}
+
+// nextWatchEvents reads the given number of events and then checks that no other event is sent
+func nextWatchEvents(t *testing.T, ch chan topo.Event, n int) []watchEvent {
+	var events []watchEvent
+	for i := 0; i < n; i++ {
+		event := nextWatchEvent(t, ch)
+		events = append(events, watchEvent{event.Type, event.Object.ID})
+	}
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected event %v %s after %v", event.Type, event.Object.ID, events)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return events
+}
+
+func testWatchFilters(t *testing.T, newStore func() Store) {
+	store := newStore()
+	entity := func(id topo.ID, kind topo.ID) *topo.Object {
+		return &topo.Object{ID: id, Type: topo.Object_ENTITY, Obj: &topo.Object_Entity{Entity: &topo.Entity{KindID: kind}}}
+	}
+	relation := func(id topo.ID, kind topo.ID, src topo.ID, tgt topo.ID) *topo.Object {
+		return &topo.Object{
+			ID:   id,
+			Type: topo.Object_RELATION,
+			Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: kind, SrcEntityID: src, TgtEntityID: tgt}},
+		}
+	}
+	for _, object := range []*topo.Object{
+		entity("node10", "e2node"),
+		entity("cell1", "e2cell"),
+		entity("cell2", "e2cell"),
+		entity("node11", "e2node"),
+		relation("node10-cell1", "contains", "node10", "cell1"),
+		relation("node11-cell2", "contains", "node11", "cell2"),
+		relation("node10-node11", "neighbors", "node10", "node11"),
+	} {
+		assert.NoError(t, store.Create(context.TODO(), object))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Watches must only send objects of the requested types
+	entities := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, entities, &topo.Filters{ObjectTypes: []topo.Object_Type{topo.Object_ENTITY}}, WithReplay()))
+	assert.Len(t, nextWatchEvents(t, entities, 4), 4)
+
+	// Watches with a relation filter must replay the same objects as List
+	filters := &topo.Filters{
+		RelationFilter: &topo.RelationFilter{SrcId: "node10", RelationKind: "contains", Scope: topo.RelationFilterScope_ALL},
+	}
+	listed, err := store.List(context.TODO(), filters)
+	assert.NoError(t, err)
+	subgraph := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, subgraph, filters, WithReplay()))
+	events := nextWatchEvents(t, subgraph, len(listed))
+	for i, object := range listed {
+		assert.Equal(t, watchEvent{topo.EventType_NONE, object.ID}, events[i])
+	}
+	targets := make(chan topo.Event)
+	assert.NoError(t, store.Watch(ctx, targets, &topo.Filters{
+		RelationFilter: &topo.RelationFilter{TargetId: "cell2", Scope: topo.RelationFilterScope_TARGETS_ONLY},
+	}))
+
+	// Relations added to the anchor must add them and their target to the subgraph
+	assert.NoError(t, store.Create(context.TODO(), relation("node10-cell2", "contains", "node10", "cell2")))
+	assert.Equal(t, []watchEvent{
+		{topo.EventType_ADDED, "node10-cell2"},
+		{topo.EventType_ADDED, "cell2"},
+	}, nextWatchEvents(t, subgraph, 2))
+	assert.Equal(t, []watchEvent{{topo.EventType_ADDED, "node10"}}, nextWatchEvents(t, targets, 1))
+	nextWatchEvents(t, entities, 0)
+
+	// Changes to members must be sent, and changes to other objects must not
+	cell2, err := store.Get(context.TODO(), "cell2")
+	assert.NoError(t, err)
+	cell2.Labels = map[string]string{"band": "n78"}
+	assert.NoError(t, store.Update(context.TODO(), cell2))
+	assert.Equal(t, []watchEvent{{topo.EventType_UPDATED, "cell2"}}, nextWatchEvents(t, subgraph, 1))
+	assert.Equal(t, []watchEvent{{topo.EventType_UPDATED, "cell2"}}, nextWatchEvents(t, entities, 1))
+	node11, err := store.Get(context.TODO(), "node11")
+	assert.NoError(t, err)
+	node11.Labels = map[string]string{"pci": "7"}
+	assert.NoError(t, store.Update(context.TODO(), node11))
+	nextWatchEvents(t, entities, 1)
+	nextWatchEvents(t, subgraph, 0)
+	nextWatchEvents(t, targets, 1)
+
+	// Relations removed from the anchor must remove them and their target from the subgraph
+	assert.NoError(t, store.Delete(context.TODO(), "node10-cell1", 0))
+	assert.Equal(t, []watchEvent{
+		{topo.EventType_REMOVED, "node10-cell1"},
+		{topo.EventType_REMOVED, "cell1"},
+	}, nextWatchEvents(t, subgraph, 2))
+	nextWatchEvents(t, entities, 0)
+
+	// The subgraph must still match List
+	listed, err = store.List(context.TODO(), filters)
+	assert.NoError(t, err)
+	ids := make([]topo.ID, 0, len(listed))
+	for _, object := range listed {
+		ids = append(ids, object.ID)
+	}
+	assert.ElementsMatch(t, []topo.ID{"node10", "node10-cell2", "cell2"}, ids)
+
+	// Relations of another kind must be ignored
+	assert.NoError(t, store.Create(context.TODO(), relation("node10-cell1-neighbors", "neighbors", "node10", "cell1")))
+	nextWatchEvents(t, subgraph, 0)
+
+	// Deleting the anchor must remove the whole subgraph
+	assert.NoError(t, store.Delete(context.TODO(), "node10", 0))
+	events = nextWatchEvents(t, subgraph, 3)
+	assert.ElementsMatch(t, []watchEvent{
+		{topo.EventType_REMOVED, "node10"},
+		{topo.EventType_REMOVED, "node10-cell2"},
+		{topo.EventType_REMOVED, "cell2"},
+	}, events)
+
+	// Relation filters without an anchor must be rejected
+	err = store.Watch(ctx, make(chan topo.Event), &topo.Filters{RelationFilter: &topo.RelationFilter{RelationKind: "contains"}})
+	assert.True(t, errors.IsInvalid(err))
+
+	// Subgraphs must not be resumed from the journal
+	err = store.Watch(ctx, make(chan topo.Event), filters, WithResumeAfter(cell2.Revision))
+	assert.True(t, errors.IsConflict(err))
+}
+
+func TestSubgraphSnapshot(t *testing.T) {
+	entity := func(id topo.ID, revision topo.Revision, labels map[string]string) topo.Object {
+		return topo.Object{ID: id, Type: topo.Object_ENTITY, Revision: revision, Labels: labels,
+			Obj: &topo.Object_Entity{Entity: &topo.Entity{KindID: "e2cell"}}}
+	}
+	relation := func(id topo.ID, revision topo.Revision, tgt topo.ID) topo.Object {
+		return topo.Object{ID: id, Type: topo.Object_RELATION, Revision: revision,
+			Obj: &topo.Object_Relation{Relation: &topo.Relation{KindID: "contains", SrcEntityID: "node10", TgtEntityID: tgt}}}
+	}
+	// The store is ahead of the events of the watch
+	stored := map[topo.ID]topo.Object{
+		"cell1": entity("cell1", 3, map[string]string{"band": "n78"}),
+		"cell3": entity("cell3", 9, nil),
+	}
+	var reads []topo.ID
+	get := func(id topo.ID) (*topo.Object, error) {
+		reads = append(reads, id)
+		object, ok := stored[id]
+		if !ok {
+			return nil, errors.NewNotFound("%s not found", id)
+		}
+		return &object, nil
+	}
+	graph, err := newSubgraph(&topo.Filters{
+		RelationFilter: &topo.RelationFilter{SrcId: "node10", RelationKind: "contains", Scope: topo.RelationFilterScope_TARGETS_ONLY},
+	}, get)
+	assert.NoError(t, err)
+	assert.Empty(t, graph.seed([]topo.Object{entity("node10", 1, nil), entity("cell1", 2, nil), entity("cell4", 2, nil)}))
+
+	// Only the anchor and the entities linked to it must be tracked
+	assert.Len(t, graph.entities, 1)
+	assert.Empty(t, graph.apply(topo.Event{Type: topo.EventType_UPDATED, Object: entity("cell4", 3, nil)}))
+	assert.Len(t, graph.entities, 1)
+
+	// Entities linked to the anchor must be read from the store, and their events already reflected in the state
+	// read must be discarded
+	assert.Empty(t, graph.apply(topo.Event{Type: topo.EventType_UPDATED, Object: entity("cell1", 3, map[string]string{"band": "n78"})}))
+	events := graph.apply(topo.Event{Type: topo.EventType_ADDED, Object: relation("node10-cell1", 4, "cell1")})
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, topo.EventType_ADDED, events[0].Type)
+		assert.Equal(t, entity("cell1", 3, map[string]string{"band": "n78"}), events[0].Object)
+	}
+	assert.Equal(t, []topo.ID{"cell1"}, reads)
+
+	events = graph.apply(topo.Event{Type: topo.EventType_ADDED, Object: relation("node10-cell3", 5, "cell3")})
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, topo.Revision(9), events[0].Object.Revision)
+	}
+	assert.Empty(t, graph.apply(topo.Event{Type: topo.EventType_UPDATED, Object: entity("cell3", 8, nil)}))
+	events = graph.apply(topo.Event{Type: topo.EventType_UPDATED, Object: entity("cell3", 10, nil)})
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, topo.EventType_UPDATED, events[0].Type)
+	}
+
+	// Entities not found must join the subgraph with their own event
+	assert.Empty(t, graph.apply(topo.Event{Type: topo.EventType_ADDED, Object: relation("node10-cell2", 6, "cell2")}))
+	events = graph.apply(topo.Event{Type: topo.EventType_ADDED, Object: entity("cell2", 7, nil)})
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, topo.EventType_ADDED, events[0].Type)
+		assert.Equal(t, topo.ID("cell2"), events[0].Object.ID)
+	}
+
+	// Entities unlinked from the anchor must be forgotten
+	events = graph.apply(topo.Event{Type: topo.EventType_REMOVED, Object: relation("node10-cell1", 4, "cell1")})
+	assert.Len(t, events, 1)
+	assert.NotContains(t, graph.entities, topo.ID("cell1"))
+	assert.Len(t, graph.entities, 3)
+}
@@ -0,0 +1,243 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// OperationType is the type of change made by an operation in a transaction
+type OperationType int
+
+const (
+	// OperationCreate creates an object
+	OperationCreate OperationType = iota
+	// OperationUpdate updates an existing object if its revision matches
+	OperationUpdate
+	// OperationDelete deletes an object if its revision matches or is zero, along with the relations of an entity
+	OperationDelete
+)
+
+// Operation is a change to a single object in a transaction
+type Operation struct {
+	// Type is the type of change
+	Type OperationType
+	// Object is the object to create or update, or the ID and revision of the object to delete. Once the
+	// transaction has been committed, the revision of created and updated objects is set to their new revision.
+	Object *topoapi.Object
+}
+
+// CreateOperation returns an operation creating the given object
+func CreateOperation(object *topoapi.Object) Operation {
+	return Operation{Type: OperationCreate, Object: object}
+}
+
+// UpdateOperation returns an operation updating the given object
+func UpdateOperation(object *topoapi.Object) Operation {
+	return Operation{Type: OperationUpdate, Object: object}
+}
+
+// DeleteOperation returns an operation deleting the object with the given ID; if the revision is non-zero,
+// the object is only deleted at that revision
+func DeleteOperation(id topoapi.ID, revision topoapi.Revision) Operation {
+	return Operation{Type: OperationDelete, Object: &topoapi.Object{ID: id, Revision: revision}}
+}
+
+// TransactionGroupAspect is the type of the aspect added to the watch events of the changes made by a transaction;
+// its value is the JSON encoding of the TransactionGroup of the event
+const TransactionGroupAspect = "onos.topo.TransactionGroup"
+
+// TransactionGroup marks the watch events of the changes made by a transaction. The events of a transaction are
+// sent to a watcher contiguously, and are never coalesced with the events of other changes, so that the watcher
+// observes the transaction as a whole once it receives the event whose Index is Count-1.
+type TransactionGroup struct {
+	// ID is the unique identifier of the transaction
+	ID string `json:"id"`
+	// Index is the position of the event among the events of the transaction sent to the watcher
+	Index int `json:"index"`
+	// Count is the number of events of the transaction sent to the watcher, i.e. of its changes matching the filters
+	// of the watch
+	Count int `json:"count"`
+}
+
+// GetTransactionGroup returns the transaction group of an event object, or nil if the object does not have the
+// TransactionGroupAspect
+func GetTransactionGroup(object *topoapi.Object) (*TransactionGroup, error) {
+	aspect, ok := object.Aspects[TransactionGroupAspect]
+	if !ok || aspect == nil {
+		return nil, nil
+	}
+	group := &TransactionGroup{}
+	if err := json.Unmarshal(aspect.Value, group); err != nil {
+		return nil, errors.NewInvalid("invalid %s aspect: %v", TransactionGroupAspect, err)
+	}
+	return group, nil
+}
+
+// withTransactionGroup returns the given event marked with the given transaction group
+func withTransactionGroup(event topoapi.Event, group TransactionGroup) topoapi.Event {
+	value, err := json.Marshal(group)
+	if err != nil {
+		log.Warnf("Failed to mark the event of Object %s with transaction %s: %v", event.Object.ID, group.ID, err)
+		return event
+	}
+	return withAspect(event, TransactionGroupAspect, value)
+}
+
+// transaction is a validated list of operations
+type transaction struct {
+	operations []Operation
+	// objects maps the ID of each object changed by the transaction to its operation
+	objects map[topoapi.ID]*Operation
+}
+
+// newTransaction validates the given operations and prepares the objects to create
+func newTransaction(operations []Operation) (*transaction, error) {
+	if len(operations) == 0 {
+		return nil, errors.NewInvalid("transaction must contain at least one operation")
+	}
+	tx := &transaction{
+		operations: make([]Operation, 0, len(operations)),
+		objects:    make(map[topoapi.ID]*Operation, len(operations)),
+	}
+	for _, op := range operations {
+		object := op.Object
+		if object == nil {
+			return nil, errors.NewInvalid("transaction operations must contain an object")
+		}
+		switch op.Type {
+		case OperationCreate:
+			if object.Type == topoapi.Object_UNSPECIFIED {
+				return nil, errors.NewInvalid("Type cannot be unspecified")
+			}
+			uuid, err := uuid.NewRandom()
+			if err != nil {
+				return nil, errors.NewInternal(err.Error())
+			}
+			object.UUID = topoapi.UUID(uuid.String())
+			// If an object is a relation and its ID is empty, build one.
+			if object.Type == topoapi.Object_RELATION && object.ID == "" {
+				object.ID = topoapi.ID("uuid:" + string(object.UUID))
+			}
+			if object.ID == "" {
+				return nil, errors.NewInvalid("ID cannot be empty")
+			}
+		case OperationUpdate:
+			if object.ID == "" {
+				return nil, errors.NewInvalid("ID cannot be empty")
+			}
+			if object.Type == topoapi.Object_UNSPECIFIED {
+				return nil, errors.NewInvalid("Type cannot be unspecified")
+			}
+			if object.Revision == 0 {
+				return nil, errors.NewInvalid("object must contain a revision on update")
+			}
+		case OperationDelete:
+			if object.ID == "" {
+				return nil, errors.NewInvalid("ID cannot be empty")
+			}
+		default:
+			return nil, errors.NewInvalid("unknown operation type %d", op.Type)
+		}
+		if op.Type != OperationDelete {
+			if err := checkReserved(object); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := tx.objects[object.ID]; ok {
+			return nil, errors.NewInvalid("Object '%s' is changed more than once in the transaction", object.ID)
+		}
+		tx.operations = append(tx.operations, op)
+		tx.objects[object.ID] = &tx.operations[len(tx.operations)-1]
+	}
+	return tx, nil
+}
+
+// deletes returns the IDs of the objects deleted by the operations of the transaction
+func (t *transaction) deletes() []topoapi.ID {
+	var ids []topoapi.ID
+	for _, op := range t.operations {
+		if op.Type == OperationDelete {
+			ids = append(ids, op.Object.ID)
+		}
+	}
+	return ids
+}
+
+// cascade adds the deletion of the given relations of an entity deleted by the transaction. The relations
+// are deleted before the entity, and must not be changed by other operations of the transaction.
+func (t *transaction) cascade(entityID topoapi.ID, relationIDs []topoapi.ID) error {
+	var cascaded []Operation
+	for _, id := range relationIDs {
+		if op, ok := t.objects[id]; ok {
+			if op.Type != OperationDelete {
+				return errors.NewInvalid("Relation '%s' of deleted Entity '%s' cannot be changed", id, entityID)
+			}
+			continue
+		}
+		cascaded = append(cascaded, DeleteOperation(id, 0))
+	}
+	if len(cascaded) == 0 {
+		return nil
+	}
+
+	operations := make([]Operation, 0, len(t.operations)+len(cascaded))
+	for _, op := range t.operations {
+		if op.Type == OperationDelete && op.Object.ID == entityID {
+			operations = append(operations, cascaded...)
+		}
+		operations = append(operations, op)
+	}
+	t.operations = operations
+	for i := range t.operations {
+		t.objects[t.operations[i].Object.ID] = &t.operations[i]
+	}
+	return nil
+}
+
+// validateRelations checks that the endpoints of the relations created or updated by the transaction are
+// either created by the transaction or exist in the store and are not deleted by the transaction. The existence
+// of the endpoints of updated relations is only checked if the unchanged function reports that they changed.
+func (t *transaction) validateRelations(exists func(id topoapi.ID) (bool, error), unchanged func(object *topoapi.Object) bool) error {
+	for _, op := range t.operations {
+		if op.Type == OperationDelete || op.Object.Type != topoapi.Object_RELATION {
+			continue
+		}
+		relation := op.Object.GetRelation()
+		if relation == nil {
+			return errors.NewInvalid("Relation '%s' must have a source and a target", op.Object.ID)
+		}
+		for _, endpoint := range []struct {
+			id   topoapi.ID
+			name string
+		}{{relation.SrcEntityID, "Source"}, {relation.TgtEntityID, "Target"}} {
+			if other, ok := t.objects[endpoint.id]; ok {
+				if other.Type == OperationDelete {
+					return errors.NewInvalid("%s Entity is deleted by the transaction", endpoint.name)
+				}
+				if other.Type == OperationCreate {
+					continue
+				}
+			}
+			if op.Type == OperationUpdate && unchanged(op.Object) {
+				continue
+			}
+			ok, err := exists(endpoint.id)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				log.Warnf("%s Entity does not exist", endpoint.name)
+				return errors.NewInvalid("%s Entity does not exist", endpoint.name)
+			}
+		}
+	}
+	return nil
+}
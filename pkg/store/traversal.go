@@ -0,0 +1,453 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// MaxTraversalDepth is the highest number of hops a traversal can follow, summed over the maximum depths of its steps
+const MaxTraversalDepth = 16
+
+// MaxTraversalResults is the highest number of objects a traversal can stream, and of entities each of its steps
+// can reach
+const MaxTraversalResults = 10000
+
+// traversalVisitsPerResult is the number of entities a traversal can visit for each object it can stream, bounding
+// the cost of traversals that visit much of the graph to reach few entities
+const traversalVisitsPerResult = 10
+
+// ErrTraversalTruncated is returned by Traverse when a traversal stops at its limits, once the objects it reached
+// until then have been streamed
+var ErrTraversalTruncated = errors.NewInvalid("traversal exceeds its limits")
+
+// Direction is the direction in which a traversal follows relations
+type Direction int
+
+const (
+	// DirectionOutbound follows relations from their source entity to their target entity
+	DirectionOutbound Direction = iota
+	// DirectionInbound follows relations from their target entity to their source entity
+	DirectionInbound
+	// DirectionBoth follows relations in both directions
+	DirectionBoth
+)
+
+// String returns the name of the direction
+func (d Direction) String() string {
+	switch d {
+	case DirectionOutbound:
+		return "outbound"
+	case DirectionInbound:
+		return "inbound"
+	case DirectionBoth:
+		return "both"
+	}
+	return "unknown"
+}
+
+// ParseDirection returns the direction with the given name
+func ParseDirection(name string) (Direction, error) {
+	switch name {
+	case DirectionOutbound.String():
+		return DirectionOutbound, nil
+	case DirectionInbound.String():
+		return DirectionInbound, nil
+	case DirectionBoth.String():
+		return DirectionBoth, nil
+	}
+	return DirectionOutbound, errors.NewInvalid("unknown direction '%s'", name)
+}
+
+// MarshalText encodes the direction as its name
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes the direction from its name
+func (d *Direction) UnmarshalText(text []byte) error {
+	direction, err := ParseDirection(string(text))
+	if err != nil {
+		return err
+	}
+	*d = direction
+	return nil
+}
+
+// TraversalStep is a step of a traversal: it follows relations of a kind from the entities reached by the
+// previous step, and reaches the entities found between MinDepth and MaxDepth hops away. If MaxDepth is zero,
+// the step follows a single hop; otherwise a MinDepth of zero includes the entities the step starts from.
+type TraversalStep struct {
+	// RelationKind is the kind of the relations to follow; relations of any kind are followed if empty
+	RelationKind topoapi.ID `json:"relationKind,omitempty"`
+	// Direction is the direction in which relations are followed
+	Direction Direction `json:"direction,omitempty"`
+	// MinDepth is the minimum number of hops to the entities reached by the step
+	MinDepth int `json:"minDepth,omitempty"`
+	// MaxDepth is the maximum number of hops to the entities reached by the step
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// TargetKind is the kind of the entities reached by the step; entities of any kind are reached if empty.
+	// Entities that do not match the kind or labels of the step are still followed to the next hop.
+	TargetKind topoapi.ID `json:"targetKind,omitempty"`
+	// Labels are the labels the entities reached by the step must have
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// depths returns the effective minimum and maximum depths of the step
+func (s TraversalStep) depths() (int, int) {
+	if s.MaxDepth == 0 {
+		return 1, 1
+	}
+	return s.MinDepth, s.MaxDepth
+}
+
+// matches returns whether the given entity is reached by the step
+func (s TraversalStep) matches(entity *topoapi.Object) bool {
+	if s.TargetKind != topoapi.NullID && entity.GetEntity().GetKindID() != s.TargetKind {
+		return false
+	}
+	for key, value := range s.Labels {
+		if actual, ok := entity.Labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// relationIDs returns the IDs of the relations of the given entity followed by the step
+func (s TraversalStep) relationIDs(entity *topoapi.Object) []topoapi.ID {
+	switch s.Direction {
+	case DirectionOutbound:
+		return entity.GetEntity().GetSrcRelationIDs()
+	case DirectionInbound:
+		return entity.GetEntity().GetTgtRelationIDs()
+	}
+	relationIDs := append([]topoapi.ID{}, entity.GetEntity().GetSrcRelationIDs()...)
+	sources := make(idSet, len(relationIDs))
+	for _, id := range relationIDs {
+		sources[id] = struct{}{}
+	}
+	for _, id := range entity.GetEntity().GetTgtRelationIDs() {
+		if _, ok := sources[id]; !ok {
+			relationIDs = append(relationIDs, id)
+		}
+	}
+	return relationIDs
+}
+
+// Traversal is a multi-hop query following relations from a start entity through a sequence of steps
+type Traversal struct {
+	// Start is the ID of the entity the traversal starts from
+	Start topoapi.ID `json:"start"`
+	// Steps are the steps of the traversal; each step starts from the entities reached by the previous step
+	Steps []TraversalStep `json:"steps"`
+	// WithRelations includes the relations of the paths from the start entity to the reached entities
+	WithRelations bool `json:"withRelations,omitempty"`
+	// Limit is the highest number of objects the traversal can stream, and of entities each of its steps can reach,
+	// up to MaxTraversalResults; it is MaxTraversalResults if zero. The traversal visits at most ten times as many
+	// entities, counting an entity once for each entity a step starts from. A traversal exceeding these limits is
+	// truncated.
+	Limit int `json:"limit,omitempty"`
+}
+
+func (t Traversal) validate() error {
+	if t.Start == topoapi.NullID {
+		return errors.NewInvalid("traversal must have a start entity")
+	}
+	if len(t.Steps) == 0 {
+		return errors.NewInvalid("traversal must contain at least one step")
+	}
+	if t.Limit < 0 || t.Limit > MaxTraversalResults {
+		return errors.NewInvalid("traversal limit must be between 0 and %d", MaxTraversalResults)
+	}
+	hops := 0
+	for i, step := range t.Steps {
+		if step.Direction < DirectionOutbound || step.Direction > DirectionBoth {
+			return errors.NewInvalid("step %d has an unknown direction %d", i, step.Direction)
+		}
+		if step.MinDepth < 0 || step.MaxDepth < 0 {
+			return errors.NewInvalid("step %d depths cannot be negative", i)
+		}
+		if step.MaxDepth > 0 && step.MinDepth > step.MaxDepth {
+			return errors.NewInvalid("step %d minimum depth %d exceeds its maximum depth %d", i, step.MinDepth, step.MaxDepth)
+		}
+		_, maxDepth := step.depths()
+		if hops += maxDepth; hops > MaxTraversalDepth {
+			return errors.NewInvalid("traversal cannot follow more than %d hops", MaxTraversalDepth)
+		}
+	}
+	return nil
+}
+
+// limit returns the effective limit of the traversal
+func (t Traversal) limit() int {
+	if t.Limit == 0 {
+		return MaxTraversalResults
+	}
+	return t.Limit
+}
+
+// hop is a relation followed by a traversal step to reach an entity at a depth
+type hop struct {
+	relation *topoapi.Object
+	from     topoapi.ID
+	depth    int
+}
+
+// traversedStep records the entities a traversal step started from and the hops to each entity it reached
+type traversedStep struct {
+	origins  idSet
+	hops     map[topoapi.ID][]hop
+	minDepth int
+}
+
+// position is an entity at a depth of a traversal step
+type position struct {
+	id    topoapi.ID
+	depth int
+}
+
+// traverse sends the entities reached by the given traversal as the last step reaches them, followed by the
+// relations of the paths to them if requested. Each step reaches an entity at the lowest number of hops from each
+// entity it starts from. A traversal exceeding its limits stops with ErrTraversalTruncated.
+func traverse(ctx context.Context, get getFunc, traversal Traversal, send func(*topoapi.Object) error, opts ...ReadOption) error {
+	if err := traversal.validate(); err != nil {
+		return err
+	}
+	get = cachedGet(get)
+	start, err := get(ctx, traversal.Start, opts...)
+	if err != nil {
+		return err
+	}
+	if start.Type != topoapi.Object_ENTITY {
+		return errors.NewInvalid("traversal must start from an entity")
+	}
+
+	limit := traversal.limit()
+	sent := 0
+	emit := func(object *topoapi.Object) error {
+		if sent == limit {
+			log.Debugf("Traversal from '%s' reaches more than %d objects", traversal.Start, limit)
+			return ErrTraversalTruncated
+		}
+		sent++
+		return send(object)
+	}
+	visits := 0
+	visit := func() error {
+		if visits == limit*traversalVisitsPerResult {
+			log.Debugf("Traversal from '%s' visits more than %d entities", traversal.Start, visits)
+			return ErrTraversalTruncated
+		}
+		visits++
+		return nil
+	}
+
+	reached := []*topoapi.Object{start}
+	steps := make([]*traversedStep, 0, len(traversal.Steps))
+	for i, step := range traversal.Steps {
+		last := i == len(traversal.Steps)-1
+		var next []*topoapi.Object
+		traversed, err := traverseStep(ctx, get, reached, step, visit, func(entity *topoapi.Object) error {
+			if len(next) == limit {
+				log.Debugf("Traversal from '%s' step %d reaches more than %d entities", traversal.Start, i, limit)
+				return ErrTraversalTruncated
+			}
+			next = append(next, entity)
+			if last {
+				return emit(entity)
+			}
+			return nil
+		}, opts...)
+		if err != nil {
+			return err
+		}
+		reached = next
+		steps = append(steps, traversed)
+	}
+	if !traversal.WithRelations {
+		return nil
+	}
+
+	// Walk back from the reached entities through the hops of each step to find the relations of their paths
+	relationIDs := make(idSet)
+	targets := make([]topoapi.ID, 0, len(reached))
+	for _, entity := range reached {
+		targets = append(targets, entity.ID)
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		var origins []topoapi.ID
+		addOrigin := make(idSet)
+		var queue []position
+		for _, id := range targets {
+			if _, ok := step.origins[id]; ok && step.minDepth == 0 {
+				queue = append(queue, position{id: id})
+			}
+			for _, h := range step.hops[id] {
+				if h.depth >= step.minDepth {
+					queue = append(queue, position{id: id, depth: h.depth})
+				}
+			}
+		}
+		visited := make(map[position]bool)
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			if visited[p] {
+				continue
+			}
+			visited[p] = true
+			if p.depth == 0 {
+				if _, ok := addOrigin[p.id]; !ok {
+					addOrigin[p.id] = struct{}{}
+					origins = append(origins, p.id)
+				}
+				continue
+			}
+			for _, h := range step.hops[p.id] {
+				if h.depth != p.depth {
+					continue
+				}
+				if _, ok := relationIDs[h.relation.ID]; !ok {
+					relationIDs[h.relation.ID] = struct{}{}
+					if err := emit(h.relation); err != nil {
+						return err
+					}
+				}
+				queue = append(queue, position{id: h.from, depth: p.depth - 1})
+			}
+		}
+		targets = origins
+	}
+	return nil
+}
+
+// streamTraversal streams the objects reached by the given traversal to the given channel as they are reached
+func streamTraversal(ctx context.Context, get getFunc, ch chan<- *topoapi.Object, traversal Traversal, opts ...ReadOption) error {
+	return traverse(ctx, get, traversal, func(object *topoapi.Object) error {
+		select {
+		case ch <- object:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, opts...)
+}
+
+// cachedGet returns a getFunc that retrieves each object once
+func cachedGet(get getFunc) getFunc {
+	objects := make(map[topoapi.ID]*topoapi.Object)
+	return func(ctx context.Context, id topoapi.ID, opts ...ReadOption) (*topoapi.Object, error) {
+		if object, ok := objects[id]; ok {
+			return object, nil
+		}
+		object, err := get(ctx, id, opts...)
+		if err != nil {
+			return nil, err
+		}
+		objects[id] = object
+		return object, nil
+	}
+}
+
+// traverseStep follows the relations of a step breadth-first from each of the given entities, calling visit before
+// visiting each entity from each of them, and reach with each entity reached by the step the first time it is visited
+func traverseStep(ctx context.Context, get getFunc, from []*topoapi.Object, step TraversalStep, visit func() error, reach func(*topoapi.Object) error, opts ...ReadOption) (*traversedStep, error) {
+	minDepth, maxDepth := step.depths()
+	traversed := &traversedStep{
+		origins:  make(idSet, len(from)),
+		hops:     make(map[topoapi.ID][]hop),
+		minDepth: minDepth,
+	}
+	for _, entity := range from {
+		traversed.origins[entity.ID] = struct{}{}
+	}
+
+	reachedIDs := make(idSet)
+	reachOnce := func(entity *topoapi.Object) error {
+		if _, ok := reachedIDs[entity.ID]; ok || !step.matches(entity) {
+			return nil
+		}
+		reachedIDs[entity.ID] = struct{}{}
+		return reach(entity)
+	}
+
+	for _, origin := range from {
+		level := []*topoapi.Object{origin}
+		visited := idSet{origin.ID: struct{}{}}
+		if minDepth == 0 {
+			if err := reachOnce(origin); err != nil {
+				return nil, err
+			}
+		}
+		for depth := 1; depth <= maxDepth && len(level) > 0; depth++ {
+			var next []*topoapi.Object
+			discovered := make(idSet)
+			for _, entity := range level {
+				for _, relationID := range step.relationIDs(entity) {
+					relation, err := get(ctx, relationID, opts...)
+					if err != nil {
+						if errors.IsNotFound(err) {
+							continue
+						}
+						return nil, err
+					}
+					rel := relation.GetRelation()
+					if rel == nil || (step.RelationKind != topoapi.NullID && rel.KindID != step.RelationKind) {
+						continue
+					}
+					otherID := rel.TgtEntityID
+					if rel.TgtEntityID == entity.ID && (step.Direction == DirectionInbound || rel.SrcEntityID != entity.ID) {
+						otherID = rel.SrcEntityID
+					}
+
+					// Entities reached at a lower depth are not reached again
+					if _, ok := visited[otherID]; ok {
+						if _, ok := discovered[otherID]; ok {
+							traversed.addHop(otherID, hop{relation: relation, from: entity.ID, depth: depth})
+						}
+						continue
+					}
+					if err := visit(); err != nil {
+						return nil, err
+					}
+					other, err := get(ctx, otherID, opts...)
+					if err != nil {
+						if errors.IsNotFound(err) {
+							continue
+						}
+						return nil, err
+					}
+					visited[otherID] = struct{}{}
+					discovered[otherID] = struct{}{}
+					traversed.addHop(otherID, hop{relation: relation, from: entity.ID, depth: depth})
+					next = append(next, other)
+					if depth >= minDepth {
+						if err := reachOnce(other); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+			level = next
+		}
+	}
+	return traversed, nil
+}
+
+// addHop records a hop to reach an entity, unless it has already been recorded
+func (t *traversedStep) addHop(id topoapi.ID, h hop) {
+	for _, recorded := range t.hops[id] {
+		if recorded.relation.ID == h.relation.ID && recorded.from == h.from && recorded.depth == h.depth {
+			return
+		}
+	}
+	t.hops[id] = append(t.hops[id], h)
+}
@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraversal(t *testing.T) {
+	forEachBackend(t, testTraversal)
+}
+
+// createFabric creates two pods containing switches, which contain ports linked to each other:
+//
+//	pod-1 -> sw-1 -> port-1-1 (speed=100G), port-1-2
+//	      -> sw-2 -> port-2-1 (speed=100G)
+//	pod-2 -> sw-3 -> port-3-1
+//	port-1-1 -link-> port-2-1 -link-> port-3-1
+func createFabric(t *testing.T, store Store) {
+	for id, kind := range map[topo.ID]topo.ID{
+		"pod-1": "pod", "pod-2": "pod",
+		"sw-1": "switch", "sw-2": "switch", "sw-3": "switch",
+		"port-1-1": "port", "port-1-2": "port", "port-2-1": "port", "port-3-1": "port",
+	} {
+		var labels map[string]string
+		if id == "port-1-1" || id == "port-2-1" {
+			labels = map[string]string{"speed": "100G"}
+		}
+		err := store.Create(context.TODO(), &topo.Object{
+			ID:     id,
+			Type:   topo.Object_ENTITY,
+			Obj:    &topo.Object_Entity{Entity: &topo.Entity{KindID: kind}},
+			Labels: labels,
+		})
+		assert.NoError(t, err)
+	}
+	for _, r := range []struct{ id, kind, src, tgt topo.ID }{
+		{"pod-1-sw-1", "contains", "pod-1", "sw-1"},
+		{"pod-1-sw-2", "contains", "pod-1", "sw-2"},
+		{"pod-2-sw-3", "contains", "pod-2", "sw-3"},
+		{"sw-1-port-1-1", "contains", "sw-1", "port-1-1"},
+		{"sw-1-port-1-2", "contains", "sw-1", "port-1-2"},
+		{"sw-2-port-2-1", "contains", "sw-2", "port-2-1"},
+		{"sw-3-port-3-1", "contains", "sw-3", "port-3-1"},
+		{"link-1", "link", "port-1-1", "port-2-1"},
+		{"link-2", "link", "port-2-1", "port-3-1"},
+	} {
+		err := store.Create(context.TODO(), &topo.Object{
+			ID:   r.id,
+			Type: topo.Object_RELATION,
+			Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: r.kind, SrcEntityID: r.src, TgtEntityID: r.tgt}},
+		})
+		assert.NoError(t, err)
+	}
+	waitForRelations(t, store, "port-2-1", 1, 2)
+	waitForRelations(t, store, "pod-1", 2, 0)
+	waitForRelations(t, store, "sw-1", 2, 1)
+	waitForRelations(t, store, "sw-2", 1, 1)
+	waitForRelations(t, store, "sw-3", 1, 1)
+}
+
+// traverseIDs returns the sorted IDs of the objects reached by the given traversal
+func traverseIDs(t *testing.T, store Store, traversal Traversal) ([]topo.ID, error) {
+	ch := make(chan *topo.Object)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- store.Traverse(context.TODO(), ch, traversal)
+	}()
+	ids := make([]topo.ID, 0)
+	for object := range ch {
+		ids = append(ids, object.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	select {
+	case err := <-errCh:
+		return ids, err
+	case <-time.After(5 * time.Second):
+		t.FailNow()
+	}
+	return nil, nil
+}
+
+func testTraversal(t *testing.T, newStore func() Store) {
+	store := newStore()
+	createFabric(t, store)
+
+	// Each step must start from the entities reached by the previous step
+	ids, err := traverseIDs(t, store, Traversal{
+		Start: "pod-1",
+		Steps: []TraversalStep{
+			{RelationKind: "contains", TargetKind: "switch"},
+			{RelationKind: "contains", TargetKind: "port"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"port-1-1", "port-1-2", "port-2-1"}, ids)
+
+	// Repeated steps must reach the entities between their minimum and maximum depth
+	ids, err = traverseIDs(t, store, Traversal{
+		Start: "pod-1",
+		Steps: []TraversalStep{{RelationKind: "contains", MinDepth: 1, MaxDepth: 3, TargetKind: "port"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"port-1-1", "port-1-2", "port-2-1"}, ids)
+	ids, err = traverseIDs(t, store, Traversal{
+		Start: "pod-1",
+		Steps: []TraversalStep{{MinDepth: 0, MaxDepth: 1}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"pod-1", "sw-1", "sw-2"}, ids)
+
+	// Inbound steps must follow relations from their target
+	ids, err = traverseIDs(t, store, Traversal{
+		Start: "port-3-1",
+		Steps: []TraversalStep{{RelationKind: "contains", Direction: DirectionInbound, MinDepth: 2, MaxDepth: 5}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"pod-2"}, ids)
+
+	// Steps in both directions must reach each entity once, at its lowest depth
+	ids, err = traverseIDs(t, store, Traversal{
+		Start: "port-2-1",
+		Steps: []TraversalStep{{RelationKind: "link", Direction: DirectionBoth, MinDepth: 1, MaxDepth: 2}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"port-1-1", "port-3-1"}, ids)
+
+	// Entities reached by a step may also be entities the step starts from
+	ids, err = traverseIDs(t, store, Traversal{
+		Start: "pod-1",
+		Steps: []TraversalStep{
+			{RelationKind: "contains", MinDepth: 2, MaxDepth: 2},
+			{RelationKind: "link"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"port-2-1", "port-3-1"}, ids)
+
+	// Labels must filter the reached entities, and the relations of the paths to them must be included if requested
+	ids, err = traverseIDs(t, store, Traversal{
+		Start:         "pod-1",
+		Steps:         []TraversalStep{{RelationKind: "contains", MinDepth: 2, MaxDepth: 2, Labels: map[string]string{"speed": "100G"}}},
+		WithRelations: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"pod-1-sw-1", "pod-1-sw-2", "port-1-1", "port-2-1", "sw-1-port-1-1", "sw-2-port-2-1"}, ids)
+	ids, err = traverseIDs(t, store, Traversal{
+		Start: "sw-1",
+		Steps: []TraversalStep{
+			{RelationKind: "contains", Labels: map[string]string{"speed": "100G"}},
+			{RelationKind: "link", MinDepth: 2, MaxDepth: 2},
+		},
+		WithRelations: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []topo.ID{"link-1", "link-2", "port-3-1", "sw-1-port-1-1"}, ids)
+
+	// Invalid traversals must be rejected
+	_, err = traverseIDs(t, store, Traversal{Start: "pod-1"})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = traverseIDs(t, store, Traversal{Start: "pod-1", Steps: []TraversalStep{{MinDepth: 3, MaxDepth: 2}}})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = traverseIDs(t, store, Traversal{Start: "link-1", Steps: []TraversalStep{{}}})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = traverseIDs(t, store, Traversal{Start: "pod-3", Steps: []TraversalStep{{}}})
+	assert.True(t, errors.IsNotFound(err))
+
+	// Traversals must not follow more hops than the limit
+	_, err = traverseIDs(t, store, Traversal{Start: "pod-1", Steps: []TraversalStep{{MaxDepth: MaxTraversalDepth + 1}}})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = traverseIDs(t, store, Traversal{Start: "pod-1", Steps: []TraversalStep{{MaxDepth: MaxTraversalDepth}, {}}})
+	assert.True(t, errors.IsInvalid(err))
+	_, err = traverseIDs(t, store, Traversal{Start: "pod-1", Steps: []TraversalStep{{}}, Limit: MaxTraversalResults + 1})
+	assert.True(t, errors.IsInvalid(err))
+
+	// Traversals streaming more objects than their limit must be truncated
+	ids, err = traverseIDs(t, store, Traversal{Start: "pod-1", Steps: []TraversalStep{{MinDepth: 2, MaxDepth: 2}}, Limit: 3})
+	assert.NoError(t, err)
+	assert.Len(t, ids, 3)
+	ids, err = traverseIDs(t, store, Traversal{Start: "pod-1", Steps: []TraversalStep{{MinDepth: 2, MaxDepth: 2}}, Limit: 2})
+	assert.Equal(t, ErrTraversalTruncated, err)
+	assert.Len(t, ids, 2)
+	ids, err = traverseIDs(t, store, Traversal{Start: "pod-1", Steps: []TraversalStep{{MinDepth: 2, MaxDepth: 2}}, Limit: 3, WithRelations: true})
+	assert.Equal(t, ErrTraversalTruncated, err)
+	assert.Len(t, ids, 3)
+	ids, err = traverseIDs(t, store, Traversal{Start: "pod-1", Steps: []TraversalStep{{MinDepth: 1, MaxDepth: 2}, {MaxDepth: 1}}, Limit: 4})
+	assert.Equal(t, ErrTraversalTruncated, err)
+	assert.Empty(t, ids)
+
+	// Objects must be streamed as they are reached, rather than once the traversal is complete
+	gets := 0
+	get := func(ctx context.Context, id topo.ID, opts ...ReadOption) (*topo.Object, error) {
+		gets++
+		return store.Get(ctx, id, opts...)
+	}
+	stopped := errors.NewCanceled("stopped")
+	err = traverse(context.TODO(), get, Traversal{Start: "pod-1", Steps: []TraversalStep{{MinDepth: 1, MaxDepth: 3}}}, func(*topo.Object) error {
+		return stopped
+	})
+	assert.Equal(t, stopped, err)
+	assert.Equal(t, 3, gets)
+
+	// Traversals visiting more entities than their limit allows must be truncated before visiting the whole graph
+	assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+		ID:   "hub",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "switch"}},
+	}))
+	spokes := 5 * traversalVisitsPerResult
+	for i := 0; i < spokes; i++ {
+		id := topo.ID(fmt.Sprintf("spoke-%d", i))
+		assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+			ID:   id,
+			Type: topo.Object_ENTITY,
+			Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "port"}},
+		}))
+		assert.NoError(t, store.Create(context.TODO(), &topo.Object{
+			ID:   "hub-" + id,
+			Type: topo.Object_RELATION,
+			Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "contains", SrcEntityID: "hub", TgtEntityID: id}},
+		}))
+	}
+	waitForRelations(t, store, "hub", spokes, 0)
+	gets = 0
+	ids = nil
+	err = traverse(context.TODO(), get, Traversal{Start: "hub", Steps: []TraversalStep{{MinDepth: 2, MaxDepth: 2}}, Limit: 1}, func(object *topo.Object) error {
+		ids = append(ids, object.ID)
+		return nil
+	})
+	assert.Equal(t, ErrTraversalTruncated, err)
+	assert.Empty(t, ids)
+	assert.Less(t, gets, 2*spokes)
+	ids, err = traverseIDs(t, store, Traversal{Start: "hub", Steps: []TraversalStep{{MinDepth: 1, MaxDepth: 1}}, Limit: spokes})
+	assert.NoError(t, err)
+	assert.Len(t, ids, spokes)
+}
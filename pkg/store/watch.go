@@ -0,0 +1,664 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+// DefaultWatchQueueSize is the default number of events queued for a watcher before its overflow policy applies
+const DefaultWatchQueueSize = 1024
+
+// WatchStats reports the state of the event queues of the watchers of a store
+type WatchStats struct {
+	// Watchers are the statistics of the active watchers
+	Watchers []WatcherStats
+	// Coalesced is the number of events merged into queued events by the active and closed watchers with each
+	// overflow policy
+	Coalesced map[OverflowPolicy]uint64
+}
+
+// WatcherStats reports the state of the event queue of a watcher
+type WatcherStats struct {
+	// ID is a unique identifier of the watcher
+	ID string
+	// Overflow is the overflow policy of the watcher
+	Overflow OverflowPolicy
+	// Depth is the number of events queued for delivery to the watcher
+	Depth int
+	// MaxDepth is the highest number of events queued for the watcher
+	MaxDepth int
+	// Capacity is the number of events queued for the watcher before its overflow policy applies
+	Capacity int
+	// Coalesced is the number of events merged into queued events
+	Coalesced uint64
+}
+
+// watchers is the set of watchers registered by Watch calls on a store, and the journal of recent events
+type watchers struct {
+	watchers map[uuid.UUID]*watcher
+	journal  *journal
+	// revision is the highest revision of the events sent to the watchers
+	revision topoapi.Revision
+	// sent is called with the watchers lock held for each change once it has been queued for the watchers
+	sent func(c change)
+	// coalesced is the number of events merged into queued events by the closed watchers with each overflow policy
+	coalesced map[OverflowPolicy]uint64
+	mu        sync.RWMutex
+}
+
+func newWatchers(journalSize int) *watchers {
+	return &watchers{
+		watchers:  make(map[uuid.UUID]*watcher),
+		journal:   newJournal(journalSize),
+		coalesced: make(map[OverflowPolicy]uint64),
+	}
+}
+
+// change is an event with the state of its object before the change, if the store knows it
+type change struct {
+	event    topoapi.Event
+	previous *topoapi.Object
+	// transaction is the ID of the transaction that made the change, if any
+	transaction string
+}
+
+// send records the given event in the journal and queues it for delivery to every registered watcher, along with
+// the previous state of the object if any; it never blocks on slow watchers
+func (w *watchers) send(event topoapi.Event, previous *topoapi.Object) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	c := change{event: event, previous: previous}
+	if event.Type == topoapi.EventType_NONE {
+		// Objects listed when the store starts are not changes that can be replayed
+		if event.Object.Revision > w.revision {
+			w.revision = event.Object.Revision
+		}
+		w.journal.load(event.Object.Revision)
+		for _, watcher := range w.watchers {
+			watcher.push(c)
+		}
+		return
+	}
+	w.record(c)
+}
+
+// sendGroup records the changes made by the transaction with the given ID in the journal and queues them for
+// delivery to every registered watcher; the changes are queued contiguously, so that they are delivered as a group
+func (w *watchers) sendGroup(transaction string, changes []change) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, c := range changes {
+		c.transaction = transaction
+		w.record(c)
+	}
+}
+
+// record records a change in the journal and queues it for every registered watcher; it must be called with the
+// watchers lock held
+func (w *watchers) record(c change) {
+	if c.event.Object.Revision > w.revision {
+		w.revision = c.event.Object.Revision
+	}
+	w.journal.record(c)
+	for _, watcher := range w.watchers {
+		watcher.push(c)
+	}
+	if w.sent != nil {
+		w.sent(c)
+	}
+}
+
+// stats returns the queue statistics of the registered watchers
+func (w *watchers) stats() WatchStats {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	stats := WatchStats{
+		Watchers:  make([]WatcherStats, 0, len(w.watchers)),
+		Coalesced: make(map[OverflowPolicy]uint64, len(w.coalesced)),
+	}
+	for overflow, coalesced := range w.coalesced {
+		stats.Coalesced[overflow] = coalesced
+	}
+	for _, watcher := range w.watchers {
+		watcherStats := watcher.stats()
+		stats.Watchers = append(stats.Watchers, watcherStats)
+		stats.Coalesced[watcherStats.Overflow] += watcherStats.Coalesced
+	}
+	return stats
+}
+
+// storeSnapshot is a copy of the objects of a store taken atomically with the registration of a watcher
+type storeSnapshot struct {
+	objects []topoapi.Object
+	// revisions is the revision reflected in the snapshot of each object, including removed objects, whose events
+	// had not been sent to the watchers when the snapshot was taken, so that they are not delivered again
+	revisions map[topoapi.ID]snapshotRevision
+}
+
+// snapshotRevision is the revision of an object in a snapshot
+type snapshotRevision struct {
+	revision topoapi.Revision
+	removed  bool
+	// pending is the number of events for the object that had not been sent when the snapshot was taken
+	pending int
+}
+
+// watch registers a new watcher and streams matching events to the given channel until the context is done.
+// If resuming is requested, the journaled events following the requested revision are sent first. Otherwise,
+// if replay is requested, the objects returned by the snapshot function are sent first as EventType_NONE events.
+// The snapshot function is called with the watchers lock held, atomically with the registration of the watcher,
+// so it must not wait for events to be sent. Only the changes after the snapshot are then sent to the watcher, in
+// revision order for each object.
+// Events are filtered like List filters objects; with a relation filter, the watch follows the subgraph of the
+// anchor entity of the filter from the snapshot, reading the entities linked to the anchor afterwards with the get
+// function, and cannot be resumed from the journal. If changes are
+// requested, UPDATED and REMOVED events for objects whose previous state is known carry the ChangesAspect.
+func (w *watchers) watch(ctx context.Context, ch chan<- topoapi.Event, filters *topoapi.Filters, snapshot func() storeSnapshot, get func(ctx context.Context, id topoapi.ID) (*topoapi.Object, error), opts ...WatchOption) error {
+	watchOpts := watchOptions{
+		queueSize: DefaultWatchQueueSize,
+	}
+	for _, opt := range opts {
+		opt.apply(&watchOpts)
+	}
+	if watchOpts.queueSize <= 0 {
+		return errors.NewInvalid("watch queue size must be positive")
+	}
+	if watchOpts.coalesceWindow < 0 {
+		return errors.NewInvalid("watch coalescing window must not be negative")
+	}
+	if watchOpts.maxRate < 0 {
+		return errors.NewInvalid("watch event rate must not be negative")
+	}
+	if watchOpts.heartbeat < 0 {
+		return errors.NewInvalid("watch heartbeat interval must not be negative")
+	}
+	if err := validateFilters(filters); err != nil {
+		return err
+	}
+	var graph *subgraph
+	if filters != nil && filters.RelationFilter != nil {
+		var err error
+		read := func(id topoapi.ID) (*topoapi.Object, error) {
+			return get(ctx, id)
+		}
+		if graph, err = newSubgraph(filters, read); err != nil {
+			return err
+		}
+		// The journal holds changes made before the snapshot the subgraph is seeded from, which cannot be applied
+		// to it, and the subgraph observed by the watcher at the requested revision is unknown
+		if watchOpts.resume {
+			return errors.NewConflict("watches with a relation filter cannot be resumed; the topology must be listed again")
+		}
+	}
+
+	// Take the snapshot and read the journaled events atomically with the registration of the watcher, so that
+	// every change is either replayed or queued exactly once. Subgraphs are always seeded from the snapshot.
+	watcher := newWatcher(watchOpts)
+	var journaled []change
+	var objects []topoapi.Object
+	w.mu.Lock()
+	synced := w.revision
+	if watchOpts.resume {
+		var err error
+		if journaled, err = w.journal.since(watchOpts.resumeAfter); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+	}
+	if graph != nil || (watchOpts.replay && !watchOpts.resume) {
+		state := snapshot()
+		objects = state.objects
+		watcher.barrier = state.revisions
+		for _, revision := range state.revisions {
+			if revision.revision > synced {
+				synced = revision.revision
+			}
+		}
+	}
+	w.watchers[watcher.id] = watcher
+	w.mu.Unlock()
+
+	// Get the objects to replay
+	if graph != nil {
+		members := graph.seed(objects)
+		objects = nil
+		if watchOpts.replay && !watchOpts.resume {
+			objects = members
+		}
+	}
+
+	// Create a goroutine to first replay existing state to the watcher and then send queued events
+	limiter := newRateLimiter(watchOpts.maxRate)
+	done := make(chan struct{})
+	if watchOpts.heartbeat > 0 {
+		go w.heartbeat(ctx, watcher, watchOpts.heartbeat, done)
+	}
+	go func() {
+		defer close(ch)
+		defer func() {
+			w.mu.Lock()
+			delete(w.watchers, watcher.id)
+			w.coalesced[watcher.overflow] += watcher.stats().Coalesced
+			w.mu.Unlock()
+			close(done)
+		}()
+
+		// The replay of the existing objects or of the journaled events counts as a single burst for the rate limit
+		replaying := len(objects) > 0 || len(journaled) > 0
+		if replaying && limiter.wait(ctx) != nil {
+			return
+		}
+
+		// Replay existing objects if they match the watch filter
+		for _, object := range objects {
+			if graph != nil || matchWatch(&object, filters) {
+				select {
+				case ch <- topoapi.Event{Type: topoapi.EventType_NONE, Object: object}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		// selected returns the events selected by the filters for the given change
+		selected := func(c change) []topoapi.Event {
+			events := []topoapi.Event{c.event}
+			switch {
+			case GetBookmark(&c.event) != nil:
+				// Bookmarks are sent to every watcher
+				return events
+			case graph != nil:
+				events = graph.apply(c.event)
+			case !matchWatch(&c.event.Object, filters):
+				return nil
+			}
+			for i, event := range events {
+				if watchOpts.changes && c.previous != nil && event.Object.ID == c.event.Object.ID &&
+					(event.Type == topoapi.EventType_UPDATED || event.Type == topoapi.EventType_REMOVED) {
+					events[i] = withChanges(event, c.previous)
+				}
+			}
+			return events
+		}
+
+		// send sends the events selected by the filters for the given changes, returning false once the watch is
+		// done. The changes of a transaction are sent together, marked with their TransactionGroup, and count as a
+		// single event for the rate limit.
+		send := func(changes ...change) bool {
+			var events []topoapi.Event
+			for _, c := range changes {
+				events = append(events, selected(c)...)
+			}
+			transaction := changes[0].transaction
+			for i, event := range events {
+				if transaction != "" {
+					event = withTransactionGroup(event, TransactionGroup{ID: transaction, Index: i, Count: len(events)})
+				}
+				if !replaying && (transaction == "" || i == 0) {
+					if limiter.wait(ctx) != nil {
+						return false
+					}
+				}
+				select {
+				case ch <- event:
+				case <-watcher.closed:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		// Resume from the journaled events if requested
+		for _, group := range groupChanges(journaled) {
+			if !send(group...) {
+				return
+			}
+		}
+		replaying = false
+
+		// Mark the end of the replay if requested
+		if watchOpts.bookmarks && !send(change{event: newBookmark(BookmarkSynced, synced)}) {
+			return
+		}
+
+		// Once the replay is done, process the queued events
+		for {
+			changes, err := watcher.next(ctx)
+			if err != nil {
+				if ctx.Err() == nil && watchOpts.onError != nil {
+					watchOpts.onError(err)
+				}
+				return
+			}
+			if !send(changes...) {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// groupChanges splits the given changes into the changes of each transaction and the other changes, in order
+func groupChanges(changes []change) [][]change {
+	var groups [][]change
+	for i, c := range changes {
+		if i > 0 && c.transaction != "" && c.transaction == changes[i-1].transaction {
+			groups[len(groups)-1] = append(groups[len(groups)-1], c)
+		} else {
+			groups = append(groups, []change{c})
+		}
+	}
+	return groups
+}
+
+// heartbeat queues a heartbeat for the given watcher at each interval until the watch is done. Heartbeats are
+// queued with the watchers lock held, so that their revision is that of the events queued before them.
+func (w *watchers) heartbeat(ctx context.Context, watcher *watcher, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			watcher.push(change{event: newBookmark(BookmarkHeartbeat, w.revision)})
+			w.mu.Unlock()
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// matchWatch returns whether an object matches filters without a relation filter
+func matchWatch(object *topoapi.Object, filters *topoapi.Filters) bool {
+	return filters == nil || (match(object, filters) && matchType(object, filters.ObjectTypes))
+}
+
+// watcher is the bounded event queue of a single Watch call
+type watcher struct {
+	id       uuid.UUID
+	capacity int
+	overflow OverflowPolicy
+	// window is the time events are held in the queue to coalesce later events for the same object
+	window time.Duration
+	// coalesce merges each event into the queued event for the same object, if any
+	coalesce bool
+	// queue holds the pending events; head is the sequence number of the first event in the queue and
+	// positions maps each object ID to the sequence number of its most recent queued event
+	queue     []queuedEvent
+	head      uint64
+	positions map[topoapi.ID]uint64
+	maxDepth  int
+	coalesced uint64
+	// barrier is the revision of the objects in the snapshot taken when the watcher was registered; it is lifted for
+	// each object by the first change after the snapshot
+	barrier map[topoapi.ID]snapshotRevision
+	err     error
+	notify  chan struct{}
+	// closed is closed when the watcher is closed by its overflow policy
+	closed chan struct{}
+	mu     sync.Mutex
+}
+
+// queuedEvent is a change in the queue of a watcher
+type queuedEvent struct {
+	change
+	// queued is the time the first of the events coalesced into the event was queued
+	queued time.Time
+	// cancelled is true if the event was cancelled by a later event, e.g. an object removed before its addition
+	// was delivered
+	cancelled bool
+}
+
+func newWatcher(opts watchOptions) *watcher {
+	return &watcher{
+		id:        uuid.New(),
+		capacity:  opts.queueSize,
+		overflow:  opts.overflow,
+		window:    opts.coalesceWindow,
+		coalesce:  opts.coalesceWindow > 0 || opts.maxRate > 0 || opts.overflow == OverflowCoalesce,
+		positions: make(map[topoapi.ID]uint64),
+		notify:    make(chan struct{}, 1),
+		closed:    make(chan struct{}),
+	}
+}
+
+// push queues a change, applying the overflow policy if the queue is full
+func (w *watcher) push(c change) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil || !w.admit(&c) {
+		return
+	}
+
+	full := len(w.queue) >= w.capacity
+	if c.transaction != "" {
+		// The changes of a transaction are delivered as a group, so they are neither merged into queued events nor
+		// followed by events merged into them
+		delete(w.positions, c.event.Object.ID)
+	} else if w.coalesce {
+		if seq, ok := w.positions[c.event.Object.ID]; ok {
+			// The merged event keeps the previous state of the first change, i.e. the state last delivered
+			queued := &w.queue[seq-w.head]
+			if merged, ok := coalesceEvents(queued.event, c.event); ok {
+				queued.event = merged
+			} else {
+				queued.change = change{}
+				queued.cancelled = true
+				delete(w.positions, c.event.Object.ID)
+			}
+			w.coalesced++
+			return
+		}
+	}
+
+	if full {
+		switch w.overflow {
+		case OverflowDrop:
+			log.Warnf("Watcher %s fell behind; closing the watch", w.id)
+			w.close(errors.NewUnavailable("watch closed after falling behind by %d events", len(w.queue)))
+		default:
+			// Events for objects that are not queued cannot be coalesced without growing the queue past its capacity
+			log.Warnf("Watcher %s fell behind; closing the watch for a resync", w.id)
+			w.close(errors.NewConflict("watch closed after falling behind by %d events; the topology must be listed again", len(w.queue)))
+		}
+		return
+	}
+
+	if c.transaction == "" {
+		w.positions[c.event.Object.ID] = w.head + uint64(len(w.queue))
+	}
+	w.queue = append(w.queue, queuedEvent{change: c, queued: time.Now()})
+	if len(w.queue) > w.maxDepth {
+		w.maxDepth = len(w.queue)
+	}
+	w.signal()
+}
+
+// close closes the watcher with the given error, discarding the queued events; it must be called with the watcher
+// lock held
+func (w *watcher) close(err error) {
+	w.err = err
+	w.queue = nil
+	close(w.closed)
+}
+
+// admit returns whether a change follows the snapshot taken when the watcher was registered. Changes already
+// reflected in the snapshot are not queued; an object added again after the snapshot was taken is reported as
+// updated, since it has been replayed to the watcher. The barrier of an object is lifted once a change follows the
+// snapshot, or once the events that were pending when the snapshot was taken have been received. It must be called
+// with the watcher lock held.
+func (w *watcher) admit(c *change) bool {
+	id := c.event.Object.ID
+	snapshot, ok := w.barrier[id]
+	if !ok {
+		return true
+	}
+	// The removal of an object may have the revision of its last change
+	revision := c.event.Object.Revision
+	removed := c.event.Type == topoapi.EventType_REMOVED
+	if revision < snapshot.revision || (revision == snapshot.revision && (!removed || snapshot.removed)) {
+		if snapshot.pending--; snapshot.pending > 0 {
+			w.barrier[id] = snapshot
+		} else {
+			delete(w.barrier, id)
+		}
+		return false
+	}
+	delete(w.barrier, id)
+	if c.event.Type == topoapi.EventType_ADDED && !snapshot.removed {
+		c.event.Type = topoapi.EventType_UPDATED
+	}
+	return true
+}
+
+// coalesceEvents merges a later event for an object into its queued event, so that the merged event carries the
+// latest state of the object and a type consistent with the last event delivered for it. It returns false if the
+// events cancel each other out, i.e. the object was removed before its addition was delivered.
+func coalesceEvents(queued, event topoapi.Event) (topoapi.Event, bool) {
+	switch {
+	case event.Type == topoapi.EventType_REMOVED:
+		if queued.Type == topoapi.EventType_ADDED || queued.Type == topoapi.EventType_NONE {
+			return topoapi.Event{}, false
+		}
+	case queued.Type == topoapi.EventType_REMOVED:
+		// An object removed and added again has changed since the last delivered event
+		event.Type = topoapi.EventType_UPDATED
+	default:
+		// An update of an object that has not been delivered yet is still an addition
+		event.Type = queued.Type
+	}
+	return event, true
+}
+
+// signal wakes up the goroutine delivering the queued events; it must be called with the watcher lock held
+func (w *watcher) signal() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next waits for and dequeues the next change, once the coalescing window of the change has elapsed, along with the
+// other changes of its transaction, if any. It returns an error if the context is done or the watcher has been
+// closed by its overflow policy.
+func (w *watcher) next(ctx context.Context) ([]change, error) {
+	var err error
+	for {
+		w.mu.Lock()
+		if w.err != nil {
+			err = w.err
+			w.mu.Unlock()
+			return nil, err
+		}
+		for len(w.queue) > 0 && w.queue[0].cancelled {
+			w.pop()
+		}
+		delay := time.Duration(-1)
+		if len(w.queue) > 0 {
+			delay = time.Until(w.queue[0].queued.Add(w.window))
+			if w.window <= 0 || delay <= 0 {
+				changes := []change{w.pop()}
+				// The changes of a transaction are queued contiguously
+				for transaction := changes[0].transaction; transaction != "" && len(w.queue) > 0 && w.queue[0].transaction == transaction; {
+					changes = append(changes, w.pop())
+				}
+				w.mu.Unlock()
+				return changes, nil
+			}
+		}
+		w.mu.Unlock()
+
+		// Wait for an event, or for the coalescing window of the first event to elapse
+		var timer *time.Timer
+		var wait <-chan time.Time
+		if delay > 0 {
+			timer = time.NewTimer(delay)
+			wait = timer.C
+		}
+		select {
+		case <-w.notify:
+		case <-wait:
+		case <-w.closed:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// pop dequeues the first change; it must be called with the watcher lock held
+func (w *watcher) pop() change {
+	queued := w.queue[0]
+	w.queue[0] = queuedEvent{}
+	w.queue = w.queue[1:]
+	if !queued.cancelled && w.positions[queued.event.Object.ID] == w.head {
+		delete(w.positions, queued.event.Object.ID)
+	}
+	w.head++
+	return queued.change
+}
+
+// rateLimiter spaces out the events sent to a watcher to a maximum rate
+type rateLimiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(maxRate float64) *rateLimiter {
+	if maxRate <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / maxRate)}
+}
+
+// wait waits until the next event may be sent; a nil limiter never waits
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	now := time.Now()
+	if delay := l.next.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		now = l.next
+	}
+	l.next = now.Add(l.interval)
+	return nil
+}
+
+// stats returns the queue statistics of the watcher
+func (w *watcher) stats() WatcherStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return WatcherStats{
+		ID:        w.id.String(),
+		Overflow:  w.overflow,
+		Depth:     len(w.queue),
+		MaxDepth:  w.maxDepth,
+		Capacity:  w.capacity,
+		Coalesced: w.coalesced,
+	}
+}
@@ -0,0 +1,666 @@
+// SPDX-FileCopyrightText: 2020-present Open Networking Foundation <info@opennetworking.org>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// nextWatchEvent returns the next event sent to the given channel, failing the test if none is sent in time
+func nextWatchEvent(t *testing.T, ch chan topo.Event) topo.Event {
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(5 * time.Second):
+		t.FailNow()
+	}
+	return topo.Event{}
+}
+
+// waitForEvents consumes events until an event has been received for each of the given objects
+func waitForEvents(t *testing.T, ch chan topo.Event, ids ...topo.ID) {
+	pending := make(map[topo.ID]bool)
+	for _, id := range ids {
+		pending[id] = true
+	}
+	for len(pending) > 0 {
+		event := nextWatchEvent(t, ch)
+		delete(pending, event.Object.ID)
+	}
+}
+
+func TestSnapshotBarrier(t *testing.T) {
+	w := newWatcher(watchOptions{queueSize: DefaultWatchQueueSize})
+	w.barrier = map[topo.ID]snapshotRevision{
+		"a": {revision: 5, pending: 2},
+		"b": {revision: 7, removed: true, pending: 1},
+		"c": {revision: 3, pending: 1},
+		"e": {revision: 6, pending: 1},
+	}
+	event := func(eventType topo.EventType, id topo.ID, revision topo.Revision) change {
+		return change{event: topo.Event{Type: eventType, Object: topo.Object{ID: id, Revision: revision}}}
+	}
+	for _, c := range []change{
+		// Changes reflected in the snapshot must be discarded
+		event(topo.EventType_UPDATED, "a", 4),
+		event(topo.EventType_UPDATED, "a", 5),
+		event(topo.EventType_REMOVED, "b", 7),
+		// Changes after the snapshot must be queued in order
+		event(topo.EventType_REMOVED, "c", 3),
+		event(topo.EventType_UPDATED, "a", 6),
+		event(topo.EventType_ADDED, "b", 8),
+		event(topo.EventType_ADDED, "d", 1),
+		// Objects added again after the snapshot must be updated
+		event(topo.EventType_ADDED, "c", 9),
+		// The barrier must be lifted once the pending events have been received, even if they are stale
+		event(topo.EventType_UPDATED, "e", 4),
+	} {
+		w.push(c)
+	}
+	var events []watchEvent
+	for len(w.queue) > 0 {
+		c := w.pop()
+		events = append(events, watchEvent{c.event.Type, c.event.Object.ID})
+	}
+	assert.Equal(t, []watchEvent{
+		{topo.EventType_REMOVED, "c"},
+		{topo.EventType_UPDATED, "a"},
+		{topo.EventType_ADDED, "b"},
+		{topo.EventType_ADDED, "d"},
+		{topo.EventType_ADDED, "c"},
+	}, events)
+	assert.Empty(t, w.barrier)
+}
+
+func TestSnapshotReplay(t *testing.T) {
+	w := newWatchers(DefaultJournalSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The snapshot reflects changes whose events are sent after the watcher is registered
+	snapshot := func() storeSnapshot {
+		return storeSnapshot{
+			objects: []topo.Object{{ID: "a", Revision: 5}},
+			revisions: map[topo.ID]snapshotRevision{
+				"a": {revision: 5, pending: 1},
+				"b": {revision: 4, removed: true, pending: 1},
+			},
+		}
+	}
+	ch := make(chan topo.Event)
+	assert.NoError(t, w.watch(ctx, ch, nil, snapshot, nil, WithReplay()))
+	for _, event := range []topo.Event{
+		{Type: topo.EventType_UPDATED, Object: topo.Object{ID: "a", Revision: 5}},
+		{Type: topo.EventType_REMOVED, Object: topo.Object{ID: "b", Revision: 4}},
+		{Type: topo.EventType_UPDATED, Object: topo.Object{ID: "a", Revision: 6}},
+		{Type: topo.EventType_ADDED, Object: topo.Object{ID: "b", Revision: 7}},
+	} {
+		w.send(event, nil)
+	}
+	assert.Equal(t, []watchEvent{
+		{topo.EventType_NONE, "a"},
+		{topo.EventType_UPDATED, "a"},
+		{topo.EventType_ADDED, "b"},
+	}, nextWatchEvents(t, ch, 3))
+}
+
+func TestConsistentReplay(t *testing.T) {
+	forEachBackend(t, testConsistentReplay)
+}
+
+// testConsistentReplay starts watches while objects are concurrently changed, and checks that each watch observes
+// a snapshot followed by the changes after the snapshot, in order for each object
+func testConsistentReplay(t *testing.T, newStore func() Store) {
+	store := newStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const objects = 8
+	id := func(i int) topo.ID {
+		return topo.ID(fmt.Sprintf("port-%d", i))
+	}
+	var writers sync.WaitGroup
+	for i := 0; i < objects; i++ {
+		writers.Add(1)
+		go func(id topo.ID) {
+			defer writers.Done()
+			for j := 0; j < 30; j++ {
+				object, err := store.Get(context.TODO(), id)
+				switch {
+				case err != nil:
+					err = store.Create(context.TODO(), &topo.Object{
+						ID:   id,
+						Type: topo.Object_ENTITY,
+						Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "port"}},
+					})
+				case rand.Intn(4) == 0:
+					err = store.Delete(context.TODO(), id, object.Revision)
+				default:
+					object.Labels = map[string]string{"rx": fmt.Sprint(j)}
+					err = store.Update(context.TODO(), object)
+				}
+				assert.NoError(t, err)
+			}
+		}(id(i))
+	}
+
+	// Start watches while the objects are changed
+	var watches []chan topo.Event
+	for i := 0; i < 4; i++ {
+		ch := make(chan topo.Event, 1000)
+		assert.NoError(t, store.Watch(ctx, ch, nil, WithReplay()))
+		watches = append(watches, ch)
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+	}
+	writers.Wait()
+
+	var views []map[topo.ID]topo.Revision
+	for _, ch := range watches {
+		type objectState struct {
+			revision topo.Revision
+			present  bool
+		}
+		states := make(map[topo.ID]objectState)
+		replaying := true
+		for {
+			var event topo.Event
+			select {
+			case event = <-ch:
+			case <-time.After(200 * time.Millisecond):
+			}
+			if event.Object.ID == "" {
+				break
+			}
+			state, known := states[event.Object.ID]
+			revision := event.Object.Revision
+			switch event.Type {
+			case topo.EventType_NONE:
+				assert.True(t, replaying, "%s replayed after changes", event.Object.ID)
+				assert.False(t, known, "%s replayed twice", event.Object.ID)
+			case topo.EventType_ADDED:
+				assert.False(t, state.present, "%s added twice", event.Object.ID)
+				assert.Greater(t, revision, state.revision, "%s added out of order", event.Object.ID)
+			case topo.EventType_UPDATED:
+				assert.True(t, state.present, "%s updated before it was added", event.Object.ID)
+				assert.Greater(t, revision, state.revision, "%s updated out of order", event.Object.ID)
+			case topo.EventType_REMOVED:
+				assert.True(t, state.present, "%s removed before it was added", event.Object.ID)
+				assert.GreaterOrEqual(t, revision, state.revision, "%s removed out of order", event.Object.ID)
+			}
+			replaying = replaying && event.Type == topo.EventType_NONE
+			states[event.Object.ID] = objectState{revision: revision, present: event.Type != topo.EventType_REMOVED}
+		}
+
+		view := make(map[topo.ID]topo.Revision)
+		for id, state := range states {
+			if state.present {
+				view[id] = state.revision
+			}
+		}
+		views = append(views, view)
+	}
+
+	// The watches must converge to the state of the store
+	listed, err := store.List(context.TODO(), nil)
+	assert.NoError(t, err)
+	expected := make(map[topo.ID]topo.Revision)
+	for _, object := range listed {
+		expected[object.ID] = object.Revision
+	}
+	for _, view := range views {
+		assert.Equal(t, expected, view)
+	}
+}
+
+func TestTransactionGroups(t *testing.T) {
+	forEachBackend(t, testTransactionGroups)
+}
+
+func testTransactionGroups(t *testing.T, newStore func() Store) {
+	store := newStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	coalesced := make(chan topo.Event)
+	err := store.Watch(ctx, coalesced, nil, WithCoalesceWindow(300*time.Millisecond))
+	assert.NoError(t, err)
+	limited := make(chan topo.Event)
+	err = store.Watch(ctx, limited, nil, WithMaxEventRate(2))
+	assert.NoError(t, err)
+	createNode(t, store, auxNode{id: "d1"})
+	waitForEvents(t, coalesced, "d1")
+	waitForEvents(t, limited, "d1")
+	node, err := store.Get(context.TODO(), "d1")
+	assert.NoError(t, err)
+
+	// An update queued before a transaction must not be merged with the change of the transaction to its object
+	node.Labels = map[string]string{"role": "spine"}
+	assert.NoError(t, store.Update(context.TODO(), node))
+	node.Labels = map[string]string{"role": "leaf"}
+	link := &topo.Object{
+		ID:   "l1",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "link"}},
+	}
+	originates := &topo.Object{
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "originates", SrcEntityID: "d1", TgtEntityID: "l1"}},
+	}
+	err = store.Transaction(context.TODO(), UpdateOperation(node), CreateOperation(link), CreateOperation(originates))
+	assert.NoError(t, err)
+
+	for _, ch := range []chan topo.Event{coalesced, limited} {
+		event := nextWatchEvent(t, ch)
+		assert.Equal(t, topo.EventType_UPDATED, event.Type)
+		assert.Equal(t, "spine", event.Object.Labels["role"])
+		group, err := GetTransactionGroup(&event.Object)
+		assert.NoError(t, err)
+		assert.Nil(t, group)
+
+		// The events of the transaction must be sent contiguously, marked with their position in the group, and
+		// count as a single event for the rate limit
+		var start time.Time
+		var id string
+		for i, expected := range []topo.ID{"d1", "l1", originates.ID} {
+			event := nextWatchEvent(t, ch)
+			if i == 0 {
+				start = time.Now()
+			}
+			assert.Equal(t, expected, event.Object.ID)
+			group, err := GetTransactionGroup(&event.Object)
+			assert.NoError(t, err)
+			if assert.NotNil(t, group) {
+				assert.Equal(t, i, group.Index)
+				assert.Equal(t, 3, group.Count)
+				if i == 0 {
+					id = group.ID
+				}
+				assert.Equal(t, id, group.ID)
+			}
+		}
+		assert.Less(t, time.Since(start), 400*time.Millisecond)
+	}
+}
+
+func TestSlowWatchers(t *testing.T) {
+	forEachBackend(t, testSlowWatchers)
+}
+
+func testSlowWatchers(t *testing.T, newStore func() Store) {
+	store := newStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A watcher that is not consumed must be dropped once its queue is full
+	errCh := make(chan error, 1)
+	dropped := make(chan topo.Event)
+	err := store.Watch(ctx, dropped, nil, WithQueue(2, OverflowDrop), WithErrorHandler(func(err error) {
+		errCh <- err
+	}))
+	assert.NoError(t, err)
+
+	coalesced := make(chan topo.Event)
+	err = store.Watch(ctx, coalesced, nil, WithQueue(3, OverflowCoalesce))
+	assert.NoError(t, err)
+
+	// A coalescing watcher whose queue is full of other objects must be closed for a resync
+	resyncCh := make(chan error, 1)
+	resynced := make(chan topo.Event)
+	err = store.Watch(ctx, resynced, nil, WithQueue(2, OverflowCoalesce), WithErrorHandler(func(err error) {
+		resyncCh <- err
+	}))
+	assert.NoError(t, err)
+
+	// Stalled watchers must not block other watchers
+	ch := make(chan topo.Event)
+	err = store.Watch(ctx, ch, nil)
+	assert.NoError(t, err)
+
+	node := &topo.Object{
+		ID:   "n1",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "e2-node"}},
+	}
+	err = store.Create(context.TODO(), node)
+	assert.NoError(t, err)
+	waitForEvents(t, ch, "n1")
+	for i := 0; i < 5; i++ {
+		node.Labels = map[string]string{"count": fmt.Sprint(i)}
+		err = store.Update(context.TODO(), node)
+		assert.NoError(t, err)
+	}
+	createCell(t, store, auxCell{id: "c1"})
+	createCell(t, store, auxCell{id: "c2"})
+	waitForEvents(t, ch, "c1", "c2")
+
+	select {
+	case err := <-errCh:
+		assert.True(t, errors.IsUnavailable(err))
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow watcher was not dropped")
+	}
+	for range dropped { //revive:disable-line:empty-block
+	}
+	select {
+	case err := <-resyncCh:
+		assert.True(t, errors.IsConflict(err))
+	case <-time.After(5 * time.Second):
+		t.Fatal("coalescing watcher was not closed for a resync")
+	}
+	for range resynced { //revive:disable-line:empty-block
+	}
+
+	// The coalescing watcher must skip intermediate updates but observe the latest state of each object, without
+	// queueing more events than its capacity
+	stats := store.WatchStats().Watchers
+	assert.Len(t, stats, 2)
+	for _, stat := range stats {
+		if stat.Capacity == 3 {
+			assert.NotZero(t, stat.Coalesced)
+			assert.LessOrEqual(t, stat.MaxDepth, 3)
+		}
+	}
+	latest := make(map[topo.ID]topo.Event)
+	for len(latest) < 3 || latest["n1"].Object.Labels["count"] != "4" {
+		event := nextWatchEvent(t, coalesced)
+		latest[event.Object.ID] = event
+	}
+	assert.Equal(t, topo.EventType_ADDED, latest["c2"].Type)
+}
+
+func TestResumeWatch(t *testing.T) {
+	forEachBackend(t, testResumeWatch)
+}
+
+func testResumeWatch(t *testing.T, newStore func() Store) {
+	store := newStore()
+
+	ch := make(chan topo.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := store.Watch(ctx, ch, nil)
+	assert.NoError(t, err)
+	createNode(t, store, auxNode{id: "n1"})
+	event := nextWatchEvent(t, ch)
+	assert.Equal(t, topo.ID("n1"), event.Object.ID)
+	cancel()
+
+	// Changes made while the watcher is disconnected must be replayed when it resumes
+	createCell(t, store, auxCell{id: "c1"})
+	createCell(t, store, auxCell{id: "c2"})
+	err = store.Delete(context.TODO(), "c1", 0)
+	assert.NoError(t, err)
+	createCell(t, store, auxCell{id: "c3"})
+
+	ch = make(chan topo.Event)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	err = store.Watch(ctx, ch, &topo.Filters{ObjectTypes: []topo.Object_Type{topo.Object_ENTITY}}, WithReplay(), WithResumeAfter(event.Object.Revision))
+	assert.NoError(t, err)
+	for _, expected := range []struct {
+		eventType topo.EventType
+		id        topo.ID
+	}{
+		{topo.EventType_ADDED, "c1"},
+		{topo.EventType_ADDED, "c2"},
+		{topo.EventType_REMOVED, "c1"},
+		{topo.EventType_ADDED, "c3"},
+	} {
+		event := nextWatchEvent(t, ch)
+		assert.Equal(t, expected.eventType, event.Type)
+		assert.Equal(t, expected.id, event.Object.ID)
+	}
+
+	// Resuming must fail once the requested revision has been compacted
+	revision := event.Object.Revision
+	if _, ok := store.(*memoryStore); ok {
+		store = NewMemoryStore(WithJournalSize(1))
+		defer store.Close()
+		createNode(t, store, auxNode{id: "n1"})
+		node, err := store.Get(context.TODO(), "n1")
+		assert.NoError(t, err)
+		revision = node.Revision
+		createCell(t, store, auxCell{id: "c1"})
+		createCell(t, store, auxCell{id: "c2"})
+	} else {
+		// A new replica cannot replay changes made before it started, but can resume watchers that observed them
+		store = newStore()
+		objects, err := store.List(context.TODO(), nil)
+		assert.NoError(t, err)
+		var loaded topo.Revision
+		for _, object := range objects {
+			if object.Revision > loaded {
+				loaded = object.Revision
+			}
+		}
+		err = store.Watch(ctx, make(chan topo.Event), nil, WithResumeAfter(loaded))
+		assert.NoError(t, err)
+	}
+	err = store.Watch(ctx, make(chan topo.Event), nil, WithResumeAfter(revision))
+	assert.True(t, errors.IsConflict(err))
+}
+
+func TestTransactions(t *testing.T) {
+	forEachBackend(t, testTransactions)
+}
+
+func testTransactions(t *testing.T, newStore func() Store) {
+	store := newStore()
+
+	createNode(t, store, auxNode{id: "d1"})
+	createNode(t, store, auxNode{id: "d2"})
+
+	ch := make(chan topo.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := store.Watch(ctx, ch, nil)
+	assert.NoError(t, err)
+
+	// A link and its relations must be created together
+	link := &topo.Object{
+		ID:   "l1",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "link"}},
+	}
+	originates := &topo.Object{
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "originates", SrcEntityID: "d1", TgtEntityID: "l1"}},
+	}
+	terminates := &topo.Object{
+		Type: topo.Object_RELATION,
+		Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "terminates", SrcEntityID: "d2", TgtEntityID: "l1"}},
+	}
+	err = store.Transaction(context.TODO(), CreateOperation(link), CreateOperation(originates), CreateOperation(terminates))
+	assert.NoError(t, err)
+	assert.NotEqual(t, topo.Revision(0), link.Revision)
+	assert.NotEqual(t, topo.Revision(0), originates.Revision)
+	assert.NotEmpty(t, terminates.ID)
+	waitForEvents(t, ch, link.ID, originates.ID, terminates.ID)
+	waitForRelations(t, store, "l1", 0, 2)
+
+	// A failed operation must fail the whole transaction
+	err = store.Transaction(context.TODO(),
+		CreateOperation(&topo.Object{ID: "l2", Type: topo.Object_ENTITY, Obj: &topo.Object_Entity{Entity: &topo.Entity{KindID: "link"}}}),
+		CreateOperation(&topo.Object{
+			Type: topo.Object_RELATION,
+			Obj:  &topo.Object_Relation{Relation: &topo.Relation{KindID: "originates", SrcEntityID: "d3", TgtEntityID: "l2"}},
+		}))
+	assert.True(t, errors.IsInvalid(err))
+	_, err = store.Get(context.TODO(), "l2")
+	assert.True(t, errors.IsNotFound(err))
+
+	node, err := store.Get(context.TODO(), "d1")
+	assert.NoError(t, err)
+	stale := node.Revision
+	node.Labels = map[string]string{"role": "spine"}
+	err = store.Update(context.TODO(), node)
+	assert.NoError(t, err)
+	err = store.Transaction(context.TODO(),
+		CreateOperation(&topo.Object{ID: "l3", Type: topo.Object_ENTITY}),
+		UpdateOperation(&topo.Object{ID: "d1", Type: topo.Object_ENTITY, Revision: stale}))
+	assert.True(t, errors.IsConflict(err))
+	_, err = store.Get(context.TODO(), "l3")
+	assert.True(t, errors.IsNotFound(err))
+
+	err = store.Transaction(context.TODO(), CreateOperation(&topo.Object{ID: "d1", Type: topo.Object_ENTITY}))
+	assert.True(t, errors.IsAlreadyExists(err))
+	err = store.Transaction(context.TODO(), DeleteOperation("l1", 0), UpdateOperation(link))
+	assert.True(t, errors.IsInvalid(err))
+
+	// Deleting an entity in a transaction must delete its relations
+	node.Labels = map[string]string{"role": "leaf"}
+	err = store.Transaction(context.TODO(), DeleteOperation("l1", link.Revision), UpdateOperation(node))
+	assert.NoError(t, err)
+	_, err = store.Get(context.TODO(), "l1")
+	assert.True(t, errors.IsNotFound(err))
+	objects, err := store.List(context.TODO(), &topo.Filters{ObjectTypes: []topo.Object_Type{topo.Object_RELATION}})
+	assert.NoError(t, err)
+	assert.Len(t, objects, 0)
+	node, err = store.Get(context.TODO(), "d1")
+	assert.NoError(t, err)
+	assert.Equal(t, "leaf", node.Labels["role"])
+	waitForRelations(t, store, "d1", 0, 0)
+}
+
+func TestCoalescedWatch(t *testing.T) {
+	forEachBackend(t, testCoalescedWatch)
+}
+
+func testCoalescedWatch(t *testing.T, newStore func() Store) {
+	store := newStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan topo.Event)
+	err := store.Watch(ctx, ch, nil, WithCoalesceWindow(500*time.Millisecond))
+	assert.NoError(t, err)
+	createNode(t, store, auxNode{id: "n0"})
+	waitForEvents(t, ch, "n0")
+
+	// Updates within the window must be coalesced into a single event carrying the latest state, and objects
+	// added and removed within the window must not be sent
+	node := &topo.Object{
+		ID:   "n1",
+		Type: topo.Object_ENTITY,
+		Obj:  &topo.Object_Entity{Entity: &topo.Entity{KindID: "e2-node"}},
+	}
+	assert.NoError(t, store.Create(context.TODO(), node))
+	for i := 0; i < 5; i++ {
+		node.Labels = map[string]string{"count": fmt.Sprint(i)}
+		assert.NoError(t, store.Update(context.TODO(), node))
+	}
+	createCell(t, store, auxCell{id: "c1"})
+	assert.NoError(t, store.Delete(context.TODO(), "c1", 0))
+
+	// Objects removed and added again within the window must be sent as updated
+	assert.NoError(t, store.Delete(context.TODO(), "n0", 0))
+	createNode(t, store, auxNode{id: "n0"})
+
+	event := nextWatchEvent(t, ch)
+	assert.Equal(t, topo.EventType_ADDED, event.Type)
+	assert.Equal(t, topo.ID("n1"), event.Object.ID)
+	assert.Equal(t, "4", event.Object.Labels["count"])
+	assert.Equal(t, node.Revision, event.Object.Revision)
+	event = nextWatchEvent(t, ch)
+	assert.Equal(t, topo.EventType_UPDATED, event.Type)
+	assert.Equal(t, topo.ID("n0"), event.Object.ID)
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected event %v %s", event.Type, event.Object.ID)
+	case <-time.After(600 * time.Millisecond):
+	}
+
+	// Changes after the window must be sent as new events
+	node.Labels = map[string]string{"count": "5"}
+	assert.NoError(t, store.Update(context.TODO(), node))
+	event = nextWatchEvent(t, ch)
+	assert.Equal(t, topo.EventType_UPDATED, event.Type)
+	assert.Equal(t, "5", event.Object.Labels["count"])
+
+	// Invalid windows and rates must be rejected
+	assert.True(t, errors.IsInvalid(store.Watch(ctx, make(chan topo.Event), nil, WithCoalesceWindow(-time.Second))))
+	assert.True(t, errors.IsInvalid(store.Watch(ctx, make(chan topo.Event), nil, WithMaxEventRate(-1))))
+}
+
+func TestRateLimitedWatch(t *testing.T) {
+	forEachBackend(t, testRateLimitedWatch)
+}
+
+func testRateLimitedWatch(t *testing.T, newStore func() Store) {
+	store := newStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan topo.Event)
+	err := store.Watch(ctx, ch, nil, WithMaxEventRate(20))
+	assert.NoError(t, err)
+
+	// Events must be spaced out to the maximum rate, and events queued meanwhile must be coalesced
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		createNode(t, store, auxNode{id: fmt.Sprintf("n%d", i)})
+	}
+	node, err := store.Get(context.TODO(), "n5")
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		node.Labels = map[string]string{"count": fmt.Sprint(i)}
+		assert.NoError(t, store.Update(context.TODO(), node))
+	}
+	latest := make(map[topo.ID]topo.Event)
+	for len(latest) < 6 || latest["n5"].Object.Labels["count"] != "4" {
+		event := nextWatchEvent(t, ch)
+		latest[event.Object.ID] = event
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 5*50*time.Millisecond)
+	assert.Equal(t, topo.EventType_ADDED, latest["n5"].Type)
+	stats := store.WatchStats().Watchers
+	assert.Len(t, stats, 1)
+	assert.NotZero(t, stats[0].Coalesced)
+
+	// The replay must be sent as a single burst
+	replayed := make(chan topo.Event)
+	err = store.Watch(ctx, replayed, nil, WithReplay(), WithMaxEventRate(2))
+	assert.NoError(t, err)
+	start = time.Now()
+	for i := 0; i < 6; i++ {
+		event := nextWatchEvent(t, replayed)
+		assert.Equal(t, topo.EventType_NONE, event.Type)
+	}
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestCoalesceEvents(t *testing.T) {
+	for _, c := range []struct {
+		queued   topo.EventType
+		event    topo.EventType
+		expected topo.EventType
+		ok       bool
+	}{
+		{topo.EventType_ADDED, topo.EventType_UPDATED, topo.EventType_ADDED, true},
+		{topo.EventType_NONE, topo.EventType_UPDATED, topo.EventType_NONE, true},
+		{topo.EventType_UPDATED, topo.EventType_UPDATED, topo.EventType_UPDATED, true},
+		{topo.EventType_UPDATED, topo.EventType_REMOVED, topo.EventType_REMOVED, true},
+		{topo.EventType_ADDED, topo.EventType_REMOVED, topo.EventType_NONE, false},
+		{topo.EventType_NONE, topo.EventType_REMOVED, topo.EventType_NONE, false},
+		{topo.EventType_REMOVED, topo.EventType_ADDED, topo.EventType_UPDATED, true},
+	} {
+		queued := topo.Event{Type: c.queued, Object: topo.Object{ID: "o", Revision: 1}}
+		event := topo.Event{Type: c.event, Object: topo.Object{ID: "o", Revision: 2}}
+		merged, ok := coalesceEvents(queued, event)
+		assert.Equal(t, c.ok, ok, "%v %v", c.queued, c.event)
+		if ok {
+			assert.Equal(t, c.expected, merged.Type, "%v %v", c.queued, c.event)
+			assert.Equal(t, topo.Revision(2), merged.Object.Revision)
+		}
+	}
+}